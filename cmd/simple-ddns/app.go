@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/ddns/provider"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/hook/script"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/http/server"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/metrics"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/netif"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/source"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/storage/sqlite"
+	"github.com/jorgesanchez-e/simple-ddns/internal/app/reconciler"
+	"github.com/jorgesanchez-e/simple-ddns/internal/app/retry"
+	"github.com/jorgesanchez-e/simple-ddns/internal/config"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/hook"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/log"
+
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/ddns/digitalocean"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/ddns/route53"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/discord"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/email"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/gotify"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/matrix"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/ntfy"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/slack"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/webhook"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/dnsquery"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/gateway"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/ipify"
+	_ "github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/stun"
+)
+
+type configReader interface {
+	Decode(node string, item any) error
+	Dump() ([]byte, error)
+	DumpRaw() ([]byte, error)
+	Watch(onChange func())
+}
+
+type messageLogger interface {
+	Trace(msg string)
+	Debug(msg string)
+	Info(msg string)
+	Warning(msg string)
+	Error(err error)
+	Fatal(err error)
+}
+
+type addressWatcher interface {
+	Run(ctx context.Context, trigger func()) error
+}
+
+type engine interface {
+	Run(ctx context.Context) error
+	Sync(ctx context.Context) (reconciler.Report, error)
+	Records(ctx context.Context) ([]dns.RecordState, error)
+	Status(ctx context.Context) (reconciler.Status, error)
+	LastStatus(ctx context.Context) (reconciler.Status, error)
+	Health(ctx context.Context) reconciler.Health
+	LastIP() (publicip.IP, time.Time)
+	History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error)
+	ForceSync(ctx context.Context, fqdn string) (reconciler.Report, error)
+	Pause(ctx context.Context, fqdn string, recordType dns.RecordType) error
+	Resume(ctx context.Context, fqdn string, recordType dns.RecordType) error
+	Providers() []reconciler.ProviderHealth
+	Trigger()
+	Close() error
+}
+
+// instrumentation decorates the engine dependencies to collect metrics.
+type instrumentation interface {
+	Source(name string, getter publicip.Getter) publicip.Getter
+	Getter(getter publicip.Getter) publicip.Getter
+	Updater(updater dns.Updater) dns.Updater
+	Store(store ddns.Controller) ddns.Controller
+	Records(source metrics.RecordSource)
+	Handler() http.Handler
+}
+
+type httpServer interface {
+	Listen() error
+	Serve(ctx context.Context) error
+}
+
+func newLogger(level string) (messageLogger, error) {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	return log.New(lvl), nil
+}
+
+func loadConfig(file string) (configReader, error) {
+	cnf, err := config.New(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return cnf, nil
+}
+
+func newStore(cnf configReader, logger messageLogger) (ddns.Controller, error) {
+	return sqlite.New(cnf, logger)
+}
+
+func newUpdaters(ctx context.Context, cnf configReader, logger messageLogger) ([]dns.Updater, error) {
+	return provider.NewUpdaters(ctx, cnf, logger)
+}
+
+func newGetter(cnf configReader, logger messageLogger) (publicip.Getter, error) {
+	return source.NewGetter(cnf, logger)
+}
+
+func newNotifier(cnf configReader, logger messageLogger) (notify.Notifier, error) {
+	return notifier.New(cnf, logger)
+}
+
+func newHooks(cnf configReader, logger messageLogger) (hook.Runner, error) {
+	return script.New(cnf, logger)
+}
+
+// newAddressWatcher builds the watcher syncing on address changes,
+// netif.ErrWatchDisabled is returned when it isn't configured.
+func newAddressWatcher(cnf configReader, logger messageLogger) (addressWatcher, error) {
+	return netif.NewWatcher(cnf, logger)
+}
+
+func newInstrumentation() instrumentation {
+	return metrics.New()
+}
+
+// newEngine builds the reconciler and its dependencies, inst may be nil when
+// no metrics are collected.
+func newEngine(ctx context.Context, cnf configReader, logger messageLogger, inst instrumentation) (engine, error) {
+	decorators := []source.Decorator{}
+	if inst != nil {
+		decorators = append(decorators, inst.Source)
+	}
+
+	getter, err := source.NewGetter(cnf, logger, decorators...)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newStore(cnf, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	updaters, err := newUpdaters(ctx, cnf, logger)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	policy, err := retry.NewPolicy(cnf)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	notifier, err := newNotifier(cnf, logger)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	hooks, err := newHooks(cnf, logger)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	if inst != nil {
+		getter = inst.Getter(getter)
+		store = inst.Store(store)
+		for i, updater := range updaters {
+			updaters[i] = inst.Updater(updater)
+		}
+	}
+
+	for i, updater := range updaters {
+		updaters[i] = retry.New(updater, policy, store, logger)
+	}
+
+	rec, err := reconciler.New(cnf, getter, store, updaters, logger, reconciler.WithNotifier(notifier), reconciler.WithHooks(hooks))
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// newServer builds the embedded http server, server.ErrDisabled is returned
+// when it isn't configured.
+func newServer(cnf configReader, d *daemon, logger messageLogger) (httpServer, error) {
+	srv, err := server.New(cnf, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.HandleHealth(d)
+	srv.HandleAdmin(d)
+	srv.HandleDashboard(d)
+	if d.instrumentation != nil {
+		srv.Handle("GET /metrics", d.instrumentation.Handler())
+	}
+
+	return srv, nil
+}
+
+func validateConfig(ctx context.Context, cnf configReader, logger messageLogger) error {
+	if _, err := newGetter(cnf, logger); err != nil {
+		return err
+	}
+
+	if err := sqlite.ValidateConfig(cnf); err != nil {
+		return err
+	}
+
+	if _, err := retry.NewPolicy(cnf); err != nil {
+		return err
+	}
+
+	if _, err := newNotifier(cnf, logger); err != nil {
+		return err
+	}
+
+	if _, err := newHooks(cnf, logger); err != nil {
+		return err
+	}
+
+	if _, err := newAddressWatcher(cnf, logger); err != nil && !errors.Is(err, netif.ErrWatchDisabled) {
+		return err
+	}
+
+	updaters, err := newUpdaters(ctx, cnf, logger)
+	if err != nil {
+		return err
+	}
+
+	_, err = reconciler.New(cnf, nil, nil, updaters, logger)
+	return err
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	exitNothingToDo    int = 0
+	exitUpdated        int = 1
+	exitPartialFailure int = 2
+	exitFatal          int = 3
+
+	exitOK    int = 0
+	exitError int = 1
+	exitUsage int = 2
+
+	defaultLogLevel     string = "info"
+	defaultHistoryLimit int    = 20
+	defaultAuditLimit   int    = 10
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "run", description: "run the reconciliation daemon", run: runCommand},
+		{name: "sync", description: "run a single sync cycle and exit", run: syncCommand},
+		{name: "status", description: "show the public ip and the state of every managed record", run: statusCommand},
+		{name: "history", description: "show the stored history of records", run: historyCommand},
+		{name: "audit", description: "show what the last sync cycles decided for every record", run: auditCommand},
+		{name: "records list", description: "list managed records and their stored values", run: recordsListCommand},
+		{name: "config validate", description: "validate a configuration file", run: configValidateCommand},
+		{name: "config show", description: "print the effective configuration", run: configShowCommand},
+		{name: "version", description: "print the version", run: versionCommand},
+	}
+}
+
+func execute(args []string) int {
+	// Keep `simple-ddns -config <file>` working as an alias of `run`.
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "-help" {
+		args = append([]string{"run"}, args...)
+	}
+
+	for _, cmd := range commands {
+		path := strings.Fields(cmd.name)
+		if len(args) >= len(path) && slices.Equal(args[:len(path)], path) {
+			return cmd.run(args[len(path):])
+		}
+	}
+
+	usage()
+	return exitUsage
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: simple-ddns <command> [flags]\n\ncommands:\n")
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.description)
+	}
+	w.Flush()
+}
+
+type commonFlags struct {
+	configFile string
+	logLevel   string
+}
+
+func newFlagSet(name string, common *commonFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&common.configFile, "config", "", "-config=<CONFIG-FILE-PATH>")
+	fs.StringVar(&common.logLevel, "log-level", defaultLogLevel, "-log-level=<trace|debug|info|warning|error>")
+
+	return fs
+}
+
+func setup(common commonFlags) (configReader, messageLogger, error) {
+	logger, err := newLogger(common.logLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cnf, err := loadConfig(common.configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cnf, logger, nil
+}
+
+func runCommand(args []string) int {
+	common := commonFlags{}
+	fs := newFlagSet("run", &common)
+	watch := fs.Bool("watch-config", true, "-watch-config=<true|false> reload when the config file changes")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cnf, logger, err := setup(common)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	ctx := context.Background()
+	inst := newInstrumentation()
+	eng, err := newEngine(ctx, cnf, logger, inst)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+
+	d := &daemon{configFile: common.configFile, config: cnf, logger: logger, engine: eng, instrumentation: inst, watch: *watch}
+	inst.Records(d)
+
+	srv, err := d.listen(cnf)
+	if err != nil {
+		logger.Error(err)
+		eng.Close()
+		return exitError
+	}
+	d.server = srv
+
+	if err = d.run(ctx); err != nil {
+		logger.Error(err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+func syncCommand(args []string) int {
+	common := commonFlags{}
+	if err := newFlagSet("sync", &common).Parse(args); err != nil {
+		return exitFatal
+	}
+
+	cnf, logger, err := setup(common)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFatal
+	}
+
+	ctx := context.Background()
+	eng, err := newEngine(ctx, cnf, logger, nil)
+	if err != nil {
+		logger.Error(err)
+		return exitFatal
+	}
+	defer eng.Close()
+
+	// the engine logs and notifies the outcome of the sync.
+	report, err := eng.Sync(ctx)
+	if err != nil {
+		return exitFatal
+	}
+
+	if len(report.Providers) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PROVIDER\tUPDATED\tFAILED\tDURATION")
+		for _, provider := range report.Providers {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", provider.Provider, len(provider.Updated), len(provider.Failed), provider.Duration.Round(time.Millisecond))
+		}
+		w.Flush()
+	}
+
+	if report.Err() != nil {
+		return exitPartialFailure
+	}
+
+	if len(report.Updated) > 0 {
+		return exitUpdated
+	}
+
+	return exitNothingToDo
+}
+
+func statusCommand(args []string) int {
+	common := commonFlags{}
+	if err := newFlagSet("status", &common).Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cnf, logger, err := setup(common)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	ctx := context.Background()
+	eng, err := newEngine(ctx, cnf, logger, nil)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+	defer eng.Close()
+
+	status, err := eng.Status(ctx)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+
+	fmt.Printf("ipv4: %s\nipv6: %s\n\n", valueOrDash(status.IP.V4), valueOrDash(status.IP.V6))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FQDN\tTYPE\tPROVIDER\tCURRENT\tDESIRED\tIN SYNC")
+	for _, state := range status.Records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n",
+			state.Record.FQDN,
+			state.Record.Type,
+			state.Provider,
+			valueOrDash(&state.Record.Value),
+			valueOrDash(&state.Desired),
+			state.InSync,
+		)
+	}
+	w.Flush()
+
+	return exitOK
+}
+
+func historyCommand(args []string) int {
+	common := commonFlags{}
+	fs := newFlagSet("history", &common)
+	fqdn := fs.String("fqdn", "", "-fqdn=<FQDN> only show the history of this record")
+	limit := fs.Int("limit", defaultHistoryLimit, "-limit=<N> maximum number of entries")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cnf, logger, err := setup(common)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	store, err := newStore(cnf, logger)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+	defer store.Close()
+
+	entries, err := store.History(context.Background(), *fqdn, *limit)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "UPDATED\tFQDN\tTYPE\tPROVIDER\tVALUE\tACTIVE")
+	for _, entry := range entries {
+		provider := entry.Provider
+		if provider == "" {
+			provider = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n",
+			entry.UpdateTime.Format("2006-01-02 15:04:05"),
+			entry.Record.FQDN,
+			entry.Record.Type,
+			provider,
+			entry.Record.Value,
+			entry.Active,
+		)
+	}
+	w.Flush()
+
+	return exitOK
+}
+
+func auditCommand(args []string) int {
+	common := commonFlags{}
+	fs := newFlagSet("audit", &common)
+	limit := fs.Int("limit", defaultAuditLimit, "-limit=<N> maximum number of cycles")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cnf, logger, err := setup(common)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	store, err := newStore(cnf, logger)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+	defer store.Close()
+
+	cycles, err := store.Cycles(context.Background(), *limit)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tFORCED\tSOURCE\tFQDN\tTYPE\tPROVIDER\tZONE\tPREVIOUS\tVALUE\tREASON\tSTATUS\tERROR")
+	for _, cycle := range cycles {
+		started := cycle.StartTime.Format("2006-01-02 15:04:05")
+		if len(cycle.Decisions) == 0 {
+			fmt.Fprintf(w, "%s\t%t\t%s\t-\t-\t-\t-\t-\t-\t-\t-\t%s\n",
+				started,
+				cycle.Forced,
+				valueOrDash(&cycle.IP.Source),
+				valueOrDash(&cycle.Error),
+			)
+			continue
+		}
+
+		for _, decision := range cycle.Decisions {
+			status := string(decision.Status)
+			fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				started,
+				cycle.Forced,
+				valueOrDash(&cycle.IP.Source),
+				decision.Record.FQDN,
+				decision.Record.Type,
+				decision.Provider,
+				valueOrDash(&decision.Zone),
+				valueOrDash(&decision.Previous),
+				valueOrDash(&decision.Record.Value),
+				decision.Reason,
+				valueOrDash(&status),
+				valueOrDash(&decision.Error),
+			)
+		}
+	}
+	w.Flush()
+
+	return exitOK
+}
+
+func recordsListCommand(args []string) int {
+	common := commonFlags{}
+	if err := newFlagSet("records list", &common).Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cnf, logger, err := setup(common)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	ctx := context.Background()
+	eng, err := newEngine(ctx, cnf, logger, nil)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+	defer eng.Close()
+
+	states, err := eng.Records(ctx)
+	if err != nil {
+		logger.Error(err)
+		return exitError
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FQDN\tTYPE\tPROVIDER\tVALUE\tUPDATED\tPAUSED")
+	for _, state := range states {
+		updated := ""
+		if !state.UpdateTime.IsZero() {
+			updated = state.UpdateTime.Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n",
+			state.Record.FQDN,
+			state.Record.Type,
+			state.Provider,
+			valueOrDash(&state.Record.Value),
+			valueOrDash(&updated),
+			state.Paused,
+		)
+	}
+	w.Flush()
+
+	return exitOK
+}
+
+func configValidateCommand(args []string) int {
+	common := commonFlags{}
+	if err := newFlagSet("config validate", &common).Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cnf, logger, err := setup(common)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if err = validateConfig(context.Background(), cnf, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: invalid configuration: %s\n", common.configFile, err)
+		return exitError
+	}
+
+	fmt.Printf("%s: configuration is valid\n", common.configFile)
+	return exitOK
+}
+
+func configShowCommand(args []string) int {
+	common := commonFlags{}
+	fs := newFlagSet("config show", &common)
+	showSecrets := fs.Bool("show-secrets", false, "-show-secrets print credentials instead of redacting them")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cnf, _, err := setup(common)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	dump := cnf.Dump
+	if *showSecrets {
+		dump = cnf.DumpRaw
+	}
+
+	settings, err := dump()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	fmt.Print(string(settings))
+	return exitOK
+}
+
+func versionCommand(args []string) int {
+	fmt.Println(version)
+	return exitOK
+}
+
+func valueOrDash(value *string) string {
+	if value == nil || *value == "" {
+		return "-"
+	}
+
+	return *value
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/http/server"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/netif"
+	"github.com/jorgesanchez-e/simple-ddns/internal/app/reconciler"
+	"github.com/jorgesanchez-e/simple-ddns/internal/config"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+// reloadDebounce groups the several write events editors produce when saving
+// a file into a single reload.
+const reloadDebounce time.Duration = 500 * time.Millisecond
+
+// httpPath holds the settings the http server is built from, it's rebuilt
+// when any of them changes.
+const httpPath string = "ddns.http."
+
+type daemon struct {
+	configFile      string
+	config          configReader
+	logger          messageLogger
+	server          httpServer
+	instrumentation instrumentation
+	watch           bool
+
+	// engine is only replaced by run, the lock is for readers serving http
+	// requests.
+	mu     sync.RWMutex
+	engine engine
+}
+
+// run drives the engine until a shutdown signal arrives or the engine stops.
+// A reload signal or a change to the config file rebuilds the engine, the
+// running one is kept when the new configuration is invalid, and a sync
+// signal forces a cycle.
+func (d *daemon) run(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, slices.Concat(shutdownSignals, reloadSignals, syncSignals)...)
+	defer signal.Stop(signals)
+
+	changes := make(chan struct{}, 1)
+	if d.watch {
+		// viper re-reads a watched config in place, watch a separate copy
+		// so the running one can still be diffed against the new file.
+		watched, err := loadConfig(d.configFile)
+		if err != nil {
+			return err
+		}
+
+		watched.Watch(func() {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		})
+	}
+
+	serverCtx, stopServer := context.WithCancel(ctx)
+	served := d.serve(serverCtx)
+	defer func() {
+		stopServer()
+		if err := <-served; err != nil {
+			d.logger.Error(err)
+		}
+	}()
+
+	runCtx, stop := context.WithCancel(ctx)
+	done := d.start(runCtx)
+
+	var debounce <-chan time.Time
+	reload := func(reason string, force bool) {
+		d.logger.Info(fmt.Sprintf("%s, reloading %s", reason, d.configFile))
+		cnf, eng, changes, err := d.reload(ctx, force)
+		if err != nil {
+			d.logger.Error(fmt.Errorf("reload failed, keeping current configuration, err:%w", err))
+			return
+		}
+
+		if eng == nil {
+			d.logger.Debug("configuration unchanged, nothing to reload")
+			return
+		}
+
+		stop()
+		<-done
+		d.close()
+
+		previous := d.config
+		d.config = cnf
+		d.mu.Lock()
+		d.engine = eng
+		d.mu.Unlock()
+		runCtx, stop = context.WithCancel(ctx)
+		done = d.start(runCtx)
+
+		// the listen address may stay the same, the running server has to
+		// release it before the new one binds it.
+		if httpChanged(changes) {
+			stopServer()
+			if err := <-served; err != nil {
+				d.logger.Error(err)
+			}
+
+			d.restartServer(previous, cnf)
+			serverCtx, stopServer = context.WithCancel(ctx)
+			served = d.serve(serverCtx)
+		}
+		d.logger.Info("configuration reloaded")
+	}
+
+	for {
+		select {
+		case err := <-done:
+			stop()
+			d.close()
+			return err
+		case <-changes:
+			debounce = time.After(reloadDebounce)
+		case <-debounce:
+			debounce = nil
+			reload("config file changed", false)
+		case sig := <-signals:
+			switch {
+			case slices.Contains(syncSignals, sig):
+				d.logger.Info(fmt.Sprintf("received %s, forcing sync", sig))
+				d.engine.Trigger()
+			case slices.Contains(reloadSignals, sig):
+				reload(fmt.Sprintf("received %s", sig), true)
+			default:
+				d.logger.Info(fmt.Sprintf("received %s, shutting down", sig))
+				stop()
+				err := <-done
+				d.close()
+				d.logger.Info("shutdown complete")
+
+				if errors.Is(err, context.Canceled) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+func (d *daemon) start(ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- d.engine.Run(ctx)
+	}()
+	d.watchAddresses(ctx)
+
+	return done
+}
+
+// watchAddresses triggers a sync of the running engine whenever a global
+// address changes, when configured. The watcher is rebuilt along with the
+// engine on reloads, a failing one only costs the early syncs.
+func (d *daemon) watchAddresses(ctx context.Context) {
+	watcher, err := newAddressWatcher(d.config, d.logger)
+	if errors.Is(err, netif.ErrWatchDisabled) {
+		return
+	}
+
+	if err != nil {
+		d.logger.Error(err)
+		return
+	}
+
+	eng := d.engine
+	go func() {
+		if err := watcher.Run(ctx, eng.Trigger); err != nil {
+			d.logger.Error(err)
+		}
+	}()
+}
+
+// listen builds the http server configured in cnf and binds its address, a
+// nil server is returned when it's disabled.
+func (d *daemon) listen(cnf configReader) (httpServer, error) {
+	srv, err := newServer(cnf, d, d.logger)
+	if err == nil {
+		err = srv.Listen()
+	}
+
+	switch {
+	case errors.Is(err, server.ErrDisabled):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return srv, nil
+}
+
+// restartServer replaces the stopped http server with the one configured in
+// cnf. When it can't listen the previous settings are served again.
+func (d *daemon) restartServer(previous, cnf configReader) {
+	srv, err := d.listen(cnf)
+	if err == nil {
+		d.server = srv
+		d.logger.Info("http server settings applied")
+		return
+	}
+
+	d.logger.Error(fmt.Errorf("unable to restart http server, keeping previous settings, err:%w", err))
+	if srv, err = d.listen(previous); err != nil {
+		d.logger.Error(err)
+	}
+	d.server = srv
+}
+
+func (d *daemon) serve(ctx context.Context) <-chan error {
+	served := make(chan error, 1)
+	if d.server == nil {
+		served <- nil
+		return served
+	}
+
+	go func() {
+		served <- d.server.Serve(ctx)
+	}()
+
+	return served
+}
+
+// reload loads and validates a fresh copy of the config file and builds an
+// engine from it, along with the settings that changed. Unless forced, a nil
+// engine is returned when the file didn't change.
+func (d *daemon) reload(ctx context.Context, force bool) (configReader, engine, []string, error) {
+	cnf, err := loadConfig(d.configFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	changes, err := config.Diff(d.config, cnf)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(changes) == 0 && !force {
+		return nil, nil, nil, nil
+	}
+
+	if err = validateConfig(ctx, cnf, d.logger); err != nil {
+		return nil, nil, nil, err
+	}
+
+	eng, err := newEngine(ctx, cnf, d.logger, d.instrumentation)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(changes) > 0 {
+		d.logger.Info(fmt.Sprintf("configuration changes: %s", strings.Join(changes, ", ")))
+	}
+	return cnf, eng, changes, nil
+}
+
+// httpChanged tells whether changes, as described by config.Diff, touch the
+// http server settings.
+func httpChanged(changes []string) bool {
+	return slices.ContainsFunc(changes, func(change string) bool {
+		_, setting, _ := strings.Cut(change, " ")
+		return strings.HasPrefix(setting, httpPath)
+	})
+}
+
+func (d *daemon) current() engine {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.engine
+}
+
+func (d *daemon) Health(ctx context.Context) reconciler.Health {
+	return d.current().Health(ctx)
+}
+
+func (d *daemon) LastIP() (publicip.IP, time.Time) {
+	return d.current().LastIP()
+}
+
+func (d *daemon) Records(ctx context.Context) ([]dns.RecordState, error) {
+	return d.current().Records(ctx)
+}
+
+func (d *daemon) LastStatus(ctx context.Context) (reconciler.Status, error) {
+	return d.current().LastStatus(ctx)
+}
+
+func (d *daemon) History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error) {
+	return d.current().History(ctx, fqdn, limit)
+}
+
+func (d *daemon) ForceSync(ctx context.Context, fqdn string) (reconciler.Report, error) {
+	return d.current().ForceSync(ctx, fqdn)
+}
+
+func (d *daemon) Pause(ctx context.Context, fqdn string, recordType dns.RecordType) error {
+	return d.current().Pause(ctx, fqdn, recordType)
+}
+
+func (d *daemon) Resume(ctx context.Context, fqdn string, recordType dns.RecordType) error {
+	return d.current().Resume(ctx, fqdn, recordType)
+}
+
+func (d *daemon) Providers() []reconciler.ProviderHealth {
+	return d.current().Providers()
+}
+
+func (d *daemon) close() {
+	if err := d.engine.Close(); err != nil {
+		d.logger.Error(fmt.Errorf("unable to close engine, err:%w", err))
+	}
+}
@@ -1,18 +1,11 @@
 package main
 
 import (
-	"fmt"
-
-	"github.com/jorgesanchez-e/simple-ddns/internal/config"
-	"github.com/jorgesanchez-e/simple-ddns/internal/log"
+	"os"
 )
 
-func main() {
-	log := log.New(log.Error)
-	config, err := config.New()
-	if err != nil {
-		log.Fatal(err)
-	}
+var version = "dev"
 
-	fmt.Println(config)
+func main() {
+	os.Exit(execute(os.Args[1:]))
 }
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+)
+
+var (
+	shutdownSignals = []os.Signal{os.Interrupt}
+	reloadSignals   = []os.Signal{}
+	syncSignals     = []os.Signal{}
+)
@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	reloadSignals   = []os.Signal{syscall.SIGHUP}
+	syncSignals     = []os.Signal{syscall.SIGUSR1}
+)
@@ -0,0 +1,299 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/ddns/provider"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+)
+
+const (
+	providerName    string = "digital-ocean"
+	doAccountsPath  string = "ddns.dns-server." + providerName
+	defaultEndpoint string = "https://api.digitalocean.com/v2"
+	defaultTTL      int    = 300
+)
+
+func init() {
+	provider.Register(providerName, func(ctx context.Context, cnf provider.ConfigDecoder, logger provider.MessageLogger) ([]dns.Updater, error) {
+		return newUpdaters(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type httpRequestor interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type recordConfig struct {
+	FQDN   string `yaml:"fqdn"`
+	Type   string `yaml:"type"`
+	APIKey string `yaml:"api-key"`
+}
+
+type doAccountConfig struct {
+	Account string         `yaml:"account"`
+	APIKey  string         `yaml:"api-key"`
+	Domain  string         `yaml:"domain"`
+	Records []recordConfig `yaml:"records"`
+}
+
+type domainRecord struct {
+	ID   int    `json:"id,omitempty"`
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+type domainRecordsResponse struct {
+	DomainRecords []domainRecord `json:"domain_records"`
+}
+
+type updater struct {
+	account  doAccountConfig
+	endpoint string
+	client   httpRequestor
+	logger   messageLogger
+}
+
+func New(cnf configDecoder, logger messageLogger, accountName string) (dns.Updater, error) {
+	accounts := []doAccountConfig{}
+	if err := cnf.Decode(doAccountsPath, &accounts); err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		if account.Account == accountName {
+			return &updater{
+				account:  account,
+				endpoint: defaultEndpoint,
+				client:   http.DefaultClient,
+				logger:   logger,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("account %s doesn't exist", accountName)
+}
+
+func newUpdaters(cnf configDecoder, logger messageLogger) ([]dns.Updater, error) {
+	accounts := []doAccountConfig{}
+	if err := cnf.Decode(doAccountsPath, &accounts); err != nil {
+		return nil, err
+	}
+
+	updaters := make([]dns.Updater, 0, len(accounts))
+	for _, account := range accounts {
+		updater, err := New(cnf, logger, account.Account)
+		if err != nil {
+			return nil, fmt.Errorf("digital-ocean: unable to create updater for account %s, err:%w", account.Account, err)
+		}
+
+		updaters = append(updaters, updater)
+	}
+
+	return updaters, nil
+}
+
+func (u *updater) Name() string {
+	return fmt.Sprintf("%s/%s", providerName, u.account.Account)
+}
+
+func (u *updater) Records() []dns.DomainRecord {
+	records := make([]dns.DomainRecord, 0, len(u.account.Records))
+	for _, rec := range u.account.Records {
+		records = append(records, dns.DomainRecord{
+			FQDN: rec.FQDN,
+			Type: dns.RecordType(rec.Type),
+		})
+	}
+
+	return records
+}
+
+func (u *updater) Zone(record dns.DomainRecord) string {
+	if _, managed := u.recordConfig(record); !managed {
+		return ""
+	}
+
+	return u.domain(strings.TrimSuffix(record.FQDN, "."))
+}
+
+func (u *updater) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	results := make([]dns.UpdateResult, 0, len(records))
+	for _, rec := range records {
+		cnf, managed := u.recordConfig(rec)
+		if !managed {
+			results = append(results, dns.UpdateResult{Record: rec, Status: dns.Skipped})
+			continue
+		}
+
+		zone := u.domain(strings.TrimSuffix(rec.FQDN, "."))
+		status, err := u.upsert(ctx, cnf, rec)
+		if err != nil {
+			results = append(results, dns.UpdateResult{
+				Record: rec,
+				Zone:   zone,
+				Status: dns.Failed,
+				Err:    fmt.Errorf("digital-ocean: unable to update %s %s, err:%w", rec.Type, rec.FQDN, err),
+			})
+			continue
+		}
+
+		results = append(results, dns.UpdateResult{Record: rec, Zone: zone, Status: status})
+	}
+
+	return results
+}
+
+func (u *updater) recordConfig(rec dns.DomainRecord) (recordConfig, bool) {
+	for _, cnf := range u.account.Records {
+		if cnf.FQDN == rec.FQDN && dns.RecordType(cnf.Type) == rec.Type {
+			return cnf, true
+		}
+	}
+
+	return recordConfig{}, false
+}
+
+func (u *updater) upsert(ctx context.Context, cnf recordConfig, rec dns.DomainRecord) (dns.UpdateStatus, error) {
+	apiKey := u.account.APIKey
+	if cnf.APIKey != "" {
+		apiKey = cnf.APIKey
+	}
+
+	fqdn := strings.TrimSuffix(rec.FQDN, ".")
+	domain := u.domain(fqdn)
+
+	existing, err := u.find(ctx, apiKey, domain, fqdn, rec.Type)
+	if err != nil {
+		return dns.Failed, err
+	}
+
+	if existing == nil {
+		u.logger.Debug(fmt.Sprintf("digital-ocean: creating %s %s", rec.Type, rec.FQDN))
+		err = u.do(ctx, apiKey, http.MethodPost, fmt.Sprintf("/domains/%s/records", domain), domainRecord{
+			Type: string(rec.Type),
+			Name: recordName(fqdn, domain),
+			Data: rec.Value,
+			TTL:  defaultTTL,
+		}, nil)
+		if err != nil {
+			return dns.Failed, err
+		}
+
+		return dns.Applied, nil
+	}
+
+	if existing.Data == rec.Value {
+		return dns.Unchanged, nil
+	}
+
+	u.logger.Debug(fmt.Sprintf("digital-ocean: updating %s %s record id %d", rec.Type, rec.FQDN, existing.ID))
+	err = u.do(ctx, apiKey, http.MethodPatch, fmt.Sprintf("/domains/%s/records/%d", domain, existing.ID), domainRecord{
+		Type: string(rec.Type),
+		Data: rec.Value,
+	}, nil)
+	if err != nil {
+		return dns.Failed, err
+	}
+
+	return dns.Applied, nil
+}
+
+func (u *updater) find(ctx context.Context, apiKey, domain, fqdn string, recordType dns.RecordType) (*domainRecord, error) {
+	query := url.Values{}
+	query.Set("type", string(recordType))
+	query.Set("name", fqdn)
+
+	response := domainRecordsResponse{}
+	if err := u.do(ctx, apiKey, http.MethodGet, fmt.Sprintf("/domains/%s/records?%s", domain, query.Encode()), nil, &response); err != nil {
+		return nil, err
+	}
+
+	if len(response.DomainRecords) == 0 {
+		return nil, nil
+	}
+
+	return &response.DomainRecords[0], nil
+}
+
+func (u *updater) do(ctx context.Context, apiKey, method, path string, payload any, result any) error {
+	var body io.Reader
+	if payload != nil {
+		buf, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.endpoint+path, body)
+	if err != nil {
+		return fmt.Errorf("request build error: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("network error: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		err = fmt.Errorf("http error: code %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+
+		// client errors won't go away by sending the same request again,
+		// except for rate limiting.
+		if res.StatusCode < http.StatusInternalServerError && res.StatusCode != http.StatusTooManyRequests {
+			return dns.Permanent(err)
+		}
+
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+func (u *updater) domain(fqdn string) string {
+	if u.account.Domain != "" {
+		return strings.TrimSuffix(u.account.Domain, ".")
+	}
+
+	labels := strings.Split(fqdn, ".")
+	if len(labels) <= 2 {
+		return fqdn
+	}
+
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+func recordName(fqdn, domain string) string {
+	if fqdn == domain {
+		return "@"
+	}
+
+	return strings.TrimSuffix(fqdn, "."+domain)
+}
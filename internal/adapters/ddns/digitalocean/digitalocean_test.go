@@ -0,0 +1,286 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+type apiCall struct {
+	Method string
+	Path   string
+	Query  string
+	Token  string
+	Body   domainRecord
+}
+
+type fakeAPI struct {
+	mu       sync.Mutex
+	calls    []apiCall
+	existing map[string][]domainRecord
+	status   int
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := apiCall{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Token:  r.Header.Get("Authorization"),
+	}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&call.Body)
+	}
+	f.calls = append(f.calls, call)
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		_, _ = w.Write([]byte(`{"message":"unauthorized"}`))
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		_ = json.NewEncoder(w).Encode(domainRecordsResponse{
+			DomainRecords: f.existing[r.URL.Query().Get("name")],
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{}`))
+}
+
+func TestUpdateDomains(t *testing.T) {
+	account := doAccountConfig{
+		Account: "main",
+		APIKey:  "API-KEY",
+		Records: []recordConfig{
+			{FQDN: "vpn.jorgesanchez-e.dev.", Type: "A"},
+			{FQDN: "jenkins.jorgesanchez-e.dev.", Type: "A", APIKey: "API-KEY-2"},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		api            *fakeAPI
+		records        []dns.DomainRecord
+		expectedCalls  []apiCall
+		expectedStatus dns.UpdateStatus
+		expectedError  string
+		permanent      bool
+	}{
+		{
+			name: "create-missing-record",
+			api:  &fakeAPI{},
+			records: []dns.DomainRecord{
+				{FQDN: "vpn.jorgesanchez-e.dev.", Type: dns.A, Value: "10.0.0.1"},
+			},
+			expectedCalls: []apiCall{
+				{
+					Method: http.MethodGet,
+					Path:   "/domains/jorgesanchez-e.dev/records",
+					Query:  "name=vpn.jorgesanchez-e.dev&type=A",
+					Token:  "Bearer API-KEY",
+				},
+				{
+					Method: http.MethodPost,
+					Path:   "/domains/jorgesanchez-e.dev/records",
+					Token:  "Bearer API-KEY",
+					Body:   domainRecord{Type: "A", Name: "vpn", Data: "10.0.0.1", TTL: defaultTTL},
+				},
+			},
+			expectedStatus: dns.Applied,
+		},
+		{
+			name: "update-existing-record-with-record-api-key",
+			api: &fakeAPI{existing: map[string][]domainRecord{
+				"jenkins.jorgesanchez-e.dev": {{ID: 42, Type: "A", Name: "jenkins", Data: "10.0.0.1"}},
+			}},
+			records: []dns.DomainRecord{
+				{FQDN: "jenkins.jorgesanchez-e.dev.", Type: dns.A, Value: "10.0.0.2"},
+			},
+			expectedCalls: []apiCall{
+				{
+					Method: http.MethodGet,
+					Path:   "/domains/jorgesanchez-e.dev/records",
+					Query:  "name=jenkins.jorgesanchez-e.dev&type=A",
+					Token:  "Bearer API-KEY-2",
+				},
+				{
+					Method: http.MethodPatch,
+					Path:   "/domains/jorgesanchez-e.dev/records/42",
+					Token:  "Bearer API-KEY-2",
+					Body:   domainRecord{Type: "A", Data: "10.0.0.2"},
+				},
+			},
+			expectedStatus: dns.Applied,
+		},
+		{
+			name: "record-already-up-to-date",
+			api: &fakeAPI{existing: map[string][]domainRecord{
+				"vpn.jorgesanchez-e.dev": {{ID: 7, Type: "A", Name: "vpn", Data: "10.0.0.1"}},
+			}},
+			records: []dns.DomainRecord{
+				{FQDN: "vpn.jorgesanchez-e.dev.", Type: dns.A, Value: "10.0.0.1"},
+			},
+			expectedCalls: []apiCall{
+				{
+					Method: http.MethodGet,
+					Path:   "/domains/jorgesanchez-e.dev/records",
+					Query:  "name=vpn.jorgesanchez-e.dev&type=A",
+					Token:  "Bearer API-KEY",
+				},
+			},
+			expectedStatus: dns.Unchanged,
+		},
+		{
+			name: "record-not-managed",
+			api:  &fakeAPI{},
+			records: []dns.DomainRecord{
+				{FQDN: "www.jorgesanchez-e.dev.", Type: dns.A, Value: "10.0.0.1"},
+			},
+			expectedCalls:  nil,
+			expectedStatus: dns.Skipped,
+		},
+		{
+			name: "api-error",
+			api:  &fakeAPI{status: http.StatusUnauthorized},
+			records: []dns.DomainRecord{
+				{FQDN: "vpn.jorgesanchez-e.dev.", Type: dns.A, Value: "10.0.0.1"},
+			},
+			expectedCalls: []apiCall{
+				{
+					Method: http.MethodGet,
+					Path:   "/domains/jorgesanchez-e.dev/records",
+					Query:  "name=vpn.jorgesanchez-e.dev&type=A",
+					Token:  "Bearer API-KEY",
+				},
+			},
+			expectedStatus: dns.Failed,
+			expectedError:  `digital-ocean: unable to update A vpn.jorgesanchez-e.dev., err:http error: code 401: {"message":"unauthorized"}`,
+			permanent:      true,
+		},
+		{
+			name: "rate-limited",
+			api:  &fakeAPI{status: http.StatusTooManyRequests},
+			records: []dns.DomainRecord{
+				{FQDN: "vpn.jorgesanchez-e.dev.", Type: dns.A, Value: "10.0.0.1"},
+			},
+			expectedCalls: []apiCall{
+				{
+					Method: http.MethodGet,
+					Path:   "/domains/jorgesanchez-e.dev/records",
+					Query:  "name=vpn.jorgesanchez-e.dev&type=A",
+					Token:  "Bearer API-KEY",
+				},
+			},
+			expectedStatus: dns.Failed,
+			expectedError:  `digital-ocean: unable to update A vpn.jorgesanchez-e.dev., err:http error: code 429: {"message":"unauthorized"}`,
+			permanent:      false,
+		},
+	}
+
+	for _, tc := range testCases {
+		api := tc.api
+		records := tc.records
+		expectedCalls := tc.expectedCalls
+		expectedStatus := tc.expectedStatus
+		expectedError := tc.expectedError
+		permanent := tc.permanent
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			u := updater{
+				account:  account,
+				endpoint: server.URL,
+				client:   server.Client(),
+				logger:   loggerMock{},
+			}
+
+			results := u.UpdateDomains(context.Background(), records)
+
+			assert.Len(t, results, 1)
+			assert.Equal(t, records[0], results[0].Record)
+			assert.Equal(t, expectedStatus, results[0].Status)
+			if expectedStatus != dns.Skipped {
+				assert.Equal(t, "jorgesanchez-e.dev", results[0].Zone)
+			}
+			if expectedError != "" {
+				assert.EqualError(t, results[0].Err, expectedError)
+				assert.Equal(t, permanent, dns.IsPermanent(results[0].Err))
+			} else {
+				assert.NoError(t, results[0].Err)
+			}
+			assert.Equal(t, expectedCalls, api.calls)
+		})
+	}
+}
+
+func TestDomain(t *testing.T) {
+	testCases := []struct {
+		name           string
+		account        doAccountConfig
+		fqdn           string
+		expectedDomain string
+		expectedName   string
+	}{
+		{
+			name:           "derived-domain",
+			fqdn:           "vpn.jorgesanchez-e.dev",
+			expectedDomain: "jorgesanchez-e.dev",
+			expectedName:   "vpn",
+		},
+		{
+			name:           "configured-domain",
+			account:        doAccountConfig{Domain: "home.example.co.uk."},
+			fqdn:           "vpn.home.example.co.uk",
+			expectedDomain: "home.example.co.uk",
+			expectedName:   "vpn",
+		},
+		{
+			name:           "apex-record",
+			fqdn:           "jorgesanchez-e.dev",
+			expectedDomain: "jorgesanchez-e.dev",
+			expectedName:   "@",
+		},
+	}
+
+	for _, tc := range testCases {
+		u := updater{account: tc.account}
+		fqdn := tc.fqdn
+		expectedDomain := tc.expectedDomain
+		expectedName := tc.expectedName
+
+		t.Run(tc.name, func(t *testing.T) {
+			domain := u.domain(fqdn)
+
+			assert.Equal(t, expectedDomain, domain)
+			assert.Equal(t, expectedName, recordName(fqdn, domain))
+		})
+	}
+}
+
+func TestZone(t *testing.T) {
+	u := updater{account: doAccountConfig{
+		Records: []recordConfig{{FQDN: "vpn.jorgesanchez-e.dev.", Type: "A"}},
+	}}
+
+	assert.Equal(t, "jorgesanchez-e.dev", u.Zone(dns.DomainRecord{FQDN: "vpn.jorgesanchez-e.dev.", Type: dns.A}))
+	assert.Equal(t, "", u.Zone(dns.DomainRecord{FQDN: "vpn.jorgesanchez-e.dev.", Type: dns.AAAA}))
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+)
+
+const (
+	dnsServersPath string = "ddns.dns-server"
+)
+
+type ConfigDecoder interface {
+	Decode(node string, item any) error
+}
+
+type MessageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+// Factory builds one dns.Updater per account configured under the provider
+// key in ddns.dns-server.
+type Factory func(ctx context.Context, cnf ConfigDecoder, logger MessageLogger) ([]dns.Updater, error)
+
+type registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+var defaultRegistry = newRegistry()
+
+func newRegistry() *registry {
+	return &registry{factories: map[string]Factory{}}
+}
+
+// Register makes a provider available under its config key. It is meant to be
+// called from the init function of each provider adapter.
+func Register(name string, factory Factory) {
+	defaultRegistry.register(name, factory)
+}
+
+func Providers() []string {
+	return defaultRegistry.providers()
+}
+
+func NewUpdaters(ctx context.Context, cnf ConfigDecoder, logger MessageLogger) ([]dns.Updater, error) {
+	return defaultRegistry.newUpdaters(ctx, cnf, logger)
+}
+
+func (r *registry) register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if factory == nil {
+		panic("provider: register factory is nil for " + name)
+	}
+
+	if _, exists := r.factories[name]; exists {
+		panic("provider: register called twice for " + name)
+	}
+
+	r.factories[name] = factory
+}
+
+func (r *registry) providers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (r *registry) newUpdaters(ctx context.Context, cnf ConfigDecoder, logger MessageLogger) ([]dns.Updater, error) {
+	configured := map[string]any{}
+	if err := cnf.Decode(dnsServersPath, &configured); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	updaters := make([]dns.Updater, 0)
+	for _, name := range names {
+		factory, ok := r.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("provider: unknown dns provider %s", name)
+		}
+
+		providerUpdaters, err := factory(ctx, cnf, logger)
+		if err != nil {
+			return nil, fmt.Errorf("provider: unable to create %s updaters, err:%w", name, err)
+		}
+
+		updaters = append(updaters, providerUpdaters...)
+	}
+
+	if len(updaters) == 0 {
+		return nil, fmt.Errorf("provider: no dns accounts configured under %s", dnsServersPath)
+	}
+
+	return updaters, nil
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	providers map[string]any
+	err       error
+}
+
+func (c configMock) Decode(node string, item any) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	*item.(*map[string]any) = c.providers
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+type updaterMock struct {
+	name string
+}
+
+func (u updaterMock) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	return nil
+}
+
+func (u updaterMock) Records() []dns.DomainRecord {
+	return nil
+}
+
+func (u updaterMock) Name() string {
+	return u.name
+}
+
+func factoryFor(names ...string) Factory {
+	return func(ctx context.Context, cnf ConfigDecoder, logger MessageLogger) ([]dns.Updater, error) {
+		updaters := []dns.Updater{}
+		for _, name := range names {
+			updaters = append(updaters, updaterMock{name: name})
+		}
+
+		return updaters, nil
+	}
+}
+
+func TestNewUpdaters(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        configMock
+		expectedNames []string
+		expectedError string
+	}{
+		{
+			name: "all-configured-accounts",
+			config: configMock{providers: map[string]any{
+				"digital-ocean": []any{},
+				"aws":           []any{},
+			}},
+			expectedNames: []string{"aws/main", "aws/secondary", "digital-ocean/main"},
+		},
+		{
+			name: "only-configured-providers",
+			config: configMock{providers: map[string]any{
+				"digital-ocean": []any{},
+			}},
+			expectedNames: []string{"digital-ocean/main"},
+		},
+		{
+			name: "unknown-provider",
+			config: configMock{providers: map[string]any{
+				"cloudflare": []any{},
+			}},
+			expectedError: "provider: unknown dns provider cloudflare",
+		},
+		{
+			name: "failing-provider",
+			config: configMock{providers: map[string]any{
+				"broken": []any{},
+			}},
+			expectedError: "provider: unable to create broken updaters, err:bad credentials",
+		},
+		{
+			name:          "no-providers",
+			config:        configMock{providers: map[string]any{}},
+			expectedError: "provider: no dns accounts configured under ddns.dns-server",
+		},
+		{
+			name:          "config-error",
+			config:        configMock{err: errors.New("node ddns.dns-server not found")},
+			expectedError: "node ddns.dns-server not found",
+		},
+	}
+
+	reg := newRegistry()
+	reg.register("aws", factoryFor("aws/main", "aws/secondary"))
+	reg.register("digital-ocean", factoryFor("digital-ocean/main"))
+	reg.register("broken", func(ctx context.Context, cnf ConfigDecoder, logger MessageLogger) ([]dns.Updater, error) {
+		return nil, errors.New("bad credentials")
+	})
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		expectedNames := tc.expectedNames
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			updaters, err := reg.newUpdaters(context.Background(), cnf, loggerMock{})
+
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			names := []string{}
+			for _, updater := range updaters {
+				names = append(names, updater.Name())
+			}
+			assert.Equal(t, expectedNames, names)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	reg := newRegistry()
+	reg.register("aws", factoryFor())
+
+	assert.Equal(t, []string{"aws"}, reg.providers())
+	assert.Panics(t, func() { reg.register("aws", factoryFor()) })
+	assert.Panics(t, func() { reg.register("nil", nil) })
+}
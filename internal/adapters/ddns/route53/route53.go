@@ -9,14 +9,23 @@ import (
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
 
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/ddns/provider"
 	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
 )
 
 const (
-	awsAccountsPath string = "ddns.dns-server.aws"
+	providerName    string = "aws"
+	awsAccountsPath string = "ddns.dns-server." + providerName
 )
 
+func init() {
+	provider.Register(providerName, func(ctx context.Context, cnf provider.ConfigDecoder, logger provider.MessageLogger) ([]dns.Updater, error) {
+		return newUpdaters(ctx, cnf, logger)
+	})
+}
+
 type configDecoder interface {
 	Decode(node string, item any) error
 }
@@ -89,9 +98,30 @@ func New(ctx context.Context, cnf configDecoder, logger messageLogger, accountNa
 	return nil, fmt.Errorf("account %s doesn't exist", accountName)
 }
 
+func newUpdaters(ctx context.Context, cnf configDecoder, logger messageLogger) ([]dns.Updater, error) {
+	awsAccounts := []awsAccountConfig{}
+	err := cnf.Decode(awsAccountsPath, &awsAccounts)
+	if err != nil {
+		return nil, err
+	}
+
+	updaters := make([]dns.Updater, 0, len(awsAccounts))
+	for _, account := range awsAccounts {
+		updater, err := New(ctx, cnf, logger, account.Account)
+		if err != nil {
+			return nil, fmt.Errorf("route53: unable to create updater for account %s, err:%w", account.Account, err)
+		}
+
+		updaters = append(updaters, updater)
+	}
+
+	return updaters, nil
+}
+
 type batch struct {
 	zoneID      string
 	changeBatch *types.ChangeBatch
+	records     []dns.DomainRecord
 }
 
 func (u *updater) buildBatches(records []dns.DomainRecord) []batch {
@@ -109,12 +139,16 @@ func (u *updater) buildBatches(records []dns.DomainRecord) []batch {
 		for _, zrecord := range zone.Records {
 			for _, rec := range records {
 				if rec.FQDN == zrecord.FQDN && rec.Type == dns.RecordType(zrecord.Type) {
+					btc.records = append(btc.records, rec)
 					changes = append(changes, types.Change{
 						Action: types.ChangeActionUpsert,
 						ResourceRecordSet: &types.ResourceRecordSet{
 							Name: aws.String(rec.FQDN),
 							Type: types.RRType(rec.Type),
 							TTL:  aws.Int64(300),
+							ResourceRecords: []types.ResourceRecord{
+								{Value: aws.String(rec.Value)},
+							},
 						},
 					})
 				}
@@ -134,24 +168,85 @@ func (u *updater) buildBatches(records []dns.DomainRecord) []batch {
 	return nil
 }
 
-func (u *updater) UpdateDomains(ctx context.Context, records []dns.DomainRecord) error {
-	errs := []error{}
-	batches := u.buildBatches(records)
-	for _, batch := range batches {
+func (u *updater) Name() string {
+	return fmt.Sprintf("%s/%s", providerName, u.awsAccountName)
+}
+
+func (u *updater) Records() []dns.DomainRecord {
+	records := make([]dns.DomainRecord, 0)
+	for _, zone := range u.zones {
+		for _, rec := range zone.Records {
+			records = append(records, dns.DomainRecord{
+				FQDN: rec.FQDN,
+				Type: dns.RecordType(rec.Type),
+			})
+		}
+	}
+
+	return records
+}
+
+func (u *updater) Zone(record dns.DomainRecord) string {
+	for _, zone := range u.zones {
+		for _, rec := range zone.Records {
+			if rec.FQDN == record.FQDN && dns.RecordType(rec.Type) == record.Type {
+				return zone.ID
+			}
+		}
+	}
+
+	return ""
+}
+
+func (u *updater) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	results := make([]dns.UpdateResult, 0, len(records))
+	batched := make(map[dns.DomainRecord]bool, len(records))
+
+	for _, batch := range u.buildBatches(records) {
 		payload := &route53.ChangeResourceRecordSetsInput{
 			ChangeBatch:  batch.changeBatch,
 			HostedZoneId: &batch.zoneID,
 		}
 
 		_, err := u.client.ChangeResourceRecordSets(ctx, payload)
-		if err != nil {
-			errs = append(errs, err)
+		for _, rec := range batch.records {
+			batched[rec] = true
+
+			if err != nil {
+				results = append(results, dns.UpdateResult{
+					Record: rec,
+					Zone:   batch.zoneID,
+					Status: dns.Failed,
+					Err:    fmt.Errorf("route53: zone %s: %w", batch.zoneID, classify(err)),
+				})
+				continue
+			}
+
+			results = append(results, dns.UpdateResult{Record: rec, Zone: batch.zoneID, Status: dns.Applied})
 		}
 	}
 
-	if len(errs) > 0 {
-		return errors.New("some records couldn't be updated")
+	for _, rec := range records {
+		if !batched[rec] {
+			results = append(results, dns.UpdateResult{Record: rec, Status: dns.Skipped})
+		}
 	}
 
-	return nil
+	return results
+}
+
+// classify marks client faults reported by the api as permanent, throttling
+// and concurrent modification errors are left retryable.
+func classify(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorFault() != smithy.FaultClient {
+		return err
+	}
+
+	switch apiErr.ErrorCode() {
+	case "Throttling", "ThrottlingException", "PriorRequestNotComplete":
+		return err
+	}
+
+	return dns.Permanent(err)
 }
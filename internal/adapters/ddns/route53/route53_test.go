@@ -3,11 +3,13 @@ package route53
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
 	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,17 +50,31 @@ func Test_buildBatches(t *testing.T) {
 			},
 			records: []dns.DomainRecord{
 				{
-					FQDN: "jenkins.local-environment.com",
-					Type: dns.A,
+					FQDN:  "jenkins.local-environment.com",
+					Type:  dns.A,
+					Value: "192.168.100.1",
 				},
 				{
-					FQDN: "www6.local-environment.com",
-					Type: "AAAA",
+					FQDN:  "www6.local-environment.com",
+					Type:  "AAAA",
+					Value: "2001:db8::1",
 				},
 			},
 			expectedBatches: []batch{
 				{
 					zoneID: "1111111111111111111111",
+					records: []dns.DomainRecord{
+						{
+							FQDN:  "jenkins.local-environment.com",
+							Type:  dns.A,
+							Value: "192.168.100.1",
+						},
+						{
+							FQDN:  "www6.local-environment.com",
+							Type:  "AAAA",
+							Value: "2001:db8::1",
+						},
+					},
 					changeBatch: &types.ChangeBatch{
 						Comment: aws.String("changes for zone id 1111111111111111111111"),
 						Changes: []types.Change{
@@ -68,6 +84,9 @@ func Test_buildBatches(t *testing.T) {
 									Name: aws.String("jenkins.local-environment.com"),
 									Type: types.RRTypeA,
 									TTL:  aws.Int64(300),
+									ResourceRecords: []types.ResourceRecord{
+										{Value: aws.String("192.168.100.1")},
+									},
 								},
 							},
 							{
@@ -76,6 +95,9 @@ func Test_buildBatches(t *testing.T) {
 									Name: aws.String("www6.local-environment.com"),
 									Type: types.RRTypeAaaa,
 									TTL:  aws.Int64(300),
+									ResourceRecords: []types.ResourceRecord{
+										{Value: aws.String("2001:db8::1")},
+									},
 								},
 							},
 						},
@@ -133,6 +155,46 @@ func Test_buildBatches(t *testing.T) {
 	}
 }
 
+func Test_Records(t *testing.T) {
+	u := updater{
+		zones: []zoneConfig{
+			{
+				ID: "1111111111111111111111",
+				Records: []recordsConfig{
+					{
+						FQDN: "www.local-environment.com",
+						Type: "A",
+					},
+				},
+			},
+			{
+				ID: "2222222222222222222222",
+				Records: []recordsConfig{
+					{
+						FQDN: "www6.local-environment.com",
+						Type: "AAAA",
+					},
+				},
+			},
+		},
+	}
+
+	expectedRecords := []dns.DomainRecord{
+		{
+			FQDN: "www.local-environment.com",
+			Type: dns.A,
+		},
+		{
+			FQDN: "www6.local-environment.com",
+			Type: dns.AAAA,
+		},
+	}
+
+	assert.Equal(t, expectedRecords, u.Records())
+	assert.Equal(t, "2222222222222222222222", u.Zone(dns.DomainRecord{FQDN: "www6.local-environment.com", Type: dns.AAAA}))
+	assert.Equal(t, "", u.Zone(dns.DomainRecord{FQDN: "www6.local-environment.com", Type: dns.A}))
+}
+
 type r53MockClient struct {
 	err error
 }
@@ -142,28 +204,30 @@ func (mock r53MockClient) ChangeResourceRecordSets(ctx context.Context, params *
 }
 
 func Test_UpdateDomains(t *testing.T) {
+	zones := []zoneConfig{
+		{
+			ID: "000000000000000",
+			Records: []recordsConfig{
+				{
+					FQDN: "home.google.com",
+					Type: "A",
+				},
+			},
+		},
+	}
+
 	testCases := []struct {
-		name          string
-		updater       updater
-		records       []dns.DomainRecord
-		expectedError error
+		name            string
+		updater         updater
+		records         []dns.DomainRecord
+		expectedResults []dns.UpdateResult
 	}{
 		{
 			name: "records-updated-ok",
 			updater: updater{
 				awsAccountName: "main",
-				zones: []zoneConfig{
-					{
-						ID: "000000000000000",
-						Records: []recordsConfig{
-							{
-								FQDN: "home.google.com",
-								Type: "A",
-							},
-						},
-					},
-				},
-				client: r53MockClient{},
+				zones:          zones,
+				client:         r53MockClient{},
 			},
 			records: []dns.DomainRecord{
 				{
@@ -172,22 +236,23 @@ func Test_UpdateDomains(t *testing.T) {
 					FQDN:  "home.google.com",
 				},
 			},
+			expectedResults: []dns.UpdateResult{
+				{
+					Record: dns.DomainRecord{
+						Type:  "A",
+						Value: "192.168.100.1",
+						FQDN:  "home.google.com",
+					},
+					Zone:   "000000000000000",
+					Status: dns.Applied,
+				},
+			},
 		},
 		{
 			name: "update-error",
 			updater: updater{
 				awsAccountName: "main",
-				zones: []zoneConfig{
-					{
-						ID: "000000000000000",
-						Records: []recordsConfig{
-							{
-								FQDN: "home.google.com",
-								Type: "A",
-							},
-						},
-					},
-				},
+				zones:          zones,
 				client: r53MockClient{
 					err: errors.New("update error"),
 				},
@@ -199,20 +264,102 @@ func Test_UpdateDomains(t *testing.T) {
 					FQDN:  "home.google.com",
 				},
 			},
-			expectedError: errors.New("some records couldn't be updated"),
+			expectedResults: []dns.UpdateResult{
+				{
+					Record: dns.DomainRecord{
+						Type:  "A",
+						Value: "192.168.100.1",
+						FQDN:  "home.google.com",
+					},
+					Zone:   "000000000000000",
+					Status: dns.Failed,
+					Err:    fmt.Errorf("route53: zone 000000000000000: %w", errors.New("update error")),
+				},
+			},
+		},
+		{
+			name: "record-not-managed",
+			updater: updater{
+				awsAccountName: "main",
+				zones:          zones,
+				client:         r53MockClient{},
+			},
+			records: []dns.DomainRecord{
+				{
+					Type:  "AAAA",
+					Value: "::1",
+					FQDN:  "home.google.com",
+				},
+			},
+			expectedResults: []dns.UpdateResult{
+				{
+					Record: dns.DomainRecord{
+						Type:  "AAAA",
+						Value: "::1",
+						FQDN:  "home.google.com",
+					},
+					Status: dns.Skipped,
+				},
+			},
 		},
 	}
 
 	for _, tc := range testCases {
 		updater := tc.updater
-		expectedError := tc.expectedError
+		expectedResults := tc.expectedResults
 		records := tc.records
 
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
-			err := updater.UpdateDomains(ctx, records)
+			results := updater.UpdateDomains(ctx, records)
+
+			assert.Equal(t, expectedResults, results)
+		})
+	}
+}
+
+func Test_classify(t *testing.T) {
+	testCases := []struct {
+		name              string
+		err               error
+		expectedPermanent bool
+	}{
+		{
+			name:              "network-error",
+			err:               errors.New("connection reset"),
+			expectedPermanent: false,
+		},
+		{
+			name:              "invalid-change-batch",
+			err:               &smithy.GenericAPIError{Code: "InvalidChangeBatch", Fault: smithy.FaultClient},
+			expectedPermanent: true,
+		},
+		{
+			name:              "throttling",
+			err:               &smithy.GenericAPIError{Code: "Throttling", Fault: smithy.FaultClient},
+			expectedPermanent: false,
+		},
+		{
+			name:              "prior-request-not-complete",
+			err:               &smithy.GenericAPIError{Code: "PriorRequestNotComplete", Fault: smithy.FaultClient},
+			expectedPermanent: false,
+		},
+		{
+			name:              "server-fault",
+			err:               &smithy.GenericAPIError{Code: "ServiceUnavailable", Fault: smithy.FaultServer},
+			expectedPermanent: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := tc.err
+		expectedPermanent := tc.expectedPermanent
+
+		t.Run(tc.name, func(t *testing.T) {
+			classified := classify(err)
 
-			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedPermanent, dns.IsPermanent(classified))
+			assert.ErrorIs(t, classified, err)
 		})
 	}
 }
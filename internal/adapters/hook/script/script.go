@@ -0,0 +1,248 @@
+package script
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/hook"
+)
+
+const (
+	hooksPath          string = "ddns.hooks"
+	defaultTimeoutSecs int    = 30
+	envPrefix          string = "SIMPLE_DDNS_"
+
+	// waitDelay bounds how long output is read once a hook exits or is
+	// killed, children left behind holding its stdout don't block the sync.
+	waitDelay time.Duration = time.Second
+)
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warning(msg string)
+}
+
+type hooksConfig struct {
+	TimeoutSecs int             `yaml:"timeout-secs"`
+	PreUpdate   []commandConfig `yaml:"pre-update"`
+	PostUpdate  []commandConfig `yaml:"post-update"`
+	OnFailure   []commandConfig `yaml:"on-failure"`
+}
+
+type commandConfig struct {
+	Command     string   `yaml:"command"`
+	Args        []string `yaml:"args"`
+	TimeoutSecs int      `yaml:"timeout-secs"`
+}
+
+type command struct {
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+// payload is the json written to the stdin of every hook.
+type payload struct {
+	Stage   string          `json:"stage"`
+	Result  string          `json:"result"`
+	OldIPv4 string          `json:"old_ipv4,omitempty"`
+	OldIPv6 string          `json:"old_ipv6,omitempty"`
+	NewIPv4 string          `json:"new_ipv4,omitempty"`
+	NewIPv6 string          `json:"new_ipv6,omitempty"`
+	Records []payloadRecord `json:"records"`
+	Errors  []string        `json:"errors"`
+}
+
+type payloadRecord struct {
+	FQDN  string `json:"fqdn"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type runner struct {
+	commands map[hook.Stage][]command
+	logger   messageLogger
+}
+
+// New builds the hooks configured under ddns.hooks, nothing runs when the
+// node is missing. Commands are looked up in PATH unless they're paths, each
+// one is given its own timeout-secs or the shared one, 30 seconds by default.
+func New(cnf configDecoder, logger messageLogger) (hook.Runner, error) {
+	r := &runner{commands: map[hook.Stage][]command{}, logger: logger}
+
+	config := hooksConfig{}
+	if err := cnf.Decode(hooksPath, &config); err != nil {
+		return r, nil
+	}
+
+	if config.TimeoutSecs < 0 {
+		return nil, fmt.Errorf("script: invalid hooks timeout %d", config.TimeoutSecs)
+	}
+
+	if config.TimeoutSecs == 0 {
+		config.TimeoutSecs = defaultTimeoutSecs
+	}
+
+	stages := map[hook.Stage][]commandConfig{
+		hook.PreUpdate:  config.PreUpdate,
+		hook.PostUpdate: config.PostUpdate,
+		hook.OnFailure:  config.OnFailure,
+	}
+
+	for _, stage := range hook.Stages() {
+		for _, cmd := range stages[stage] {
+			path, err := exec.LookPath(cmd.Command)
+			if err != nil {
+				return nil, fmt.Errorf("script: invalid %s hook %s, err:%w", stage, cmd.Command, err)
+			}
+
+			if cmd.TimeoutSecs < 0 {
+				return nil, fmt.Errorf("script: invalid timeout %d for %s hook %s", cmd.TimeoutSecs, stage, cmd.Command)
+			}
+
+			timeout := config.TimeoutSecs
+			if cmd.TimeoutSecs > 0 {
+				timeout = cmd.TimeoutSecs
+			}
+
+			r.commands[stage] = append(r.commands[stage], command{
+				path:    path,
+				args:    cmd.Args,
+				timeout: time.Duration(timeout) * time.Second,
+			})
+		}
+	}
+
+	return r, nil
+}
+
+// Run runs the hooks of run.Stage one after the other, a failing hook doesn't
+// keep the next ones from running.
+func (r *runner) Run(ctx context.Context, run hook.Run) error {
+	commands := r.commands[run.Stage]
+	if len(commands) == 0 {
+		return nil
+	}
+
+	stdin, err := json.Marshal(newPayload(run))
+	if err != nil {
+		return err
+	}
+
+	env := append(os.Environ(), environment(run)...)
+
+	errs := make([]error, 0)
+	for _, cmd := range commands {
+		if err = r.exec(ctx, run.Stage, cmd, env, stdin); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(cmd.path), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *runner) exec(ctx context.Context, stage hook.Stage, cmd command, env []string, stdin []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, cmd.timeout)
+	defer cancel()
+
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	c := exec.CommandContext(ctx, cmd.path, cmd.args...)
+	c.Env = env
+	c.Stdin = bytes.NewReader(stdin)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	c.WaitDelay = waitDelay
+
+	start := time.Now()
+	err := c.Run()
+
+	name := filepath.Base(cmd.path)
+	r.log(r.logger.Info, stage, name, stdout.String())
+	r.log(r.logger.Warning, stage, name, stderr.String())
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", cmd.timeout)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.logger.Debug(fmt.Sprintf("script: %s hook %s finished in %s", stage, name, time.Since(start).Round(time.Millisecond)))
+	return nil
+}
+
+// log logs every line a hook wrote, prefixed with the hook it came from.
+func (r *runner) log(logf func(string), stage hook.Stage, name, output string) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			logf(fmt.Sprintf("script: %s hook %s: %s", stage, name, line))
+		}
+	}
+}
+
+func newPayload(run hook.Run) payload {
+	p := payload{
+		Stage:   string(run.Stage),
+		Result:  string(run.Result),
+		OldIPv4: valueOrEmpty(run.OldIP.V4),
+		OldIPv6: valueOrEmpty(run.OldIP.V6),
+		NewIPv4: valueOrEmpty(run.NewIP.V4),
+		NewIPv6: valueOrEmpty(run.NewIP.V6),
+		Records: make([]payloadRecord, 0, len(run.Records)),
+		Errors:  run.Errors,
+	}
+
+	if p.Errors == nil {
+		p.Errors = []string{}
+	}
+
+	for _, record := range run.Records {
+		p.Records = append(p.Records, payloadRecord{FQDN: record.FQDN, Type: string(record.Type), Value: record.Value})
+	}
+
+	return p
+}
+
+// environment returns the variables hooks get besides the daemon ones, record
+// fqdns are space separated so shell hooks can loop over them.
+func environment(run hook.Run) []string {
+	fqdns := make([]string, 0, len(run.Records))
+	for _, record := range run.Records {
+		fqdns = append(fqdns, record.FQDN)
+	}
+
+	return []string{
+		envPrefix + "STAGE=" + string(run.Stage),
+		envPrefix + "RESULT=" + string(run.Result),
+		envPrefix + "OLD_IPV4=" + valueOrEmpty(run.OldIP.V4),
+		envPrefix + "OLD_IPV6=" + valueOrEmpty(run.OldIP.V6),
+		envPrefix + "NEW_IPV4=" + valueOrEmpty(run.NewIP.V4),
+		envPrefix + "NEW_IPV6=" + valueOrEmpty(run.NewIP.V6),
+		envPrefix + "RECORDS=" + strings.Join(fqdns, " "),
+		envPrefix + "ERRORS=" + strings.Join(run.Errors, "; "),
+	}
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
@@ -0,0 +1,253 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/hook"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type configMock struct {
+	hooks *hooksConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	if c.hooks == nil {
+		return errors.New("node not found")
+	}
+
+	*item.(*hooksConfig) = *c.hooks
+	return nil
+}
+
+type loggerMock struct {
+	infos    []string
+	warnings []string
+}
+
+func (l *loggerMock) Debug(msg string)   {}
+func (l *loggerMock) Info(msg string)    { l.infos = append(l.infos, msg) }
+func (l *loggerMock) Warning(msg string) { l.warnings = append(l.warnings, msg) }
+
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755)
+	assert.NoError(t, err)
+
+	return path
+}
+
+func TestRun(t *testing.T) {
+	oldIP, newIP := "10.0.0.1", "10.0.0.2"
+	run := hook.Run{
+		Stage:  hook.PostUpdate,
+		Result: hook.Failure,
+		OldIP:  publicip.IP{V4: &oldIP},
+		NewIP:  publicip.IP{V4: &newIP},
+		Records: []dns.DomainRecord{
+			{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			{FQDN: "www.home.com.", Type: dns.A, Value: "10.0.0.2"},
+		},
+		Errors: []string{"throttled", "rejected"},
+	}
+
+	testCases := []struct {
+		name             string
+		script           string
+		timeoutSecs      int
+		expectedOutput   string
+		expectedInfos    []string
+		expectedWarnings []string
+		expectedError    string
+	}{
+		{
+			name:   "environment",
+			script: `env | grep ^SIMPLE_DDNS_ | sort > "$OUTPUT"`,
+			expectedOutput: strings.Join([]string{
+				"SIMPLE_DDNS_ERRORS=throttled; rejected",
+				"SIMPLE_DDNS_NEW_IPV4=10.0.0.2",
+				"SIMPLE_DDNS_NEW_IPV6=",
+				"SIMPLE_DDNS_OLD_IPV4=10.0.0.1",
+				"SIMPLE_DDNS_OLD_IPV6=",
+				"SIMPLE_DDNS_RECORDS=vpn.home.com. www.home.com.",
+				"SIMPLE_DDNS_RESULT=failure",
+				"SIMPLE_DDNS_STAGE=post-update",
+			}, "\n") + "\n",
+		},
+		{
+			name:   "stdin",
+			script: `cat > "$OUTPUT"`,
+			expectedOutput: `{"stage":"post-update","result":"failure","old_ipv4":"10.0.0.1","new_ipv4":"10.0.0.2",` +
+				`"records":[{"fqdn":"vpn.home.com.","type":"A","value":"10.0.0.2"},{"fqdn":"www.home.com.","type":"A","value":"10.0.0.2"}],` +
+				`"errors":["throttled","rejected"]}`,
+		},
+		{
+			name:             "output-logged",
+			script:           "echo reloading wireguard\necho\necho peer endpoint updated\necho missing peer >&2",
+			expectedInfos:    []string{"script: post-update hook hook.sh: reloading wireguard", "script: post-update hook hook.sh: peer endpoint updated"},
+			expectedWarnings: []string{"script: post-update hook hook.sh: missing peer"},
+		},
+		{
+			name:             "failing",
+			script:           "echo firewall locked >&2\nexit 3",
+			expectedWarnings: []string{"script: post-update hook hook.sh: firewall locked"},
+			expectedError:    "hook.sh: exit status 3",
+		},
+		{
+			name:          "timed-out",
+			script:        "sleep 5",
+			timeoutSecs:   1,
+			expectedError: "hook.sh: timed out after 1s",
+		},
+	}
+
+	for _, tc := range testCases {
+		script := tc.script
+		timeoutSecs := tc.timeoutSecs
+		expectedOutput := tc.expectedOutput
+		expectedInfos := tc.expectedInfos
+		expectedWarnings := tc.expectedWarnings
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			output := filepath.Join(dir, "output")
+			t.Setenv("OUTPUT", output)
+
+			hooks := hooksConfig{
+				PostUpdate: []commandConfig{{Command: writeScript(t, dir, "hook.sh", script), TimeoutSecs: timeoutSecs}},
+			}
+			logger := &loggerMock{}
+			r, err := New(configMock{hooks: &hooks}, logger)
+			assert.NoError(t, err)
+
+			start := time.Now()
+			err = r.Run(context.Background(), run)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Less(t, time.Since(start), 4*time.Second)
+
+			if expectedOutput != "" {
+				got, err := os.ReadFile(output)
+				assert.NoError(t, err)
+				assert.Equal(t, expectedOutput, string(got))
+			}
+
+			assert.Equal(t, expectedInfos, logger.infos)
+			assert.Equal(t, expectedWarnings, logger.warnings)
+		})
+	}
+}
+
+func TestRunStages(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	t.Setenv("OUTPUT", output)
+
+	hooks := hooksConfig{
+		PreUpdate: []commandConfig{{Command: writeScript(t, dir, "pre.sh", `echo pre "$@" >> "$OUTPUT"`), Args: []string{"--stop"}}},
+		PostUpdate: []commandConfig{
+			{Command: writeScript(t, dir, "failing.sh", "exit 1")},
+			{Command: writeScript(t, dir, "post.sh", `echo post "$@" >> "$OUTPUT"`), Args: []string{"--start"}},
+		},
+	}
+	r, err := New(configMock{hooks: &hooks}, &loggerMock{})
+	assert.NoError(t, err)
+
+	for _, stage := range hook.Stages() {
+		err = r.Run(context.Background(), hook.Run{Stage: stage})
+		if stage == hook.PostUpdate {
+			assert.EqualError(t, err, "failing.sh: exit status 1")
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	got, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "pre --stop\npost --start\n", string(got))
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, dir, "hook.sh", "true")
+
+	testCases := []struct {
+		name             string
+		hooks            *hooksConfig
+		expectedCommands map[hook.Stage][]command
+		expectedError    string
+	}{
+		{
+			name:             "not-configured",
+			expectedCommands: map[hook.Stage][]command{},
+		},
+		{
+			name: "default-timeout",
+			hooks: &hooksConfig{
+				PreUpdate: []commandConfig{{Command: script, Args: []string{"down"}}},
+				OnFailure: []commandConfig{{Command: script, TimeoutSecs: 5}},
+			},
+			expectedCommands: map[hook.Stage][]command{
+				hook.PreUpdate: {{path: script, args: []string{"down"}, timeout: 30 * time.Second}},
+				hook.OnFailure: {{path: script, timeout: 5 * time.Second}},
+			},
+		},
+		{
+			name: "shared-timeout",
+			hooks: &hooksConfig{
+				TimeoutSecs: 10,
+				PostUpdate:  []commandConfig{{Command: script}},
+			},
+			expectedCommands: map[hook.Stage][]command{
+				hook.PostUpdate: {{path: script, timeout: 10 * time.Second}},
+			},
+		},
+		{
+			name:          "invalid-timeout",
+			hooks:         &hooksConfig{TimeoutSecs: -1},
+			expectedError: "script: invalid hooks timeout -1",
+		},
+		{
+			name:          "invalid-hook-timeout",
+			hooks:         &hooksConfig{OnFailure: []commandConfig{{Command: script, TimeoutSecs: -5}}},
+			expectedError: "script: invalid timeout -5 for on-failure hook " + script,
+		},
+		{
+			name:          "missing-command",
+			hooks:         &hooksConfig{PostUpdate: []commandConfig{{Command: filepath.Join(dir, "missing.sh")}}},
+			expectedError: "script: invalid post-update hook " + filepath.Join(dir, "missing.sh") + ", err:exec: \"" + filepath.Join(dir, "missing.sh") + "\": stat " + filepath.Join(dir, "missing.sh") + ": no such file or directory",
+		},
+	}
+
+	for _, tc := range testCases {
+		hooks := tc.hooks
+		expectedCommands := tc.expectedCommands
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(configMock{hooks: hooks}, &loggerMock{})
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedCommands, r.(*runner).commands)
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/app/reconciler"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+const (
+	adminPrefix         string = "/api/v1"
+	defaultHistoryLimit int    = 20
+	maxHistoryLimit     int    = 1000
+)
+
+type admin interface {
+	Records(ctx context.Context) ([]dns.RecordState, error)
+	LastStatus(ctx context.Context) (reconciler.Status, error)
+	History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error)
+	ForceSync(ctx context.Context, fqdn string) (reconciler.Report, error)
+	Pause(ctx context.Context, fqdn string, recordType dns.RecordType) error
+	Resume(ctx context.Context, fqdn string, recordType dns.RecordType) error
+	Providers() []reconciler.ProviderHealth
+}
+
+type recordResponse struct {
+	FQDN      string     `json:"fqdn"`
+	Type      string     `json:"type"`
+	Provider  string     `json:"provider"`
+	Zone      string     `json:"zone,omitempty"`
+	Value     string     `json:"value,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Paused    bool       `json:"paused"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+type statusRecordResponse struct {
+	recordResponse
+	Desired string `json:"desired,omitempty"`
+	InSync  bool   `json:"in_sync"`
+}
+
+type statusResponse struct {
+	IPv4    string                 `json:"ipv4,omitempty"`
+	IPv6    string                 `json:"ipv6,omitempty"`
+	Source  string                 `json:"source,omitempty"`
+	Records []statusRecordResponse `json:"records"`
+}
+
+type historyResponse struct {
+	FQDN      string    `json:"fqdn"`
+	Type      string    `json:"type"`
+	Provider  string    `json:"provider,omitempty"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Active    bool      `json:"active"`
+}
+
+type valueResponse struct {
+	FQDN  string `json:"fqdn"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type syncResponse struct {
+	Updated []valueResponse `json:"updated"`
+	Failed  []valueResponse `json:"failed"`
+	Errors  []string        `json:"errors"`
+}
+
+type providerResponse struct {
+	Provider    string     `json:"provider"`
+	Records     int        `json:"records"`
+	Healthy     bool       `json:"healthy"`
+	LastUpdate  *time.Time `json:"last_update,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Failed      int        `json:"failed"`
+	DurationMs  int64      `json:"duration_ms"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// HandleAdmin registers the admin api under /api/v1. When an admin token is
+// configured every request must carry it as a bearer token, without one the
+// routes changing state are only served on a loopback listen address and to
+// requests addressed to a loopback host.
+func (s *server) HandleAdmin(a admin) {
+	routes := map[string]http.HandlerFunc{
+		"GET /records":                func(w http.ResponseWriter, r *http.Request) { listRecords(w, r, a) },
+		"GET /records/{fqdn}/history": func(w http.ResponseWriter, r *http.Request) { recordHistory(w, r, a) },
+		"POST /records/{fqdn}/sync":   func(w http.ResponseWriter, r *http.Request) { forceSync(w, r, a, r.PathValue("fqdn")) },
+		"POST /records/{fqdn}/pause":  func(w http.ResponseWriter, r *http.Request) { setPaused(w, r, a.Pause) },
+		"POST /records/{fqdn}/resume": func(w http.ResponseWriter, r *http.Request) { setPaused(w, r, a.Resume) },
+		"POST /sync":                  func(w http.ResponseWriter, r *http.Request) { forceSync(w, r, a, "") },
+		"GET /status":                 func(w http.ResponseWriter, r *http.Request) { status(w, r, a) },
+		"GET /providers":              func(w http.ResponseWriter, r *http.Request) { providers(w, a) },
+	}
+
+	writable := s.adminToken != "" || isLoopback(s.address)
+	if !writable {
+		s.logger.Info(fmt.Sprintf("server: no admin-token set, admin api routes changing state are disabled on %s", s.address))
+	}
+
+	for route, handler := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		if method != http.MethodGet {
+			handler = sameOrigin(handler)
+			switch {
+			case !writable:
+				handler = forbidden
+			case s.adminToken == "":
+				handler = s.loopbackOnly(handler)
+			}
+		}
+
+		s.mux.Handle(method+" "+adminPrefix+path, s.authorize(handler, "Bearer"))
+	}
+}
+
+// sameOrigin rejects requests sent by pages of other sites, browsers attach
+// the basic auth credentials of the dashboard to them. Clients other than
+// browsers send neither Sec-Fetch-Site nor Origin and aren't affected.
+func sameOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if crossOrigin(r) {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "cross origin requests are not allowed"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func crossOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return false
+	case "":
+	default:
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// loopbackOnly rejects requests addressed to any host other than the loopback
+// or the listen address. Pages of other sites rebinding their own name to the
+// loopback pass the same origin check, but send that name as Host.
+func (s *server) loopbackOnly(next http.HandlerFunc) http.HandlerFunc {
+	listenHost, _, _ := net.SplitHostPort(s.address)
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+
+		if !loopbackHost(host) && !strings.EqualFold(host, listenHost) {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "requests changing state must be addressed to a loopback host without an admin token"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusForbidden, errorResponse{Error: "an admin token is required to change state unless listening on loopback"})
+}
+
+// isLoopback tells whether address only accepts connections from this host.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	return err == nil && loopbackHost(host)
+}
+
+func loopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	return err == nil && ip.IsLoopback()
+}
+
+// authorize requires the admin token, when configured, either as a bearer
+// token or as the password of basic auth so browsers can prompt for it.
+// challenge is the scheme announced to clients that didn't send it.
+func (s *server) authorize(next http.Handler, challenge string) http.Handler {
+	if s.adminToken == "" {
+		return next
+	}
+
+	expected := []byte(s.adminToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if _, password, ok := r.BasicAuth(); ok {
+			token = password
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", challenge+` realm="simple-ddns"`)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid or missing admin token"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func listRecords(w http.ResponseWriter, r *http.Request, a admin) {
+	states, err := a.Records(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := make([]recordResponse, 0, len(states))
+	for _, state := range states {
+		response = append(response, newRecordResponse(state))
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// status compares the records with the ip found by the last cycle, requests
+// never make the daemon query the public ip sources.
+func status(w http.ResponseWriter, r *http.Request, a admin) {
+	st, err := a.LastStatus(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := statusResponse{
+		IPv4:    valueOrEmpty(st.IP.V4),
+		IPv6:    valueOrEmpty(st.IP.V6),
+		Source:  st.IP.Source,
+		Records: make([]statusRecordResponse, 0, len(st.Records)),
+	}
+	for _, state := range st.Records {
+		response.Records = append(response.Records, statusRecordResponse{
+			recordResponse: newRecordResponse(state),
+			Desired:        state.Desired,
+			InSync:         state.InSync,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func recordHistory(w http.ResponseWriter, r *http.Request, a admin) {
+	limit := defaultHistoryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxHistoryLimit {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("limit must be between 1 and %d", maxHistoryLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := a.History(r.Context(), r.PathValue("fqdn"), limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := make([]historyResponse, 0, len(entries))
+	for _, entry := range entries {
+		response = append(response, historyResponse{
+			FQDN:      entry.Record.FQDN,
+			Type:      string(entry.Record.Type),
+			Provider:  entry.Provider,
+			Value:     entry.Record.Value,
+			UpdatedAt: entry.UpdateTime,
+			Active:    entry.Active,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func forceSync(w http.ResponseWriter, r *http.Request, a admin, fqdn string) {
+	// a client giving up shouldn't abort updates already sent to providers.
+	report, err := a.ForceSync(context.WithoutCancel(r.Context()), fqdn)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := syncResponse{
+		Updated: valueResponses(report.Updated),
+		Failed:  valueResponses(report.Failed),
+		Errors:  make([]string, 0, len(report.Errors)),
+	}
+	for _, err := range report.Errors {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func setPaused(w http.ResponseWriter, r *http.Request, action func(context.Context, string, dns.RecordType) error) {
+	recordType := dns.RecordType(strings.ToUpper(r.URL.Query().Get("type")))
+	if recordType != "" && recordType != dns.A && recordType != dns.AAAA {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("unsupported record type %s", recordType)})
+		return
+	}
+
+	if err := action(r.Context(), r.PathValue("fqdn"), recordType); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func providers(w http.ResponseWriter, a admin) {
+	health := a.Providers()
+
+	response := make([]providerResponse, 0, len(health))
+	for _, provider := range health {
+		response = append(response, providerResponse{
+			Provider:    provider.Provider,
+			Records:     provider.Records,
+			Healthy:     provider.Healthy,
+			LastUpdate:  timeOrNil(provider.LastUpdate),
+			LastSuccess: timeOrNil(provider.LastSuccess),
+			LastError:   provider.LastError,
+			Failed:      provider.Failed,
+			DurationMs:  provider.Duration.Milliseconds(),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, reconciler.ErrUnknownRecord):
+		status = http.StatusNotFound
+	case errors.Is(err, reconciler.ErrNoPublicIP):
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func newRecordResponse(state dns.RecordState) recordResponse {
+	return recordResponse{
+		FQDN:      state.Record.FQDN,
+		Type:      string(state.Record.Type),
+		Provider:  state.Provider,
+		Zone:      state.Zone,
+		Value:     state.Record.Value,
+		UpdatedAt: timeOrNil(state.UpdateTime),
+		Paused:    state.Paused,
+		LastError: state.LastError,
+	}
+}
+
+func valueResponses(records []dns.DomainRecord) []valueResponse {
+	response := make([]valueResponse, 0, len(records))
+	for _, record := range records {
+		response = append(response, valueResponse{FQDN: record.FQDN, Type: string(record.Type), Value: record.Value})
+	}
+
+	return response
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/app/reconciler"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+	"github.com/stretchr/testify/assert"
+)
+
+type adminMock struct {
+	states    []dns.RecordState
+	history   []ddns.HistoryEntry
+	report    reconciler.Report
+	providers []reconciler.ProviderHealth
+	err       error
+	calls     []string
+}
+
+func (a *adminMock) Records(ctx context.Context) ([]dns.RecordState, error) {
+	return a.states, a.err
+}
+
+func (a *adminMock) LastStatus(ctx context.Context) (reconciler.Status, error) {
+	return reconciler.Status{IP: ipv4("10.0.0.2"), Records: a.states}, a.err
+}
+
+func (a *adminMock) History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error) {
+	a.calls = append(a.calls, fmt.Sprintf("history %s %d", fqdn, limit))
+	return a.history, a.err
+}
+
+func (a *adminMock) ForceSync(ctx context.Context, fqdn string) (reconciler.Report, error) {
+	a.calls = append(a.calls, fmt.Sprintf("sync %s", fqdn))
+	return a.report, a.err
+}
+
+func (a *adminMock) Pause(ctx context.Context, fqdn string, recordType dns.RecordType) error {
+	a.calls = append(a.calls, fmt.Sprintf("pause %s %s", fqdn, recordType))
+	return a.err
+}
+
+func (a *adminMock) Resume(ctx context.Context, fqdn string, recordType dns.RecordType) error {
+	a.calls = append(a.calls, fmt.Sprintf("resume %s %s", fqdn, recordType))
+	return a.err
+}
+
+func (a *adminMock) Providers() []reconciler.ProviderHealth {
+	return a.providers
+}
+
+func TestHandleAdmin(t *testing.T) {
+	updated := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	states := []dns.RecordState{
+		{
+			Record:     dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+			Provider:   "aws/main",
+			UpdateTime: updated,
+			Desired:    "10.0.0.2",
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		listen         string
+		host           string
+		token          string
+		header         string
+		origin         string
+		fetchSite      string
+		admin          *adminMock
+		expectedStatus int
+		expectedBody   string
+		expectedCalls  []string
+	}{
+		{
+			name:           "records",
+			method:         http.MethodGet,
+			path:           "/api/v1/records",
+			admin:          &adminMock{states: states},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"fqdn":"vpn.home.com.","type":"A","provider":"aws/main","value":"10.0.0.1","updated_at":"2025-01-01T10:00:00Z","paused":false}]`,
+		},
+		{
+			name:           "status",
+			method:         http.MethodGet,
+			path:           "/api/v1/status",
+			admin:          &adminMock{states: states},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"ipv4":"10.0.0.2","records":[{"fqdn":"vpn.home.com.","type":"A","provider":"aws/main","value":"10.0.0.1",` +
+				`"updated_at":"2025-01-01T10:00:00Z","paused":false,"desired":"10.0.0.2","in_sync":false}]}`,
+		},
+		{
+			name:   "history",
+			method: http.MethodGet,
+			path:   "/api/v1/records/vpn.home.com/history?limit=5",
+			admin: &adminMock{history: []ddns.HistoryEntry{
+				{Provider: "aws/main", Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"}, UpdateTime: updated, Active: true},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"fqdn":"vpn.home.com.","type":"A","provider":"aws/main","value":"10.0.0.1","updated_at":"2025-01-01T10:00:00Z","active":true}]`,
+			expectedCalls:  []string{"history vpn.home.com 5"},
+		},
+		{
+			name:           "history-invalid-limit",
+			method:         http.MethodGet,
+			path:           "/api/v1/records/vpn.home.com/history?limit=0",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"limit must be between 1 and 1000"}`,
+		},
+		{
+			name:   "sync-all",
+			method: http.MethodPost,
+			path:   "/api/v1/sync",
+			admin: &adminMock{report: reconciler.Report{
+				Updated: []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+				Failed:  []dns.DomainRecord{{FQDN: "www.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+				Errors:  []error{errors.New("throttled")},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"updated":[{"fqdn":"vpn.home.com.","type":"A","value":"10.0.0.2"}],` +
+				`"failed":[{"fqdn":"www.home.com.","type":"A","value":"10.0.0.2"}],"errors":["throttled"]}`,
+			expectedCalls: []string{"sync "},
+		},
+		{
+			name:           "sync-unknown-record",
+			method:         http.MethodPost,
+			path:           "/api/v1/records/www.home.com/sync",
+			admin:          &adminMock{err: fmt.Errorf("reconciler: %w: www.home.com", reconciler.ErrUnknownRecord)},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"reconciler: record not managed by any provider: www.home.com"}`,
+			expectedCalls:  []string{"sync www.home.com"},
+		},
+		{
+			name:           "pause",
+			method:         http.MethodPost,
+			path:           "/api/v1/records/vpn.home.com/pause?type=aaaa",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusNoContent,
+			expectedCalls:  []string{"pause vpn.home.com AAAA"},
+		},
+		{
+			name:           "resume-invalid-type",
+			method:         http.MethodPost,
+			path:           "/api/v1/records/vpn.home.com/resume?type=MX",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"unsupported record type MX"}`,
+		},
+		{
+			name:   "providers",
+			method: http.MethodGet,
+			path:   "/api/v1/providers",
+			admin: &adminMock{providers: []reconciler.ProviderHealth{
+				{Provider: "aws/main", Records: 2, LastUpdate: updated, LastError: "throttled", Failed: 1, Duration: 1500 * time.Millisecond},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody: `[{"provider":"aws/main","records":2,"healthy":false,"last_update":"2025-01-01T10:00:00Z",` +
+				`"last_error":"throttled","failed":1,"duration_ms":1500}]`,
+		},
+		{
+			name:           "missing-token",
+			method:         http.MethodPost,
+			path:           "/api/v1/sync",
+			token:          "s3cr3t",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"invalid or missing admin token"}`,
+		},
+		{
+			name:           "valid-token",
+			method:         http.MethodPost,
+			path:           "/api/v1/records/vpn.home.com/resume",
+			token:          "s3cr3t",
+			header:         "Bearer s3cr3t",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusNoContent,
+			expectedCalls:  []string{"resume vpn.home.com "},
+		},
+		{
+			name:           "no-token-public-listener",
+			method:         http.MethodPost,
+			path:           "/api/v1/sync",
+			listen:         "0.0.0.0:0",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"an admin token is required to change state unless listening on loopback"}`,
+		},
+		{
+			name:           "no-token-public-listener-read",
+			method:         http.MethodGet,
+			path:           "/api/v1/providers",
+			listen:         ":0",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name:           "token-public-listener",
+			method:         http.MethodPost,
+			path:           "/api/v1/records/vpn.home.com/pause",
+			listen:         "0.0.0.0:0",
+			token:          "s3cr3t",
+			header:         "Bearer s3cr3t",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusNoContent,
+			expectedCalls:  []string{"pause vpn.home.com "},
+		},
+		{
+			name:           "cross-site-basic-auth",
+			method:         http.MethodPost,
+			path:           "/api/v1/sync",
+			token:          "s3cr3t",
+			header:         "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:s3cr3t")),
+			fetchSite:      "cross-site",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"cross origin requests are not allowed"}`,
+		},
+		{
+			name:           "cross-origin-without-fetch-metadata",
+			method:         http.MethodPost,
+			path:           "/api/v1/records/vpn.home.com/pause",
+			origin:         "https://evil.example.com",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"cross origin requests are not allowed"}`,
+		},
+		{
+			name:           "rebound-host-without-token",
+			method:         http.MethodPost,
+			path:           "/api/v1/sync",
+			host:           "rebind.example.com:8080",
+			origin:         "http://rebind.example.com:8080",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"requests changing state must be addressed to a loopback host without an admin token"}`,
+		},
+		{
+			name:           "rebound-host-read-without-token",
+			method:         http.MethodGet,
+			path:           "/api/v1/providers",
+			host:           "rebind.example.com:8080",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name:           "localhost-without-token",
+			method:         http.MethodPost,
+			path:           "/api/v1/sync",
+			host:           "localhost:8080",
+			origin:         "http://localhost:8080",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"updated":[],"failed":[],"errors":[]}`,
+			expectedCalls:  []string{"sync "},
+		},
+		{
+			name:           "same-origin",
+			method:         http.MethodPost,
+			path:           "/api/v1/sync",
+			origin:         "http://example.com",
+			fetchSite:      "same-origin",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"updated":[],"failed":[],"errors":[]}`,
+			expectedCalls:  []string{"sync "},
+		},
+		{
+			name:           "cross-site-read",
+			method:         http.MethodGet,
+			path:           "/api/v1/providers",
+			fetchSite:      "cross-site",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name:           "wrong-method",
+			method:         http.MethodGet,
+			path:           "/api/v1/sync",
+			admin:          &adminMock{},
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		method := tc.method
+		path := tc.path
+		listen := tc.listen
+		host := tc.host
+		token := tc.token
+		header := tc.header
+		origin := tc.origin
+		fetchSite := tc.fetchSite
+		a := tc.admin
+		expectedStatus := tc.expectedStatus
+		expectedBody := tc.expectedBody
+		expectedCalls := tc.expectedCalls
+
+		t.Run(tc.name, func(t *testing.T) {
+			if listen == "" {
+				listen = "127.0.0.1:0"
+			}
+
+			srv, err := New(configMock{values: map[string]any{httpPath: httpConfig{Listen: listen, AdminToken: token}}}, loggerMock{})
+			assert.NoError(t, err)
+			srv.HandleAdmin(a)
+
+			req := httptest.NewRequest(method, path, nil)
+			req.Host = host
+			if host == "" {
+				req.Host = "127.0.0.1:8080"
+			}
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+
+			if origin != "" {
+				req.Header.Set("Origin", origin)
+			}
+
+			if fetchSite != "" {
+				req.Header.Set("Sec-Fetch-Site", fetchSite)
+			}
+
+			rec := httptest.NewRecorder()
+			srv.mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, expectedStatus, rec.Code)
+			if expectedBody != "" {
+				assert.JSONEq(t, expectedBody, rec.Body.String())
+			}
+			assert.Equal(t, expectedCalls, a.calls)
+		})
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	testCases := map[string]bool{
+		"127.0.0.1:8080": true,
+		"[::1]:8080":     true,
+		"localhost:8080": true,
+		"0.0.0.0:8080":   false,
+		":8080":          false,
+		"[::]:8080":      false,
+		"10.0.0.2:8080":  false,
+		"invalid":        false,
+	}
+
+	for address, expected := range testCases {
+		assert.Equal(t, expected, isLoopback(address), address)
+	}
+}
+
+func ipv4(value string) publicip.IP {
+	return publicip.IP{V4: &value}
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+// dashboardHistoryLimit bounds the history rows read to build the timeline,
+// every record adds a row per cycle so it covers a few hundred ip changes.
+const dashboardHistoryLimit int = 1000
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+var dashboardTemplate = template.Must(template.New("index.html").Funcs(template.FuncMap{
+	"datetime": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+
+		return t.Local().Format("2006-01-02 15:04:05 MST")
+	},
+	"dash": func(value string) string {
+		if value == "" {
+			return "-"
+		}
+
+		return value
+	},
+}).ParseFS(dashboardFiles, "dashboard/index.html"))
+
+type dashboardSource interface {
+	Records(ctx context.Context) ([]dns.RecordState, error)
+	History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error)
+	LastIP() (publicip.IP, time.Time)
+}
+
+type dashboardRecord struct {
+	FQDN      string
+	Type      string
+	Provider  string
+	Zone      string
+	Value     string
+	Since     time.Time
+	Paused    bool
+	LastError string
+}
+
+type ipChange struct {
+	Type    string
+	Value   string
+	At      time.Time
+	Records []string
+}
+
+type dashboardView struct {
+	IPv4        string
+	IPv6        string
+	Source      string
+	DetectedAt  time.Time
+	Records     []dashboardRecord
+	Changes     []ipChange
+	GeneratedAt time.Time
+}
+
+// HandleDashboard serves a read only status page at / when the dashboard is
+// enabled, it's protected by the admin token like the admin api.
+func (s *server) HandleDashboard(source dashboardSource) {
+	if !s.dashboard {
+		return
+	}
+
+	static, _ := fs.Sub(dashboardFiles, "dashboard")
+	s.mux.Handle("GET /static/", s.authorize(http.FileServerFS(static), "Basic"))
+	s.mux.Handle("GET /{$}", s.authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		view, err := newDashboardView(r.Context(), source)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err = dashboardTemplate.Execute(w, view); err != nil {
+			s.logger.Error(err)
+		}
+	}), "Basic"))
+}
+
+func newDashboardView(ctx context.Context, source dashboardSource) (dashboardView, error) {
+	ip, detectedAt := source.LastIP()
+	view := dashboardView{
+		IPv4:        valueOrEmpty(ip.V4),
+		IPv6:        valueOrEmpty(ip.V6),
+		Source:      ip.Source,
+		DetectedAt:  detectedAt,
+		GeneratedAt: time.Now(),
+	}
+
+	states, err := source.Records(ctx)
+	if err != nil {
+		return view, err
+	}
+
+	history, err := source.History(ctx, "", dashboardHistoryLimit)
+	if err != nil {
+		return view, err
+	}
+
+	for _, state := range states {
+		view.Records = append(view.Records, dashboardRecord{
+			FQDN:      state.Record.FQDN,
+			Type:      string(state.Record.Type),
+			Provider:  state.Provider,
+			Zone:      state.Zone,
+			Value:     state.Record.Value,
+			Since:     since(state, history),
+			Paused:    state.Paused,
+			LastError: state.LastError,
+		})
+	}
+	view.Changes = ipChanges(history)
+
+	return view, nil
+}
+
+// since returns when the record started pointing at its current value. The
+// value is stored again on every push, so the oldest of the newest entries
+// holding it is used.
+func since(state dns.RecordState, history []ddns.HistoryEntry) time.Time {
+	at := state.UpdateTime
+	for _, entry := range history {
+		if entry.Record.FQDN != state.Record.FQDN || entry.Record.Type != state.Record.Type {
+			continue
+		}
+
+		if entry.Provider != "" && entry.Provider != state.Provider {
+			continue
+		}
+
+		if entry.Record.Value != state.Record.Value {
+			break
+		}
+		at = entry.UpdateTime
+	}
+
+	return at
+}
+
+// ipChanges groups history, newest first, into the moments each address
+// family moved to a new value along with the records that followed it.
+func ipChanges(history []ddns.HistoryEntry) []ipChange {
+	changes := make([]ipChange, 0)
+	current := make(map[dns.RecordType]int)
+
+	for _, entry := range history {
+		i, ok := current[entry.Record.Type]
+		if !ok || changes[i].Value != entry.Record.Value {
+			changes = append(changes, ipChange{Type: string(entry.Record.Type), Value: entry.Record.Value})
+			i = len(changes) - 1
+			current[entry.Record.Type] = i
+		}
+
+		changes[i].At = entry.UpdateTime
+		if !slices.Contains(changes[i].Records, entry.Record.FQDN) {
+			changes[i].Records = append(changes[i].Records, entry.Record.FQDN)
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b ipChange) int {
+		return b.At.Compare(a.At)
+	})
+
+	return changes
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+	"github.com/stretchr/testify/assert"
+)
+
+type dashboardMock struct {
+	states  []dns.RecordState
+	history []ddns.HistoryEntry
+}
+
+func (d dashboardMock) Records(ctx context.Context) ([]dns.RecordState, error) {
+	return d.states, nil
+}
+
+func (d dashboardMock) History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error) {
+	return d.history, nil
+}
+
+func (d dashboardMock) LastIP() (publicip.IP, time.Time) {
+	ip := ipv4("10.0.0.2")
+	ip.Source = "ipify"
+
+	return ip, time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)
+}
+
+func historyEntry(fqdn string, recordType dns.RecordType, value string, day int) ddns.HistoryEntry {
+	return ddns.HistoryEntry{
+		Record:     dns.DomainRecord{FQDN: fqdn, Type: recordType, Value: value},
+		UpdateTime: time.Date(2025, 1, day, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestIPChanges(t *testing.T) {
+	history := []ddns.HistoryEntry{
+		historyEntry("vpn.home.com.", dns.A, "10.0.0.2", 4),
+		historyEntry("vpn6.home.com.", dns.AAAA, "fd00::2", 4),
+		historyEntry("www.home.com.", dns.A, "10.0.0.2", 3),
+		historyEntry("vpn.home.com.", dns.A, "10.0.0.2", 3),
+		historyEntry("vpn.home.com.", dns.A, "10.0.0.1", 1),
+		historyEntry("vpn6.home.com.", dns.AAAA, "fd00::1", 2),
+	}
+
+	assert.Equal(t, []ipChange{
+		{Type: "AAAA", Value: "fd00::2", At: history[1].UpdateTime, Records: []string{"vpn6.home.com."}},
+		{Type: "A", Value: "10.0.0.2", At: history[3].UpdateTime, Records: []string{"vpn.home.com.", "www.home.com."}},
+		{Type: "AAAA", Value: "fd00::1", At: history[5].UpdateTime, Records: []string{"vpn6.home.com."}},
+		{Type: "A", Value: "10.0.0.1", At: history[4].UpdateTime, Records: []string{"vpn.home.com."}},
+	}, ipChanges(history))
+}
+
+func TestSince(t *testing.T) {
+	history := []ddns.HistoryEntry{
+		historyEntry("vpn.home.com.", dns.A, "10.0.0.2", 4),
+		historyEntry("www.home.com.", dns.A, "10.0.0.2", 3),
+		historyEntry("vpn.home.com.", dns.A, "10.0.0.2", 3),
+		historyEntry("vpn.home.com.", dns.A, "10.0.0.1", 1),
+	}
+
+	testCases := []struct {
+		name          string
+		state         dns.RecordState
+		expectedSince time.Time
+	}{
+		{
+			name: "stored-several-times",
+			state: dns.RecordState{
+				Record:     dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+				UpdateTime: history[0].UpdateTime,
+			},
+			expectedSince: history[2].UpdateTime,
+		},
+		{
+			name: "not-in-history",
+			state: dns.RecordState{
+				Record:     dns.DomainRecord{FQDN: "ftp.home.com.", Type: dns.A, Value: "10.0.0.2"},
+				UpdateTime: history[0].UpdateTime,
+			},
+			expectedSince: history[0].UpdateTime,
+		},
+	}
+
+	for _, tc := range testCases {
+		state := tc.state
+		expectedSince := tc.expectedSince
+
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, expectedSince, since(state, history))
+		})
+	}
+}
+
+func TestHandleDashboard(t *testing.T) {
+	source := dashboardMock{
+		states: []dns.RecordState{
+			{
+				Record:    dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+				Provider:  "aws/main",
+				Zone:      "Z0123456789",
+				LastError: "throttled",
+				Paused:    true,
+			},
+		},
+		history: []ddns.HistoryEntry{historyEntry("vpn.home.com.", dns.A, "10.0.0.2", 3)},
+	}
+
+	testCases := []struct {
+		name             string
+		config           httpConfig
+		path             string
+		password         string
+		expectedStatus   int
+		expectedContains []string
+	}{
+		{
+			name:           "disabled",
+			config:         httpConfig{Listen: "127.0.0.1:0"},
+			path:           "/",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:             "page",
+			config:           httpConfig{Listen: "127.0.0.1:0", Dashboard: true},
+			path:             "/",
+			expectedStatus:   http.StatusOK,
+			expectedContains: []string{"10.0.0.2", "detected by ipify", "vpn.home.com.", "aws/main", "Z0123456789", "throttled", "paused"},
+		},
+		{
+			name:             "stylesheet",
+			config:           httpConfig{Listen: "127.0.0.1:0", Dashboard: true},
+			path:             "/static/style.css",
+			expectedStatus:   http.StatusOK,
+			expectedContains: []string{"font-family"},
+		},
+		{
+			name:           "unknown-path",
+			config:         httpConfig{Listen: "127.0.0.1:0", Dashboard: true},
+			path:           "/other",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "missing-token",
+			config:         httpConfig{Listen: "127.0.0.1:0", Dashboard: true, AdminToken: "s3cr3t"},
+			path:           "/",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:             "basic-auth",
+			config:           httpConfig{Listen: "127.0.0.1:0", Dashboard: true, AdminToken: "s3cr3t"},
+			path:             "/",
+			password:         "s3cr3t",
+			expectedStatus:   http.StatusOK,
+			expectedContains: []string{"vpn.home.com."},
+		},
+	}
+
+	for _, tc := range testCases {
+		hc := tc.config
+		path := tc.path
+		password := tc.password
+		expectedStatus := tc.expectedStatus
+		expectedContains := tc.expectedContains
+
+		t.Run(tc.name, func(t *testing.T) {
+			srv, err := New(configMock{values: map[string]any{httpPath: hc}}, loggerMock{})
+			assert.NoError(t, err)
+			srv.HandleDashboard(source)
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if password != "" {
+				req.SetBasicAuth("admin", password)
+			}
+
+			rec := httptest.NewRecorder()
+			srv.mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, expectedStatus, rec.Code)
+			for _, expected := range expectedContains {
+				assert.Contains(t, rec.Body.String(), expected)
+			}
+
+			if expectedStatus == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="simple-ddns"`, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
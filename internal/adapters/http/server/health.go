@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/app/reconciler"
+)
+
+const probeTimeout time.Duration = 2 * time.Second
+
+type healthChecker interface {
+	Health(ctx context.Context) reconciler.Health
+}
+
+type checkResponse struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type readinessResponse struct {
+	Status      string          `json:"status"`
+	LastSync    *time.Time      `json:"last_sync,omitempty"`
+	LastSuccess *time.Time      `json:"last_success,omitempty"`
+	Checks      []checkResponse `json:"checks"`
+}
+
+// HandleHealth registers /healthz, answering as long as the process serves
+// requests, and /readyz, answering 503 while checker isn't ready.
+func (s *server) HandleHealth(checker healthChecker) {
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	s.mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+		defer cancel()
+
+		health := checker.Health(ctx)
+
+		response := readinessResponse{
+			Status:      "ready",
+			LastSync:    timeOrNil(health.LastSync),
+			LastSuccess: timeOrNil(health.LastSuccess),
+			Checks:      make([]checkResponse, 0, len(health.Checks)),
+		}
+		for _, check := range health.Checks {
+			response.Checks = append(response.Checks, checkResponse(check))
+		}
+
+		status := http.StatusOK
+		if !health.Ready {
+			response.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, status, response)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/app/reconciler"
+	"github.com/stretchr/testify/assert"
+)
+
+type healthMock struct {
+	health reconciler.Health
+}
+
+func (h healthMock) Health(ctx context.Context) reconciler.Health {
+	return h.health
+}
+
+func TestHandleHealth(t *testing.T) {
+	lastSync := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		path           string
+		health         reconciler.Health
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "healthz",
+			path:           "/healthz",
+			health:         reconciler.Health{Ready: false},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"ok"}`,
+		},
+		{
+			name: "ready",
+			path: "/readyz",
+			health: reconciler.Health{
+				Ready:       true,
+				LastSync:    lastSync,
+				LastSuccess: lastSync,
+				Checks: []reconciler.Check{
+					{Name: "sync", OK: true},
+					{Name: "store", OK: true},
+					{Name: "public-ip", OK: true, Detail: "detected by ipify"},
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"ready","last_sync":"2025-01-01T10:00:00Z","last_success":"2025-01-01T10:00:00Z","checks":[` +
+				`{"name":"sync","ok":true},{"name":"store","ok":true},{"name":"public-ip","ok":true,"detail":"detected by ipify"}]}`,
+		},
+		{
+			name: "not-ready",
+			path: "/readyz",
+			health: reconciler.Health{
+				Ready: false,
+				Checks: []reconciler.Check{
+					{Name: "sync", OK: false, Detail: "no sync cycle completed yet"},
+				},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"not ready","checks":[{"name":"sync","ok":false,"detail":"no sync cycle completed yet"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		path := tc.path
+		health := tc.health
+		expectedStatus := tc.expectedStatus
+		expectedBody := tc.expectedBody
+
+		t.Run(tc.name, func(t *testing.T) {
+			srv, err := New(configMock{values: map[string]any{httpPath: httpConfig{Listen: "127.0.0.1:0"}}}, loggerMock{})
+			assert.NoError(t, err)
+			srv.HandleHealth(healthMock{health: health})
+
+			rec := httptest.NewRecorder()
+			srv.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, expectedStatus, rec.Code)
+			assert.JSONEq(t, expectedBody, rec.Body.String())
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		})
+	}
+}
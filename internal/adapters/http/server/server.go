@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	httpPath        string        = "ddns.http"
+	shutdownTimeout time.Duration = 5 * time.Second
+	readTimeout     time.Duration = 10 * time.Second
+)
+
+var ErrDisabled = errors.New("http server disabled")
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Info(msg string)
+	Error(err error)
+}
+
+type httpConfig struct {
+	Listen     string `yaml:"listen"`
+	AdminToken string `yaml:"admin-token"`
+	Dashboard  bool   `yaml:"dashboard"`
+}
+
+type server struct {
+	address    string
+	adminToken string
+	dashboard  bool
+	mux        *http.ServeMux
+	http       *http.Server
+	listener   net.Listener
+	logger     messageLogger
+}
+
+// New builds the embedded http server from the ddns.http node, ErrDisabled is
+// returned when the node or its listen address are missing.
+func New(cnf configDecoder, logger messageLogger) (*server, error) {
+	hc := httpConfig{}
+	if err := cnf.Decode(httpPath, &hc); err != nil {
+		return nil, ErrDisabled
+	}
+
+	hc.Listen = strings.TrimSpace(hc.Listen)
+	if hc.Listen == "" {
+		return nil, ErrDisabled
+	}
+
+	mux := http.NewServeMux()
+	return &server{
+		address:    hc.Listen,
+		adminToken: strings.TrimSpace(hc.AdminToken),
+		dashboard:  hc.Dashboard,
+		mux:        mux,
+		http: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: readTimeout,
+		},
+		logger: logger,
+	}, nil
+}
+
+func (s *server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Listen binds the listen address, so callers can fail fast when it's already
+// in use before starting to serve.
+func (s *server) Listen() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("server: unable to listen on %s, err:%w", s.address, err)
+	}
+
+	s.listener = listener
+	return nil
+}
+
+// Serve handles requests until ctx is cancelled, then gives in-flight
+// requests a few seconds to complete.
+func (s *server) Serve(ctx context.Context) error {
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			return err
+		}
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		s.logger.Info(fmt.Sprintf("server: listening on %s", s.listener.Addr()))
+		served <- s.http.Serve(s.listener)
+	}()
+
+	select {
+	case err := <-served:
+		return fmt.Errorf("server: unable to serve, err:%w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("server: unable to shutdown, err:%w", err)
+	}
+
+	return nil
+}
+
+func (s *server) Addr() string {
+	if s.listener == nil {
+		return s.address
+	}
+
+	return s.listener.Addr().String()
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	values map[string]any
+}
+
+func (c configMock) Decode(node string, item any) error {
+	value, ok := c.values[node]
+	if !ok {
+		return errors.New("node not found")
+	}
+
+	reflect.ValueOf(item).Elem().Set(reflect.ValueOf(value))
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string) {}
+func (loggerMock) Info(msg string)  {}
+func (loggerMock) Error(err error)  {}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name            string
+		config          configMock
+		expectedAddress string
+		expectedError   error
+	}{
+		{
+			name:          "not-configured",
+			config:        configMock{},
+			expectedError: ErrDisabled,
+		},
+		{
+			name:          "empty-listen",
+			config:        configMock{values: map[string]any{httpPath: httpConfig{Listen: "\n"}}},
+			expectedError: ErrDisabled,
+		},
+		{
+			name:            "configured",
+			config:          configMock{values: map[string]any{httpPath: httpConfig{Listen: "127.0.0.1:8080"}}},
+			expectedAddress: "127.0.0.1:8080",
+		},
+	}
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		expectedAddress := tc.expectedAddress
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			srv, err := New(cnf, loggerMock{})
+
+			assert.Equal(t, expectedError, err)
+			if err == nil {
+				assert.Equal(t, expectedAddress, srv.Addr())
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	srv, err := New(configMock{values: map[string]any{httpPath: httpConfig{Listen: "127.0.0.1:0"}}}, loggerMock{})
+	assert.NoError(t, err)
+	assert.NoError(t, srv.Listen())
+
+	srv.Handle("/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ctx)
+	}()
+
+	res, err := http.Get("http://" + srv.Addr() + "/ping")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, "pong", string(body))
+
+	cancel()
+	select {
+	case err = <-served:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server didn't shutdown")
+	}
+}
+
+func TestListenAddressInUse(t *testing.T) {
+	first, _ := New(configMock{values: map[string]any{httpPath: httpConfig{Listen: "127.0.0.1:0"}}}, loggerMock{})
+	assert.NoError(t, first.Listen())
+	defer first.listener.Close()
+
+	second, _ := New(configMock{values: map[string]any{httpPath: httpConfig{Listen: first.Addr()}}}, loggerMock{})
+	assert.ErrorContains(t, second.Listen(), "server: unable to listen on "+first.Addr())
+}
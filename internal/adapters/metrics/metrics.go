@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const namespace string = "ddns"
+
+const (
+	familyV4 string = "ipv4"
+	familyV6 string = "ipv6"
+)
+
+type metrics struct {
+	registry *prometheus.Registry
+
+	lookupDuration   *prometheus.HistogramVec
+	lookupErrors     *prometheus.CounterVec
+	invalidResponses *prometheus.CounterVec
+	ipChanges        *prometheus.CounterVec
+
+	updateDuration *prometheus.HistogramVec
+	recordUpdates  *prometheus.CounterVec
+	outOfSync      *outOfSync
+
+	storeDuration *prometheus.HistogramVec
+	storeErrors   *prometheus.CounterVec
+
+	// the last detected ip outlives getters rebuilt by a config reload, so
+	// a reload isn't counted as an ip change.
+	mu     sync.Mutex
+	lastIP publicip.IP
+}
+
+// New registers every simple-ddns collector, plus the go runtime and process
+// ones, on a dedicated registry.
+func New() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		lookupDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "public_ip_lookup_duration_seconds",
+			Help:      "Duration of public ip lookups per source.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"source"}),
+		lookupErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "public_ip_lookup_errors_total",
+			Help:      "Public ip lookups that returned no address, per source.",
+		}, []string{"source"}),
+		invalidResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "public_ip_invalid_responses_total",
+			Help:      "Public ip lookups that returned something that isn't an address of the expected family, per source.",
+		}, []string{"source", "family"}),
+		ipChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "public_ip_changes_total",
+			Help:      "Public ip changes detected, per family.",
+		}, []string{"family"}),
+		updateDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "provider_update_duration_seconds",
+			Help:      "Duration of update calls per provider account.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		recordUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "provider_record_updates_total",
+			Help:      "Records sent to providers, per provider account, zone and status.",
+		}, []string{"provider", "zone", "status"}),
+		outOfSync: newOutOfSync(),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "store_operation_duration_seconds",
+			Help:      "Duration of store operations.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+		storeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "store_operation_errors_total",
+			Help:      "Failed store operations.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.lookupDuration,
+		m.lookupErrors,
+		m.invalidResponses,
+		m.ipChanges,
+		m.updateDuration,
+		m.recordUpdates,
+		m.outOfSync,
+		m.storeDuration,
+		m.storeErrors,
+	)
+
+	return m
+}
+
+func (m *metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
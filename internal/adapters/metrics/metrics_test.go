@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stringPointer(s string) *string {
+	return &s
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.ipChanges.WithLabelValues(familyV4).Inc()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `ddns_public_ip_changes_total{family="ipv4"} 1`)
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
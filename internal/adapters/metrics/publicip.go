@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type sourceGetter struct {
+	name    string
+	getter  publicip.Getter
+	metrics *metrics
+}
+
+type changesGetter struct {
+	getter  publicip.Getter
+	metrics *metrics
+}
+
+// Source instruments a single public ip source, it has the signature of a
+// source.Decorator.
+func (m *metrics) Source(name string, getter publicip.Getter) publicip.Getter {
+	return &sourceGetter{name: name, getter: getter, metrics: m}
+}
+
+// Getter counts the changes of the address returned by the getter the
+// reconciler uses.
+func (m *metrics) Getter(getter publicip.Getter) publicip.Getter {
+	return &changesGetter{getter: getter, metrics: m}
+}
+
+func (g *sourceGetter) GetIP(ctx context.Context) publicip.IP {
+	start := time.Now()
+	ip := g.getter.GetIP(ctx)
+	g.metrics.lookupDuration.WithLabelValues(g.name).Observe(time.Since(start).Seconds())
+
+	if ip.V4 == nil && ip.V6 == nil {
+		g.metrics.lookupErrors.WithLabelValues(g.name).Inc()
+		return ip
+	}
+
+	if ip.V4 != nil && !isFamily(*ip.V4, familyV4) {
+		g.metrics.invalidResponses.WithLabelValues(g.name, familyV4).Inc()
+	}
+
+	if ip.V6 != nil && !isFamily(*ip.V6, familyV6) {
+		g.metrics.invalidResponses.WithLabelValues(g.name, familyV6).Inc()
+	}
+
+	return ip
+}
+
+func (g *changesGetter) GetIP(ctx context.Context) publicip.IP {
+	ip := g.getter.GetIP(ctx)
+
+	g.metrics.mu.Lock()
+	defer g.metrics.mu.Unlock()
+
+	if changed(g.metrics.lastIP.V4, ip.V4) {
+		g.metrics.ipChanges.WithLabelValues(familyV4).Inc()
+	}
+
+	if changed(g.metrics.lastIP.V6, ip.V6) {
+		g.metrics.ipChanges.WithLabelValues(familyV6).Inc()
+	}
+
+	if ip.V4 != nil {
+		g.metrics.lastIP.V4 = ip.V4
+	}
+
+	if ip.V6 != nil {
+		g.metrics.lastIP.V6 = ip.V6
+	}
+
+	return ip
+}
+
+// changed ignores missing values, a failed lookup isn't an address change.
+func changed(previous, current *string) bool {
+	return previous != nil && current != nil && *previous != *current
+}
+
+func isFamily(value string, family string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+
+	if family == familyV4 {
+		return ip.To4() != nil
+	}
+
+	return ip.To4() == nil
+}
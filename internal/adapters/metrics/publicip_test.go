@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type getterMock struct {
+	ips   []publicip.IP
+	calls int
+}
+
+func (g *getterMock) GetIP(ctx context.Context) publicip.IP {
+	ip := g.ips[g.calls%len(g.ips)]
+	g.calls++
+	return ip
+}
+
+func TestSource(t *testing.T) {
+	testCases := []struct {
+		name            string
+		ip              publicip.IP
+		expectedErrors  float64
+		expectedInvalid map[string]float64
+	}{
+		{
+			name:            "valid",
+			ip:              publicip.IP{V4: stringPointer("10.0.0.1"), V6: stringPointer("2001:db8::1")},
+			expectedInvalid: map[string]float64{familyV4: 0, familyV6: 0},
+		},
+		{
+			name:            "no-address",
+			ip:              publicip.IP{},
+			expectedErrors:  1,
+			expectedInvalid: map[string]float64{familyV4: 0, familyV6: 0},
+		},
+		{
+			name:            "captive-portal",
+			ip:              publicip.IP{V4: stringPointer("<html>login</html>")},
+			expectedInvalid: map[string]float64{familyV4: 1, familyV6: 0},
+		},
+		{
+			name:            "wrong-family",
+			ip:              publicip.IP{V4: stringPointer("2001:db8::1"), V6: stringPointer("10.0.0.1")},
+			expectedInvalid: map[string]float64{familyV4: 1, familyV6: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		ip := tc.ip
+		expectedErrors := tc.expectedErrors
+		expectedInvalid := tc.expectedInvalid
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := New()
+			getter := m.Source("ipify", &getterMock{ips: []publicip.IP{ip}})
+
+			assert.Equal(t, ip, getter.GetIP(context.Background()))
+			assert.Equal(t, 1, testutil.CollectAndCount(m.lookupDuration))
+			assert.Equal(t, expectedErrors, testutil.ToFloat64(m.lookupErrors.WithLabelValues("ipify")))
+			for family, expected := range expectedInvalid {
+				assert.Equal(t, expected, testutil.ToFloat64(m.invalidResponses.WithLabelValues("ipify", family)), family)
+			}
+		})
+	}
+}
+
+func TestGetterChanges(t *testing.T) {
+	m := New()
+	getter := m.Getter(&getterMock{ips: []publicip.IP{
+		{V4: stringPointer("10.0.0.1")},
+		{},
+		{V4: stringPointer("10.0.0.1"), V6: stringPointer("2001:db8::1")},
+		{V4: stringPointer("10.0.0.2"), V6: stringPointer("2001:db8::2")},
+	}})
+
+	for range 4 {
+		getter.GetIP(context.Background())
+	}
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ipChanges.WithLabelValues(familyV4)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ipChanges.WithLabelValues(familyV6)))
+
+	// a getter rebuilt by a reload keeps comparing against the last address.
+	reloaded := m.Getter(&getterMock{ips: []publicip.IP{{V4: stringPointer("10.0.0.2")}}})
+	reloaded.GetIP(context.Background())
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ipChanges.WithLabelValues(familyV4)))
+}
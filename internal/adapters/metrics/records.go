@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const recordsTimeout time.Duration = 5 * time.Second
+
+// RecordSource gives the state of the managed records, the daemon serving the
+// running engine implements it.
+type RecordSource interface {
+	Records(ctx context.Context) ([]dns.RecordState, error)
+	LastIP() (publicip.IP, time.Time)
+}
+
+// outOfSync is derived from the stored records every time it's collected, so
+// it stays current through retried, skipped and paused cycles and reloads.
+type outOfSync struct {
+	desc *prometheus.Desc
+
+	mu     sync.Mutex
+	source RecordSource
+}
+
+func newOutOfSync() *outOfSync {
+	return &outOfSync{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "records_out_of_sync"),
+			"Records not paused whose stored value differs from the last detected public ip or whose last update failed, per provider account.",
+			[]string{"provider"}, nil,
+		),
+	}
+}
+
+// Records sets where the out of sync records are counted from, nothing is
+// reported until it's called.
+func (m *metrics) Records(source RecordSource) {
+	m.outOfSync.mu.Lock()
+	defer m.outOfSync.mu.Unlock()
+
+	m.outOfSync.source = source
+}
+
+func (o *outOfSync) Describe(ch chan<- *prometheus.Desc) {
+	ch <- o.desc
+}
+
+// Collect reports nothing when the records can't be read, failing store
+// operations are already counted.
+func (o *outOfSync) Collect(ch chan<- prometheus.Metric) {
+	o.mu.Lock()
+	source := o.source
+	o.mu.Unlock()
+
+	if source == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordsTimeout)
+	defer cancel()
+
+	states, err := source.Records(ctx)
+	if err != nil {
+		return
+	}
+
+	ip, _ := source.LastIP()
+	counts := make(map[string]int)
+	for _, state := range states {
+		out := 0
+		if !state.Paused && !inSync(state, ip) {
+			out = 1
+		}
+
+		counts[state.Provider] += out
+	}
+
+	for provider, count := range counts {
+		ch <- prometheus.MustNewConstMetric(o.desc, prometheus.GaugeValue, float64(count), provider)
+	}
+}
+
+// inSync tells whether state holds the last detected address of its family
+// and didn't fail its last update. Records of a family without a detected
+// address are only judged by their last update.
+func inSync(state dns.RecordState, ip publicip.IP) bool {
+	if state.LastError != "" {
+		return false
+	}
+
+	desired := ip.V4
+	if state.Record.Type == dns.AAAA {
+		desired = ip.V6
+	}
+
+	return desired == nil || *desired == state.Record.Value
+}
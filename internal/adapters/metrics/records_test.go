@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type recordSourceMock struct {
+	states []dns.RecordState
+	ip     publicip.IP
+	err    error
+}
+
+func (r recordSourceMock) Records(ctx context.Context) ([]dns.RecordState, error) {
+	return r.states, r.err
+}
+
+func (r recordSourceMock) LastIP() (publicip.IP, time.Time) {
+	return r.ip, time.Time{}
+}
+
+func recordState(provider, fqdn string, recordType dns.RecordType, value string) dns.RecordState {
+	return dns.RecordState{Provider: provider, Record: dns.DomainRecord{FQDN: fqdn, Type: recordType, Value: value}}
+}
+
+func TestOutOfSync(t *testing.T) {
+	failed := recordState("aws/main", "ftp.home.com.", dns.A, "10.0.0.2")
+	failed.LastError = "throttled"
+	paused := recordState("aws/main", "old.home.com.", dns.A, "10.0.0.1")
+	paused.Paused = true
+
+	testCases := []struct {
+		name     string
+		source   RecordSource
+		expected string
+	}{
+		{
+			name: "counted-per-provider",
+			source: recordSourceMock{
+				ip: publicip.IP{V4: stringPointer("10.0.0.2")},
+				states: []dns.RecordState{
+					recordState("aws/main", "vpn.home.com.", dns.A, "10.0.0.2"),
+					recordState("aws/main", "www.home.com.", dns.A, "10.0.0.1"),
+					failed,
+					paused,
+					recordState("do/main", "vpn.home.com.", dns.A, "10.0.0.2"),
+					recordState("do/main", "vpn6.home.com.", dns.AAAA, ""),
+				},
+			},
+			expected: `
+# HELP ddns_records_out_of_sync Records not paused whose stored value differs from the last detected public ip or whose last update failed, per provider account.
+# TYPE ddns_records_out_of_sync gauge
+ddns_records_out_of_sync{provider="aws/main"} 2
+ddns_records_out_of_sync{provider="do/main"} 0
+`,
+		},
+		{
+			name:   "records-unavailable",
+			source: recordSourceMock{err: errors.New("db locked")},
+		},
+		{
+			name: "no-source",
+		},
+	}
+
+	for _, tc := range testCases {
+		source := tc.source
+		expected := tc.expected
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := New()
+			if source != nil {
+				m.Records(source)
+			}
+
+			if expected == "" {
+				assert.Equal(t, 0, testutil.CollectAndCount(m.outOfSync))
+				return
+			}
+
+			assert.NoError(t, testutil.CollectAndCompare(m.outOfSync, strings.NewReader(expected)))
+		})
+	}
+}
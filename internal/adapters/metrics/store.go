@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+type store struct {
+	next    ddns.Controller
+	metrics *metrics
+}
+
+func (m *metrics) Store(next ddns.Controller) ddns.Controller {
+	return &store{next: next, metrics: m}
+}
+
+func (s *store) UpdateRecord(ctx context.Context, provider string, record dns.DomainRecord) error {
+	start := time.Now()
+	err := s.next.UpdateRecord(ctx, provider, record)
+	s.observe("update_record", start, err)
+
+	return err
+}
+
+func (s *store) GetRecords(ctx context.Context) ([]dns.DomainRecord, error) {
+	start := time.Now()
+	records, err := s.next.GetRecords(ctx)
+	s.observe("get_records", start, err)
+
+	return records, err
+}
+
+func (s *store) ActiveRecords(ctx context.Context) ([]ddns.HistoryEntry, error) {
+	start := time.Now()
+	entries, err := s.next.ActiveRecords(ctx)
+	s.observe("active_records", start, err)
+
+	return entries, err
+}
+
+func (s *store) InitRecords(ctx context.Context, provider string, records []dns.DomainRecord) error {
+	start := time.Now()
+	err := s.next.InitRecords(ctx, provider, records)
+	s.observe("init_records", start, err)
+
+	return err
+}
+
+func (s *store) History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error) {
+	start := time.Now()
+	entries, err := s.next.History(ctx, fqdn, limit)
+	s.observe("history", start, err)
+
+	return entries, err
+}
+
+func (s *store) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.next.Ping(ctx)
+	s.observe("ping", start, err)
+
+	return err
+}
+
+func (s *store) Close() error {
+	return s.next.Close()
+}
+
+func (s *store) SavePendingRetry(ctx context.Context, retry ddns.PendingRetry) error {
+	start := time.Now()
+	err := s.next.SavePendingRetry(ctx, retry)
+	s.observe("save_pending_retry", start, err)
+
+	return err
+}
+
+func (s *store) DeletePendingRetry(ctx context.Context, provider string, record dns.DomainRecord) error {
+	start := time.Now()
+	err := s.next.DeletePendingRetry(ctx, provider, record)
+	s.observe("delete_pending_retry", start, err)
+
+	return err
+}
+
+func (s *store) PendingRetries(ctx context.Context, provider string) ([]ddns.PendingRetry, error) {
+	start := time.Now()
+	retries, err := s.next.PendingRetries(ctx, provider)
+	s.observe("pending_retries", start, err)
+
+	return retries, err
+}
+
+func (s *store) SetPaused(ctx context.Context, record dns.DomainRecord, paused bool) error {
+	start := time.Now()
+	err := s.next.SetPaused(ctx, record, paused)
+	s.observe("set_paused", start, err)
+
+	return err
+}
+
+func (s *store) PausedRecords(ctx context.Context) ([]dns.DomainRecord, error) {
+	start := time.Now()
+	records, err := s.next.PausedRecords(ctx)
+	s.observe("paused_records", start, err)
+
+	return records, err
+}
+
+func (s *store) SaveCycle(ctx context.Context, cycle ddns.Cycle) error {
+	start := time.Now()
+	err := s.next.SaveCycle(ctx, cycle)
+	s.observe("save_cycle", start, err)
+
+	return err
+}
+
+func (s *store) Cycles(ctx context.Context, limit int) ([]ddns.Cycle, error) {
+	start := time.Now()
+	cycles, err := s.next.Cycles(ctx, limit)
+	s.observe("cycles", start, err)
+
+	return cycles, err
+}
+
+func (s *store) observe(operation string, start time.Time, err error) {
+	s.metrics.storeDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.metrics.storeErrors.WithLabelValues(operation).Inc()
+	}
+}
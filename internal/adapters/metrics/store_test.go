@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+type storeMock struct {
+	err error
+}
+
+func (s storeMock) UpdateRecord(ctx context.Context, provider string, record dns.DomainRecord) error {
+	return s.err
+}
+
+func (s storeMock) GetRecords(ctx context.Context) ([]dns.DomainRecord, error) {
+	return nil, s.err
+}
+
+func (s storeMock) ActiveRecords(ctx context.Context) ([]ddns.HistoryEntry, error) {
+	return nil, s.err
+}
+
+func (s storeMock) InitRecords(ctx context.Context, provider string, records []dns.DomainRecord) error {
+	return s.err
+}
+
+func (s storeMock) History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error) {
+	return nil, s.err
+}
+
+func (s storeMock) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func (s storeMock) Close() error {
+	return nil
+}
+
+func (s storeMock) SavePendingRetry(ctx context.Context, retry ddns.PendingRetry) error {
+	return s.err
+}
+
+func (s storeMock) DeletePendingRetry(ctx context.Context, provider string, record dns.DomainRecord) error {
+	return s.err
+}
+
+func (s storeMock) PendingRetries(ctx context.Context, provider string) ([]ddns.PendingRetry, error) {
+	return nil, s.err
+}
+
+func (s storeMock) SetPaused(ctx context.Context, record dns.DomainRecord, paused bool) error {
+	return s.err
+}
+
+func (s storeMock) PausedRecords(ctx context.Context) ([]dns.DomainRecord, error) {
+	return nil, s.err
+}
+
+func (s storeMock) SaveCycle(ctx context.Context, cycle ddns.Cycle) error {
+	return s.err
+}
+
+func (s storeMock) Cycles(ctx context.Context, limit int) ([]ddns.Cycle, error) {
+	return nil, s.err
+}
+
+func TestStore(t *testing.T) {
+	testCases := []struct {
+		name           string
+		store          storeMock
+		expectedErrors float64
+	}{
+		{
+			name:           "ok",
+			store:          storeMock{},
+			expectedErrors: 0,
+		},
+		{
+			name:           "error",
+			store:          storeMock{err: errors.New("database is locked")},
+			expectedErrors: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		next := tc.store
+		expectedErrors := tc.expectedErrors
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := New()
+			st := m.Store(next)
+			ctx := context.Background()
+
+			assert.Equal(t, next.err, st.UpdateRecord(ctx, "route53", dns.DomainRecord{}))
+			_, err := st.GetRecords(ctx)
+			assert.Equal(t, next.err, err)
+			assert.Equal(t, next.err, st.Ping(ctx))
+
+			assert.Equal(t, 3, testutil.CollectAndCount(m.storeDuration))
+			for _, operation := range []string{"update_record", "get_records", "ping"} {
+				assert.Equal(t, expectedErrors, testutil.ToFloat64(m.storeErrors.WithLabelValues(operation)), operation)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+)
+
+type updater struct {
+	next    dns.Updater
+	metrics *metrics
+}
+
+func (m *metrics) Updater(next dns.Updater) dns.Updater {
+	return &updater{next: next, metrics: m}
+}
+
+func (u *updater) Name() string {
+	return u.next.Name()
+}
+
+func (u *updater) Records() []dns.DomainRecord {
+	return u.next.Records()
+}
+
+func (u *updater) Zone(record dns.DomainRecord) string {
+	return dns.ZoneOf(u.next, record)
+}
+
+func (u *updater) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	start := time.Now()
+	results := u.next.UpdateDomains(ctx, records)
+	u.metrics.updateDuration.WithLabelValues(u.Name()).Observe(time.Since(start).Seconds())
+
+	for _, result := range results {
+		u.metrics.recordUpdates.WithLabelValues(u.Name(), result.Zone, string(result.Status)).Inc()
+	}
+
+	return results
+}
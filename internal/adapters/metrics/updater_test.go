@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+)
+
+type updaterMock struct {
+	results []dns.UpdateResult
+}
+
+func (u updaterMock) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	return u.results
+}
+
+func (u updaterMock) Records() []dns.DomainRecord {
+	return []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A}}
+}
+
+func (u updaterMock) Name() string {
+	return "aws/main"
+}
+
+func (u updaterMock) Zone(record dns.DomainRecord) string {
+	return "Z1"
+}
+
+func TestUpdater(t *testing.T) {
+	m := New()
+	results := []dns.UpdateResult{
+		{Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A}, Zone: "Z1", Status: dns.Applied},
+		{Record: dns.DomainRecord{FQDN: "www.home.com.", Type: dns.A}, Zone: "Z1", Status: dns.Failed, Err: errors.New("throttled")},
+		{Record: dns.DomainRecord{FQDN: "ftp.home.com.", Type: dns.A}, Zone: "Z1", Status: dns.Failed, Err: errors.New("throttled")},
+	}
+	u := m.Updater(updaterMock{results: results})
+
+	assert.Equal(t, results, u.UpdateDomains(context.Background(), nil))
+	assert.Equal(t, "aws/main", u.Name())
+	assert.Len(t, u.Records(), 1)
+	assert.Equal(t, "Z1", dns.ZoneOf(u, dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A}))
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.updateDuration))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.recordUpdates.WithLabelValues("aws/main", "Z1", string(dns.Applied))))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.recordUpdates.WithLabelValues("aws/main", "Z1", string(dns.Failed))))
+}
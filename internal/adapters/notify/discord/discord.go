@@ -0,0 +1,159 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+const (
+	notifierName string = "discord"
+	discordPath  string = "ddns.notifications." + notifierName
+)
+
+func init() {
+	notifier.Register(notifierName, func(cnf notifier.ConfigDecoder, logger notifier.MessageLogger) ([]notify.Notifier, error) {
+		return newNotifiers(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type discordConfig struct {
+	Name            string `yaml:"name"`
+	URL             string `yaml:"url"`
+	Username        string `yaml:"username"`
+	notifier.Filter `yaml:",inline"`
+}
+
+type discordMessage struct {
+	Username string  `json:"username,omitempty"`
+	Embeds   []embed `json:"embeds"`
+}
+
+type embed struct {
+	Title     string       `json:"title"`
+	Color     int          `json:"color"`
+	Timestamp string       `json:"timestamp"`
+	Fields    []embedField `json:"fields,omitempty"`
+	Footer    embedFooter  `json:"footer"`
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type embedFooter struct {
+	Text string `json:"text"`
+}
+
+var severityColors = map[notify.Severity]int{
+	notify.Info:    0x3498db,
+	notify.Warning: 0xf1c40f,
+	notify.Error:   0xe74c3c,
+}
+
+type discord struct {
+	config discordConfig
+	client notifier.HTTPRequestor
+	logger messageLogger
+}
+
+// New builds the discord webhook configured as name under
+// ddns.notifications.discord.
+func New(cnf configDecoder, logger messageLogger, name string) (notify.Notifier, error) {
+	channels := []discordConfig{}
+	if err := cnf.Decode(discordPath, &channels); err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		if channel.Name != name {
+			continue
+		}
+
+		endpoint, err := notifier.ParseURL(channel.URL)
+		if err != nil {
+			return nil, err
+		}
+		channel.URL = endpoint.String()
+
+		return notifier.Filtered(&discord{config: channel, client: http.DefaultClient, logger: logger}, channel.Filter)
+	}
+
+	return nil, fmt.Errorf("discord %s doesn't exist", name)
+}
+
+func newNotifiers(cnf configDecoder, logger messageLogger) ([]notify.Notifier, error) {
+	channels := []discordConfig{}
+	if err := cnf.Decode(discordPath, &channels); err != nil {
+		return nil, err
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(channels))
+	for _, channel := range channels {
+		n, err := New(cnf, logger, channel.Name)
+		if err != nil {
+			return nil, fmt.Errorf("discord: unable to create discord %s, err:%w", channel.Name, err)
+		}
+
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+func (d *discord) Name() string {
+	return fmt.Sprintf("%s/%s", notifierName, d.config.Name)
+}
+
+func (d *discord) Notify(ctx context.Context, event notify.Event) error {
+	msg := newDiscordMessage(notifier.NewMessage(event))
+	msg.Username = d.config.Username
+
+	body, err := notifier.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if err = notifier.Send(ctx, d.client, http.MethodPost, d.config.URL, nil, body); err != nil {
+		return err
+	}
+
+	d.logger.Debug(fmt.Sprintf("discord: %s sent to %s", event.Type, d.config.Name))
+	return nil
+}
+
+// newDiscordMessage lays message out as an embed coloured by severity,
+// addresses and the provider are shown side by side.
+func newDiscordMessage(message notifier.Message) discordMessage {
+	e := embed{
+		Title:     message.Summary,
+		Color:     severityColors[notify.Severity(message.Severity)],
+		Timestamp: message.Time.UTC().Format(time.RFC3339),
+		Footer:    embedFooter{Text: "simple-ddns · " + message.Event},
+	}
+
+	for _, field := range message.Fields() {
+		e.Fields = append(e.Fields, embedField{
+			Name:   field.Name,
+			Value:  field.Value,
+			Inline: field.Name != "Records" && field.Name != "Errors",
+		})
+	}
+
+	return discordMessage{Embeds: []embed{e}}
+}
@@ -0,0 +1,167 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	channels []discordConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	*item.(*[]discordConfig) = c.channels
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+func TestNotify(t *testing.T) {
+	oldIP, newIP := "10.0.0.1", "10.0.0.2"
+	at := time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		event           notify.Event
+		status          int
+		expectedMessage discordMessage
+		expectedError   string
+	}{
+		{
+			name: "ip-changed",
+			event: notify.Event{
+				Type:     notify.IPChanged,
+				Severity: notify.Info,
+				Time:     at,
+				Summary:  "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				OldIP:    publicip.IP{V4: &oldIP},
+				NewIP:    publicip.IP{V4: &newIP},
+			},
+			status: http.StatusNoContent,
+			expectedMessage: discordMessage{
+				Username: "simple-ddns",
+				Embeds: []embed{{
+					Title:     "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+					Color:     0x3498db,
+					Timestamp: "2025-01-03T10:00:00Z",
+					Fields:    []embedField{{Name: "IPv4", Value: "10.0.0.1 -> 10.0.0.2", Inline: true}},
+					Footer:    embedFooter{Text: "simple-ddns · ip-changed"},
+				}},
+			},
+		},
+		{
+			name: "update-failed",
+			event: notify.Event{
+				Type:     notify.UpdateFailed,
+				Severity: notify.Error,
+				Time:     at,
+				Summary:  "1 records failed to update on aws/main",
+				Provider: "aws/main",
+				Records:  []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+				Errors:   []string{"throttled"},
+			},
+			status: http.StatusNotFound,
+			expectedMessage: discordMessage{
+				Username: "simple-ddns",
+				Embeds: []embed{{
+					Title:     "1 records failed to update on aws/main",
+					Color:     0xe74c3c,
+					Timestamp: "2025-01-03T10:00:00Z",
+					Fields: []embedField{
+						{Name: "Provider", Value: "aws/main", Inline: true},
+						{Name: "Records", Value: "vpn.home.com. A 10.0.0.2"},
+						{Name: "Errors", Value: "throttled"},
+					},
+					Footer: embedFooter{Text: "simple-ddns · update-failed"},
+				}},
+			},
+			expectedError: `http error: code 404: {"message": "Unknown Webhook", "code": 10015}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		event := tc.event
+		status := tc.status
+		expectedMessage := tc.expectedMessage
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			var got discordMessage
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(status)
+				if status != http.StatusNoContent {
+					_, _ = w.Write([]byte(`{"message": "Unknown Webhook", "code": 10015}`))
+				}
+			}))
+			defer srv.Close()
+
+			channel := discordConfig{Name: "ops", URL: srv.URL + "/api/webhooks/1/TOKEN", Username: "simple-ddns"}
+			n, err := New(configMock{channels: []discordConfig{channel}}, loggerMock{}, "ops")
+			assert.NoError(t, err)
+
+			err = n.Notify(context.Background(), event)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, expectedMessage, got)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name          string
+		channels      []discordConfig
+		expectedName  string
+		expectedError string
+	}{
+		{
+			name:         "configured",
+			channels:     []discordConfig{{Name: "ops", URL: "https://discord.com/api/webhooks/1/TOKEN"}},
+			expectedName: "discord/ops",
+		},
+		{
+			name:          "missing",
+			channels:      []discordConfig{},
+			expectedError: "discord ops doesn't exist",
+		},
+		{
+			name:          "invalid-url",
+			channels:      []discordConfig{{Name: "ops", URL: "discord.com/api/webhooks/1/TOKEN"}},
+			expectedError: `invalid url "discord.com/api/webhooks/1/TOKEN"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		channels := tc.channels
+		expectedName := tc.expectedName
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := New(configMock{channels: channels}, loggerMock{}, "ops")
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedName, n.Name())
+		})
+	}
+}
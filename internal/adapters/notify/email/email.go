@@ -0,0 +1,336 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+const (
+	notifierName      string = "email"
+	emailsPath        string = "ddns.notifications." + notifierName
+	defaultPort       int    = 587
+	defaultDigestMins int    = 15
+
+	// digestTimeout bounds the delivery of digests, they're sent from a timer
+	// rather than from a cycle so no caller deadline applies.
+	digestTimeout time.Duration = 30 * time.Second
+
+	defaultSubject string = `[simple-ddns] {{ .Summary }}`
+	defaultBody    string = `{{ if .Digest }}{{ len .Messages }} events were batched since the last mail.
+
+{{ end }}{{ range .Messages }}{{ .Time.Format "2006-01-02 15:04:05 MST" }} [{{ .Severity }}] {{ .Summary }}
+{{ if or .OldIPv4 .NewIPv4 }}  ipv4: {{ or .OldIPv4 "-" }} -> {{ or .NewIPv4 "-" }}
+{{ end }}{{ if or .OldIPv6 .NewIPv6 }}  ipv6: {{ or .OldIPv6 "-" }} -> {{ or .NewIPv6 "-" }}
+{{ end }}{{ if .Provider }}  provider: {{ .Provider }}
+{{ end }}{{ range .Records }}  record: {{ .FQDN }} {{ .Type }}{{ if .Value }} {{ .Value }}{{ end }}
+{{ end }}{{ range .Errors }}  error: {{ . }}
+{{ end }}
+{{ end }}`
+)
+
+func init() {
+	notifier.Register(notifierName, func(cnf notifier.ConfigDecoder, logger notifier.MessageLogger) ([]notify.Notifier, error) {
+		return newNotifiers(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type emailConfig struct {
+	Name            string   `yaml:"name"`
+	Host            string   `yaml:"host"`
+	Port            int      `yaml:"port"`
+	TLS             string   `yaml:"tls"`
+	Username        string   `yaml:"username"`
+	Password        string   `yaml:"password"`
+	From            string   `yaml:"from"`
+	To              []string `yaml:"to"`
+	Subject         string   `yaml:"subject"`
+	Body            string   `yaml:"body"`
+	DigestMins      int      `yaml:"digest-mins"`
+	notifier.Filter `yaml:",inline"`
+}
+
+// mailData is what the subject and body templates are executed with, it holds
+// a single message unless the mail is a digest.
+type mailData struct {
+	Summary  string
+	Digest   bool
+	Messages []notifier.Message
+}
+
+type email struct {
+	config  emailConfig
+	mode    tlsMode
+	subject *template.Template
+	body    *template.Template
+	rootCAs *x509.CertPool
+	logger  messageLogger
+
+	// digest is the minimum time between mails, events arriving sooner are
+	// batched and sent together once it elapses.
+	digest   time.Duration
+	mu       sync.Mutex
+	lastSent time.Time
+	pending  []notifier.Message
+	timer    *time.Timer
+	closed   bool
+}
+
+// New builds the email notifier configured as name under
+// ddns.notifications.email. At most one mail is sent every digest-mins, events
+// in between are sent as a single digest when the interval ends.
+func New(cnf configDecoder, logger messageLogger, name string) (notify.Notifier, error) {
+	emails := []emailConfig{}
+	if err := cnf.Decode(emailsPath, &emails); err != nil {
+		return nil, err
+	}
+
+	for _, config := range emails {
+		if config.Name != name {
+			continue
+		}
+
+		e, err := newEmail(config, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		return notifier.Filtered(e, config.Filter)
+	}
+
+	return nil, fmt.Errorf("email %s doesn't exist", name)
+}
+
+func newNotifiers(cnf configDecoder, logger messageLogger) ([]notify.Notifier, error) {
+	emails := []emailConfig{}
+	if err := cnf.Decode(emailsPath, &emails); err != nil {
+		return nil, err
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(emails))
+	for _, config := range emails {
+		n, err := New(cnf, logger, config.Name)
+		if err != nil {
+			return nil, fmt.Errorf("email: unable to create email %s, err:%w", config.Name, err)
+		}
+
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+func newEmail(config emailConfig, logger messageLogger) (*email, error) {
+	config.Host = strings.TrimSpace(config.Host)
+	if config.Host == "" {
+		return nil, fmt.Errorf("missing smtp host")
+	}
+
+	if config.Port == 0 {
+		config.Port = defaultPort
+	}
+
+	mode, err := parseTLSMode(config.TLS, config.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == noTLS && config.Username != "" && !isLocalhost(config.Host) {
+		return nil, fmt.Errorf("credentials can't be sent to %s without tls, use starttls or tls", config.Host)
+	}
+
+	if _, err = mail.ParseAddress(config.From); err != nil {
+		return nil, fmt.Errorf("invalid from address %q", config.From)
+	}
+
+	if len(config.To) == 0 {
+		return nil, fmt.Errorf("missing recipients")
+	}
+
+	for _, to := range config.To {
+		if _, err = mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("invalid recipient address %q", to)
+		}
+	}
+
+	if config.DigestMins < 0 {
+		return nil, fmt.Errorf("invalid digest interval %d", config.DigestMins)
+	}
+
+	if config.DigestMins == 0 {
+		config.DigestMins = defaultDigestMins
+	}
+
+	if config.Subject == "" {
+		config.Subject = defaultSubject
+	}
+
+	if config.Body == "" {
+		config.Body = defaultBody
+	}
+
+	e := &email{
+		config: config,
+		mode:   mode,
+		logger: logger,
+		digest: time.Duration(config.DigestMins) * time.Minute,
+	}
+
+	if e.subject, err = template.New("subject").Parse(config.Subject); err != nil {
+		return nil, fmt.Errorf("invalid subject template, err:%w", err)
+	}
+
+	if e.body, err = template.New("body").Parse(config.Body); err != nil {
+		return nil, fmt.Errorf("invalid body template, err:%w", err)
+	}
+
+	return e, nil
+}
+
+func (e *email) Name() string {
+	return fmt.Sprintf("%s/%s", notifierName, e.config.Name)
+}
+
+// Notify mails event right away unless a mail went out less than the digest
+// interval ago, in that case it's queued for the next digest. Nothing is
+// queued once the notifier is closed.
+func (e *email) Notify(ctx context.Context, event notify.Event) error {
+	message := notifier.NewMessage(event)
+
+	e.mu.Lock()
+	now := time.Now()
+	if !e.closed && (e.timer != nil || now.Sub(e.lastSent) < e.digest) {
+		e.pending = append(e.pending, message)
+		if e.timer == nil {
+			e.timer = time.AfterFunc(e.digest-now.Sub(e.lastSent), e.flush)
+		}
+		e.mu.Unlock()
+
+		e.logger.Debug(fmt.Sprintf("email: %s queued for the next digest to %s", event.Type, e.config.Name))
+		return nil
+	}
+	e.lastSent = now
+	e.mu.Unlock()
+
+	return e.send(ctx, []notifier.Message{message})
+}
+
+// flush sends the queued events as a single digest.
+func (e *email) flush() {
+	e.mu.Lock()
+	messages := e.pending
+	e.pending = nil
+	e.timer = nil
+	e.lastSent = time.Now()
+	e.mu.Unlock()
+
+	if len(messages) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), digestTimeout)
+	defer cancel()
+
+	if err := e.send(ctx, messages); err != nil {
+		e.logger.Warning(fmt.Sprintf("email: unable to send digest to %s, err:%s", e.config.Name, err))
+	}
+}
+
+// Close stops the digest timer and sends the queued events right away, so
+// they aren't lost on shutdown nor mailed again by the notifier a reload
+// replaces this one with.
+func (e *email) Close() error {
+	e.mu.Lock()
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	messages := e.pending
+	e.pending = nil
+	e.timer = nil
+	e.closed = true
+	e.mu.Unlock()
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), digestTimeout)
+	defer cancel()
+
+	return e.send(ctx, messages)
+}
+
+func (e *email) send(ctx context.Context, messages []notifier.Message) error {
+	msg, err := e.compose(messages, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err = e.deliver(ctx, msg); err != nil {
+		return err
+	}
+
+	e.logger.Debug(fmt.Sprintf("email: %d events sent to %s", len(messages), e.config.Name))
+	return nil
+}
+
+// compose renders the templates into an RFC 5322 message.
+func (e *email) compose(messages []notifier.Message, now time.Time) ([]byte, error) {
+	data := mailData{Messages: messages}
+	if len(messages) == 1 {
+		data.Summary = messages[0].Summary
+	} else {
+		data.Digest = true
+		data.Summary = fmt.Sprintf("%d events, latest: %s", len(messages), messages[len(messages)-1].Summary)
+	}
+
+	subject := bytes.Buffer{}
+	if err := e.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("subject template error: %w", err)
+	}
+
+	body := bytes.Buffer{}
+	if err := e.body.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("body template error: %w", err)
+	}
+
+	msg := bytes.Buffer{}
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&msg)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(body.String(), "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	return msg.Bytes(), nil
+}
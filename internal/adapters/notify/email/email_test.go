@@ -0,0 +1,518 @@
+package email
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	emails []emailConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	*item.(*[]emailConfig) = c.emails
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+type delivery struct {
+	TLS  bool
+	Auth string
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTP is a local smtp server speaking just enough of the protocol for
+// net/smtp to deliver a mail.
+type fakeSMTP struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	startTLS  bool
+
+	mu         sync.Mutex
+	deliveries []delivery
+}
+
+func newFakeSMTP(t *testing.T, mode tlsMode) (*fakeSMTP, *x509.CertPool) {
+	cert, pool := selfSigned(t)
+	f := &fakeSMTP{
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		startTLS:  mode == startTLS,
+	}
+
+	var err error
+	if mode == implicitTLS {
+		f.listener, err = tls.Listen("tcp", "127.0.0.1:0", f.tlsConfig)
+	} else {
+		f.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	assert.NoError(t, err)
+	t.Cleanup(func() { f.listener.Close() })
+
+	go f.serve()
+	return f, pool
+}
+
+func (f *fakeSMTP) port() int {
+	return f.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) received() []delivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]delivery{}, f.deliveries...)
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeSMTP) handle(conn net.Conn) {
+	defer func() { conn.Close() }()
+
+	_, isTLS := conn.(*tls.Conn)
+	d := delivery{TLS: isTLS}
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 fake ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		switch strings.ToUpper(fields[0]) {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250-fake greets %s", fields[1])
+			if f.startTLS && !d.TLS {
+				_ = tp.PrintfLine("250-STARTTLS")
+			}
+			_ = tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, f.tlsConfig)
+			if err = tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, d.TLS = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(fields[2])
+			parts := strings.Split(string(credentials), "\x00")
+			d.Auth = parts[1] + ":" + parts[2]
+			if d.Auth != "ddns:s3cr3t" {
+				_ = tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			_ = tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			d.From = between(line, "<", ">")
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			d.To = append(d.To, between(line, "<", ">"))
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, _ := tp.ReadDotBytes()
+			d.Data = string(data)
+			_ = tp.PrintfLine("250 queued")
+
+			f.mu.Lock()
+			f.deliveries = append(f.deliveries, d)
+			f.mu.Unlock()
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+func between(value, start, end string) string {
+	_, after, _ := strings.Cut(value, start)
+	before, _, _ := strings.Cut(after, end)
+
+	return before
+}
+
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake smtp"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func ipChangedEvent(oldIP, newIP string) notify.Event {
+	return notify.Event{
+		Type:     notify.IPChanged,
+		Severity: notify.Info,
+		Time:     time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC),
+		Summary:  "public ip changed: A " + oldIP + " -> " + newIP,
+		OldIP:    publicip.IP{V4: &oldIP},
+		NewIP:    publicip.IP{V4: &newIP},
+	}
+}
+
+func TestNotify(t *testing.T) {
+	testCases := []struct {
+		name             string
+		serverMode       tlsMode
+		clientMode       string
+		password         string
+		expectedDelivery delivery
+		expectedError    string
+	}{
+		{
+			name:       "starttls",
+			serverMode: startTLS,
+			password:   "s3cr3t",
+			expectedDelivery: delivery{
+				TLS:  true,
+				Auth: "ddns:s3cr3t",
+				From: "ddns@home.com",
+				To:   []string{"ops@home.com", "me@home.com"},
+			},
+		},
+		{
+			name:       "implicit-tls",
+			serverMode: implicitTLS,
+			clientMode: "tls",
+			password:   "s3cr3t",
+			expectedDelivery: delivery{
+				TLS:  true,
+				Auth: "ddns:s3cr3t",
+				From: "ddns@home.com",
+				To:   []string{"ops@home.com", "me@home.com"},
+			},
+		},
+		{
+			name:       "plain-local-relay",
+			serverMode: noTLS,
+			clientMode: "none",
+			password:   "s3cr3t",
+			expectedDelivery: delivery{
+				Auth: "ddns:s3cr3t",
+				From: "ddns@home.com",
+				To:   []string{"ops@home.com", "me@home.com"},
+			},
+		},
+		{
+			name:          "starttls-not-offered",
+			serverMode:    noTLS,
+			password:      "s3cr3t",
+			expectedError: "smtp error: 127.0.0.1 doesn't support STARTTLS",
+		},
+		{
+			name:          "wrong-password",
+			serverMode:    startTLS,
+			password:      "wrong",
+			expectedError: `smtp auth error: 535 "authentication failed"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		serverMode := tc.serverMode
+		clientMode := tc.clientMode
+		password := tc.password
+		expectedDelivery := tc.expectedDelivery
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			srv, pool := newFakeSMTP(t, serverMode)
+
+			e, err := newEmail(emailConfig{
+				Name:     "home",
+				Host:     "127.0.0.1",
+				Port:     srv.port(),
+				TLS:      clientMode,
+				Username: "ddns",
+				Password: password,
+				From:     "Simple DDNS <ddns@home.com>",
+				To:       []string{"ops@home.com", "Me <me@home.com>"},
+			}, loggerMock{})
+			assert.NoError(t, err)
+			e.rootCAs = pool
+
+			err = e.Notify(context.Background(), ipChangedEvent("10.0.0.1", "10.0.0.2"))
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				assert.Empty(t, srv.received())
+				return
+			}
+
+			assert.NoError(t, err)
+			deliveries := srv.received()
+			assert.Len(t, deliveries, 1)
+
+			got := deliveries[0]
+			assert.Contains(t, got.Data, "Subject: [simple-ddns] public ip changed: A 10.0.0.1 -> 10.0.0.2\n")
+			assert.Contains(t, got.Data, "  ipv4: 10.0.0.1 -> 10.0.0.2\n")
+			got.Data = ""
+			assert.Equal(t, expectedDelivery, got)
+		})
+	}
+}
+
+func TestDigest(t *testing.T) {
+	srv, _ := newFakeSMTP(t, noTLS)
+	e, err := newEmail(emailConfig{
+		Name: "home",
+		Host: "127.0.0.1",
+		Port: srv.port(),
+		TLS:  "none",
+		From: "ddns@home.com",
+		To:   []string{"ops@home.com"},
+	}, loggerMock{})
+	assert.NoError(t, err)
+	e.digest = 200 * time.Millisecond
+
+	assert.NoError(t, e.Notify(context.Background(), ipChangedEvent("10.0.0.1", "10.0.0.2")))
+	assert.NoError(t, e.Notify(context.Background(), ipChangedEvent("10.0.0.2", "10.0.0.3")))
+	assert.NoError(t, e.Notify(context.Background(), ipChangedEvent("10.0.0.3", "10.0.0.4")))
+	assert.Len(t, srv.received(), 1)
+
+	assert.Eventually(t, func() bool { return len(srv.received()) == 2 }, 2*time.Second, 20*time.Millisecond)
+
+	digest := srv.received()[1].Data
+	assert.Contains(t, digest, "Subject: [simple-ddns] 2 events, latest: public ip changed: A 10.0.0.3 -> 10.0.0.4\n")
+	assert.Contains(t, digest, "2 events were batched since the last mail.\n")
+	assert.Contains(t, digest, "  ipv4: 10.0.0.2 -> 10.0.0.3\n")
+	assert.Contains(t, digest, "  ipv4: 10.0.0.3 -> 10.0.0.4\n")
+}
+
+func TestClose(t *testing.T) {
+	srv, _ := newFakeSMTP(t, noTLS)
+	e, err := newEmail(emailConfig{
+		Name: "home",
+		Host: "127.0.0.1",
+		Port: srv.port(),
+		TLS:  "none",
+		From: "ddns@home.com",
+		To:   []string{"ops@home.com"},
+	}, loggerMock{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, e.Notify(context.Background(), ipChangedEvent("10.0.0.1", "10.0.0.2")))
+	assert.NoError(t, e.Notify(context.Background(), ipChangedEvent("10.0.0.2", "10.0.0.3")))
+	assert.Len(t, srv.received(), 1)
+
+	assert.NoError(t, e.Close())
+	assert.Len(t, srv.received(), 2)
+	assert.Contains(t, srv.received()[1].Data, "  ipv4: 10.0.0.2 -> 10.0.0.3\n")
+	assert.Nil(t, e.timer)
+
+	assert.NoError(t, e.Notify(context.Background(), ipChangedEvent("10.0.0.3", "10.0.0.4")))
+	assert.Len(t, srv.received(), 3)
+	assert.NoError(t, e.Close())
+	assert.Len(t, srv.received(), 3)
+}
+
+func TestCompose(t *testing.T) {
+	e, err := newEmail(emailConfig{
+		Name: "home",
+		Host: "smtp.home.com",
+		From: "ddns@home.com",
+		To:   []string{"ops@home.com"},
+	}, loggerMock{})
+	assert.NoError(t, err)
+
+	msg, err := e.compose([]notifier.Message{notifier.NewMessage(notify.Event{
+		Type:     notify.UpdateFailed,
+		Severity: notify.Error,
+		Time:     time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC),
+		Summary:  "1 records failed to update on aws/main",
+		Provider: "aws/main",
+		Records:  []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+		Errors:   []string{"throttled"},
+	})}, time.Date(2025, 1, 3, 10, 0, 5, 0, time.UTC))
+	assert.NoError(t, err)
+
+	assert.Equal(t, strings.Join([]string{
+		"From: ddns@home.com",
+		"To: ops@home.com",
+		"Subject: [simple-ddns] 1 records failed to update on aws/main",
+		"Date: Fri, 03 Jan 2025 10:00:05 +0000",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"2025-01-03 10:00:00 UTC [error] 1 records failed to update on aws/main",
+		"  provider: aws/main",
+		"  record: vpn.home.com. A 10.0.0.2",
+		"  error: throttled",
+		"",
+		"",
+	}, "\r\n"), string(msg))
+}
+
+func TestNew(t *testing.T) {
+	valid := emailConfig{Name: "home", Host: "smtp.home.com", From: "ddns@home.com", To: []string{"ops@home.com"}}
+	with := func(change func(*emailConfig)) emailConfig {
+		config := valid
+		change(&config)
+		return config
+	}
+
+	testCases := []struct {
+		name          string
+		config        emailConfig
+		expectedError string
+	}{
+		{
+			name:   "valid",
+			config: valid,
+		},
+		{
+			name:          "missing-host",
+			config:        with(func(c *emailConfig) { c.Host = "" }),
+			expectedError: "missing smtp host",
+		},
+		{
+			name:          "invalid-tls-mode",
+			config:        with(func(c *emailConfig) { c.TLS = "ssl" }),
+			expectedError: "invalid tls mode ssl, expected starttls, tls or none",
+		},
+		{
+			name:          "credentials-without-tls",
+			config:        with(func(c *emailConfig) { c.TLS, c.Username = "none", "ddns@home.com" }),
+			expectedError: "credentials can't be sent to smtp.home.com without tls, use starttls or tls",
+		},
+		{
+			name:   "credentials-without-tls-to-localhost",
+			config: with(func(c *emailConfig) { c.Host, c.TLS, c.Username = "127.0.0.1", "none", "ddns@home.com" }),
+		},
+		{
+			name:          "invalid-from",
+			config:        with(func(c *emailConfig) { c.From = "ddns" }),
+			expectedError: `invalid from address "ddns"`,
+		},
+		{
+			name:          "missing-recipients",
+			config:        with(func(c *emailConfig) { c.To = nil }),
+			expectedError: "missing recipients",
+		},
+		{
+			name:          "invalid-recipient",
+			config:        with(func(c *emailConfig) { c.To = []string{"ops@home.com", "me"} }),
+			expectedError: `invalid recipient address "me"`,
+		},
+		{
+			name:          "invalid-digest",
+			config:        with(func(c *emailConfig) { c.DigestMins = -1 }),
+			expectedError: "invalid digest interval -1",
+		},
+		{
+			name:          "invalid-subject",
+			config:        with(func(c *emailConfig) { c.Subject = "{{ .Summary" }),
+			expectedError: "invalid subject template, err:template: subject:1: unclosed action",
+		},
+		{
+			name:          "unknown-event",
+			config:        with(func(c *emailConfig) { c.Events = []string{"ip-lost"} }),
+			expectedError: "notifier: unknown event ip-lost for email/home",
+		},
+	}
+
+	for _, tc := range testCases {
+		config := tc.config
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := New(configMock{emails: []emailConfig{config}}, loggerMock{}, config.Name)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "email/home", n.Name())
+		})
+	}
+}
+
+func TestParseTLSMode(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mode          string
+		port          int
+		expectedMode  tlsMode
+		expectedError string
+	}{
+		{name: "submission-port", port: 587, expectedMode: startTLS},
+		{name: "smtps-port", port: 465, expectedMode: implicitTLS},
+		{name: "explicit-mode", mode: "none", port: 465, expectedMode: noTLS},
+		{name: "invalid-mode", mode: "ssl", port: 465, expectedError: "invalid tls mode ssl, expected starttls, tls or none"},
+	}
+
+	for _, tc := range testCases {
+		mode := tc.mode
+		port := tc.port
+		expectedMode := tc.expectedMode
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseTLSMode(mode, port)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedMode, got)
+		})
+	}
+}
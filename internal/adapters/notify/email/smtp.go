@@ -0,0 +1,128 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+type tlsMode string
+
+const (
+	// startTLS upgrades a plain connection and fails when the server doesn't
+	// offer STARTTLS, credentials are never sent in clear text.
+	startTLS tlsMode = "starttls"
+	// implicitTLS connects over TLS from the start, usually on port 465.
+	implicitTLS tlsMode = "tls"
+	// noTLS is meant for relays on the local network, credentials are only
+	// sent over it to the loopback.
+	noTLS tlsMode = "none"
+)
+
+func parseTLSMode(mode string, port int) (tlsMode, error) {
+	switch tlsMode(mode) {
+	case startTLS, implicitTLS, noTLS:
+		return tlsMode(mode), nil
+	case "":
+		if port == 465 {
+			return implicitTLS, nil
+		}
+
+		return startTLS, nil
+	}
+
+	return "", fmt.Errorf("invalid tls mode %s, expected starttls, tls or none", mode)
+}
+
+// isLocalhost tells whether host is one smtp.PlainAuth sends credentials to
+// over an unencrypted connection.
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// deliver sends msg through the configured smtp server, ctx bounds the whole
+// conversation.
+func (e *email) deliver(ctx context.Context, msg []byte) error {
+	address := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	tlsConfig := &tls.Config{ServerName: e.config.Host, RootCAs: e.rootCAs, MinVersion: tls.VersionTLS12}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if e.mode == implicitTLS {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("network error: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp error: %w", err)
+	}
+	defer client.Close()
+
+	if e.mode == startTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp error: %s doesn't support STARTTLS", e.config.Host)
+		}
+
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp error: %w", err)
+		}
+	}
+
+	if e.config.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)); err != nil {
+			return fmt.Errorf("smtp auth error: %w", err)
+		}
+	}
+
+	if err = client.Mail(bareAddress(e.config.From)); err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+
+	for _, to := range e.config.To {
+		if err = client.Rcpt(bareAddress(to)); err != nil {
+			return fmt.Errorf("smtp error: %w", err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+
+	if _, err = w.Write(msg); err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// bareAddress returns user@host out of a "Name <user@host>" address, every
+// address was validated when the notifier was built.
+func bareAddress(value string) string {
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return value
+	}
+
+	return addr.Address
+}
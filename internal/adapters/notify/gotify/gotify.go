@@ -0,0 +1,142 @@
+package gotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+const (
+	notifierName string = "gotify"
+	gotifyPath   string = "ddns.notifications." + notifierName
+	tokenHeader  string = "X-Gotify-Key"
+)
+
+func init() {
+	notifier.Register(notifierName, func(cnf notifier.ConfigDecoder, logger notifier.MessageLogger) ([]notify.Notifier, error) {
+		return newNotifiers(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type gotifyConfig struct {
+	Name            string `yaml:"name"`
+	Server          string `yaml:"server"`
+	Token           string `yaml:"token"`
+	notifier.Filter `yaml:",inline"`
+}
+
+type gotifyMessage struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras"`
+}
+
+// severityPriorities maps severities to gotify priorities, android clients
+// stay silent below 4 and show high priority notifications from 8 on.
+var severityPriorities = map[notify.Severity]int{
+	notify.Info:    3,
+	notify.Warning: 5,
+	notify.Error:   8,
+}
+
+type gotify struct {
+	config gotifyConfig
+	client notifier.HTTPRequestor
+	logger messageLogger
+}
+
+// New builds the gotify application configured as name under
+// ddns.notifications.gotify, token is the application token.
+func New(cnf configDecoder, logger messageLogger, name string) (notify.Notifier, error) {
+	apps := []gotifyConfig{}
+	if err := cnf.Decode(gotifyPath, &apps); err != nil {
+		return nil, err
+	}
+
+	for _, app := range apps {
+		if app.Name != name {
+			continue
+		}
+
+		server, err := notifier.ParseURL(app.Server)
+		if err != nil {
+			return nil, err
+		}
+		app.Server = strings.TrimSuffix(server.String(), "/")
+
+		if app.Token == "" {
+			return nil, fmt.Errorf("missing application token")
+		}
+
+		return notifier.Filtered(&gotify{config: app, client: http.DefaultClient, logger: logger}, app.Filter)
+	}
+
+	return nil, fmt.Errorf("gotify %s doesn't exist", name)
+}
+
+func newNotifiers(cnf configDecoder, logger messageLogger) ([]notify.Notifier, error) {
+	apps := []gotifyConfig{}
+	if err := cnf.Decode(gotifyPath, &apps); err != nil {
+		return nil, err
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(apps))
+	for _, app := range apps {
+		n, err := New(cnf, logger, app.Name)
+		if err != nil {
+			return nil, fmt.Errorf("gotify: unable to create gotify %s, err:%w", app.Name, err)
+		}
+
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+func (g *gotify) Name() string {
+	return fmt.Sprintf("%s/%s", notifierName, g.config.Name)
+}
+
+func (g *gotify) Notify(ctx context.Context, event notify.Event) error {
+	message := notifier.NewMessage(event)
+	msg := gotifyMessage{
+		Title:    message.Summary,
+		Message:  message.Details(),
+		Priority: severityPriorities[event.Severity],
+		Extras: map[string]any{
+			"client::display": map[string]string{"contentType": "text/plain"},
+		},
+	}
+
+	if msg.Message == "" {
+		msg.Message = message.Summary
+	}
+
+	body, err := notifier.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set(tokenHeader, g.config.Token)
+	if err = notifier.Send(ctx, g.client, http.MethodPost, g.config.Server+"/message", header, body); err != nil {
+		return err
+	}
+
+	g.logger.Debug(fmt.Sprintf("gotify: %s sent to %s", event.Type, g.config.Name))
+	return nil
+}
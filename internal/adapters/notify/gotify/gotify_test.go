@@ -0,0 +1,164 @@
+package gotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	apps []gotifyConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	*item.(*[]gotifyConfig) = c.apps
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+func TestNotify(t *testing.T) {
+	oldIP, newIP := "10.0.0.1", "10.0.0.2"
+	at := time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)
+	extras := map[string]any{"client::display": map[string]any{"contentType": "text/plain"}}
+
+	testCases := []struct {
+		name            string
+		event           notify.Event
+		status          int
+		expectedMessage gotifyMessage
+		expectedError   string
+	}{
+		{
+			name: "ip-changed",
+			event: notify.Event{
+				Type:     notify.IPChanged,
+				Severity: notify.Info,
+				Time:     at,
+				Summary:  "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				OldIP:    publicip.IP{V4: &oldIP},
+				NewIP:    publicip.IP{V4: &newIP},
+			},
+			expectedMessage: gotifyMessage{
+				Title:    "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				Message:  "IPv4: 10.0.0.1 -> 10.0.0.2",
+				Priority: 3,
+				Extras:   extras,
+			},
+		},
+		{
+			name: "update-failed",
+			event: notify.Event{
+				Type:     notify.UpdateFailed,
+				Severity: notify.Error,
+				Time:     at,
+				Summary:  "1 records failed to update on aws/main",
+				Provider: "aws/main",
+				Records:  []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+				Errors:   []string{"throttled"},
+			},
+			status: http.StatusUnauthorized,
+			expectedMessage: gotifyMessage{
+				Title:    "1 records failed to update on aws/main",
+				Message:  "Provider: aws/main\nRecords: vpn.home.com. A 10.0.0.2\nErrors: throttled",
+				Priority: 8,
+				Extras:   extras,
+			},
+			expectedError: `http error: code 401: {"error":"Unauthorized","errorCode":401}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		event := tc.event
+		status := tc.status
+		expectedMessage := tc.expectedMessage
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			var got gotifyMessage
+			var path, token string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, token = r.URL.Path, r.Header.Get(tokenHeader)
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				if status != 0 {
+					w.WriteHeader(status)
+					_, _ = w.Write([]byte(`{"error":"Unauthorized","errorCode":401}`))
+				}
+			}))
+			defer srv.Close()
+
+			app := gotifyConfig{Name: "phone", Server: srv.URL + "/", Token: "APP-TOKEN"}
+			n, err := New(configMock{apps: []gotifyConfig{app}}, loggerMock{}, "phone")
+			assert.NoError(t, err)
+
+			err = n.Notify(context.Background(), event)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, "/message", path)
+			assert.Equal(t, "APP-TOKEN", token)
+			assert.Equal(t, expectedMessage, got)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name          string
+		apps          []gotifyConfig
+		expectedName  string
+		expectedError string
+	}{
+		{
+			name:         "configured",
+			apps:         []gotifyConfig{{Name: "phone", Server: "https://gotify.home.com", Token: "APP-TOKEN"}},
+			expectedName: "gotify/phone",
+		},
+		{
+			name:          "missing",
+			apps:          []gotifyConfig{},
+			expectedError: "gotify phone doesn't exist",
+		},
+		{
+			name:          "missing-token",
+			apps:          []gotifyConfig{{Name: "phone", Server: "https://gotify.home.com"}},
+			expectedError: "missing application token",
+		},
+		{
+			name:          "invalid-server",
+			apps:          []gotifyConfig{{Name: "phone", Token: "APP-TOKEN"}},
+			expectedError: `invalid url ""`,
+		},
+	}
+
+	for _, tc := range testCases {
+		apps := tc.apps
+		expectedName := tc.expectedName
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := New(configMock{apps: apps}, loggerMock{}, "phone")
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedName, n.Name())
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+const (
+	notifierName string = "matrix"
+	matrixPath   string = "ddns.notifications." + notifierName
+)
+
+func init() {
+	notifier.Register(notifierName, func(cnf notifier.ConfigDecoder, logger notifier.MessageLogger) ([]notify.Notifier, error) {
+		return newNotifiers(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type matrixConfig struct {
+	Name            string `yaml:"name"`
+	Homeserver      string `yaml:"homeserver"`
+	AccessToken     string `yaml:"access-token"`
+	RoomID          string `yaml:"room-id"`
+	notifier.Filter `yaml:",inline"`
+}
+
+// roomMessage is an m.room.message event, notices are the message type meant
+// for bots and don't trigger other bots.
+type roomMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+type matrix struct {
+	config matrixConfig
+	client notifier.HTTPRequestor
+	logger messageLogger
+	txn    atomic.Uint64
+}
+
+// New builds the matrix room configured as name under
+// ddns.notifications.matrix, messages are sent with the access token of the
+// account that joined the room.
+func New(cnf configDecoder, logger messageLogger, name string) (notify.Notifier, error) {
+	rooms := []matrixConfig{}
+	if err := cnf.Decode(matrixPath, &rooms); err != nil {
+		return nil, err
+	}
+
+	for _, room := range rooms {
+		if room.Name != name {
+			continue
+		}
+
+		homeserver, err := notifier.ParseURL(room.Homeserver)
+		if err != nil {
+			return nil, err
+		}
+		room.Homeserver = strings.TrimSuffix(homeserver.String(), "/")
+
+		if room.AccessToken == "" {
+			return nil, fmt.Errorf("missing access token")
+		}
+
+		if !strings.HasPrefix(room.RoomID, "!") {
+			return nil, fmt.Errorf("invalid room id %q", room.RoomID)
+		}
+
+		return notifier.Filtered(&matrix{config: room, client: http.DefaultClient, logger: logger}, room.Filter)
+	}
+
+	return nil, fmt.Errorf("matrix %s doesn't exist", name)
+}
+
+func newNotifiers(cnf configDecoder, logger messageLogger) ([]notify.Notifier, error) {
+	rooms := []matrixConfig{}
+	if err := cnf.Decode(matrixPath, &rooms); err != nil {
+		return nil, err
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(rooms))
+	for _, room := range rooms {
+		n, err := New(cnf, logger, room.Name)
+		if err != nil {
+			return nil, fmt.Errorf("matrix: unable to create matrix %s, err:%w", room.Name, err)
+		}
+
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+func (m *matrix) Name() string {
+	return fmt.Sprintf("%s/%s", notifierName, m.config.Name)
+}
+
+func (m *matrix) Notify(ctx context.Context, event notify.Event) error {
+	body, err := notifier.Marshal(newRoomMessage(notifier.NewMessage(event)))
+	if err != nil {
+		return err
+	}
+
+	// the transaction id makes the homeserver drop duplicates of a request,
+	// it only has to be unique for the access token.
+	txnID := fmt.Sprintf("simple-ddns-%d-%d", time.Now().UnixNano(), m.txn.Add(1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", m.config.Homeserver, url.PathEscape(m.config.RoomID), txnID)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+m.config.AccessToken)
+	if err = notifier.Send(ctx, m.client, http.MethodPut, endpoint, header, body); err != nil {
+		return err
+	}
+
+	m.logger.Debug(fmt.Sprintf("matrix: %s sent to %s", event.Type, m.config.Name))
+	return nil
+}
+
+// newRoomMessage renders message as a notice with an html body, clients
+// unable to render html show the plain text one.
+func newRoomMessage(message notifier.Message) roomMessage {
+	formatted := strings.Builder{}
+	fmt.Fprintf(&formatted, "<p><strong>%s</strong></p>", html.EscapeString(message.Summary))
+	if fields := message.Fields(); len(fields) > 0 {
+		formatted.WriteString("<ul>")
+		for _, field := range fields {
+			value := strings.ReplaceAll(html.EscapeString(field.Value), "\n", "<br>")
+			fmt.Fprintf(&formatted, "<li><strong>%s</strong>: %s</li>", field.Name, value)
+		}
+		formatted.WriteString("</ul>")
+	}
+
+	return roomMessage{
+		MsgType:       "m.notice",
+		Body:          message.Text(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted.String(),
+	}
+}
@@ -0,0 +1,175 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	rooms []matrixConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	*item.(*[]matrixConfig) = c.rooms
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+func TestNotify(t *testing.T) {
+	oldIP, newIP := "10.0.0.1", "10.0.0.2"
+	at := time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		event           notify.Event
+		status          int
+		expectedMessage roomMessage
+		expectedError   string
+	}{
+		{
+			name: "ip-changed",
+			event: notify.Event{
+				Type:     notify.IPChanged,
+				Severity: notify.Info,
+				Time:     at,
+				Summary:  "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				OldIP:    publicip.IP{V4: &oldIP},
+				NewIP:    publicip.IP{V4: &newIP},
+			},
+			expectedMessage: roomMessage{
+				MsgType:       "m.notice",
+				Body:          "public ip changed: A 10.0.0.1 -> 10.0.0.2\nIPv4: 10.0.0.1 -> 10.0.0.2",
+				Format:        "org.matrix.custom.html",
+				FormattedBody: "<p><strong>public ip changed: A 10.0.0.1 -&gt; 10.0.0.2</strong></p><ul><li><strong>IPv4</strong>: 10.0.0.1 -&gt; 10.0.0.2</li></ul>",
+			},
+		},
+		{
+			name: "update-failed",
+			event: notify.Event{
+				Type:     notify.UpdateFailed,
+				Severity: notify.Error,
+				Time:     at,
+				Summary:  "2 records failed to update on aws/main",
+				Provider: "aws/main",
+				Records: []dns.DomainRecord{
+					{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+					{FQDN: "www.home.com.", Type: dns.A, Value: "10.0.0.2"},
+				},
+				Errors: []string{"throttled"},
+			},
+			status: http.StatusForbidden,
+			expectedMessage: roomMessage{
+				MsgType:       "m.notice",
+				Body:          "2 records failed to update on aws/main\nProvider: aws/main\nRecords: vpn.home.com. A 10.0.0.2, www.home.com. A 10.0.0.2\nErrors: throttled",
+				Format:        "org.matrix.custom.html",
+				FormattedBody: "<p><strong>2 records failed to update on aws/main</strong></p><ul><li><strong>Provider</strong>: aws/main</li><li><strong>Records</strong>: vpn.home.com. A 10.0.0.2<br>www.home.com. A 10.0.0.2</li><li><strong>Errors</strong>: throttled</li></ul>",
+			},
+			expectedError: `http error: code 403: {"errcode":"M_FORBIDDEN"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		event := tc.event
+		status := tc.status
+		expectedMessage := tc.expectedMessage
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			var got roomMessage
+			var method, path, token string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, path, token = r.Method, r.URL.EscapedPath(), r.Header.Get("Authorization")
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				if status != 0 {
+					w.WriteHeader(status)
+					_, _ = w.Write([]byte(`{"errcode":"M_FORBIDDEN"}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"event_id":"$1"}`))
+			}))
+			defer srv.Close()
+
+			room := matrixConfig{Name: "ops", Homeserver: srv.URL + "/", AccessToken: "TOKEN", RoomID: "!ops:home.com"}
+			n, err := New(configMock{rooms: []matrixConfig{room}}, loggerMock{}, "ops")
+			assert.NoError(t, err)
+
+			err = n.Notify(context.Background(), event)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, http.MethodPut, method)
+			assert.True(t, strings.HasPrefix(path, "/_matrix/client/v3/rooms/%21ops:home.com/send/m.room.message/simple-ddns-"), path)
+			assert.Equal(t, "Bearer TOKEN", token)
+			assert.Equal(t, expectedMessage, got)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name          string
+		rooms         []matrixConfig
+		expectedName  string
+		expectedError string
+	}{
+		{
+			name:         "configured",
+			rooms:        []matrixConfig{{Name: "ops", Homeserver: "https://matrix.home.com", AccessToken: "TOKEN", RoomID: "!ops:home.com"}},
+			expectedName: "matrix/ops",
+		},
+		{
+			name:          "missing",
+			rooms:         []matrixConfig{},
+			expectedError: "matrix ops doesn't exist",
+		},
+		{
+			name:          "invalid-homeserver",
+			rooms:         []matrixConfig{{Name: "ops", Homeserver: "matrix.home.com", AccessToken: "TOKEN", RoomID: "!ops:home.com"}},
+			expectedError: `invalid url "matrix.home.com"`,
+		},
+		{
+			name:          "missing-token",
+			rooms:         []matrixConfig{{Name: "ops", Homeserver: "https://matrix.home.com", RoomID: "!ops:home.com"}},
+			expectedError: "missing access token",
+		},
+		{
+			name:          "room-alias",
+			rooms:         []matrixConfig{{Name: "ops", Homeserver: "https://matrix.home.com", AccessToken: "TOKEN", RoomID: "#ops:home.com"}},
+			expectedError: `invalid room id "#ops:home.com"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		rooms := tc.rooms
+		expectedName := tc.expectedName
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := New(configMock{rooms: rooms}, loggerMock{}, "ops")
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedName, n.Name())
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend(t *testing.T) {
+	testCases := []struct {
+		name          string
+		status        int
+		expectedError string
+	}{
+		{
+			name:   "accepted",
+			status: http.StatusNoContent,
+		},
+		{
+			name:          "rejected",
+			status:        http.StatusUnauthorized,
+			expectedError: "http error: code 401: invalid token",
+		},
+	}
+
+	for _, tc := range testCases {
+		status := tc.status
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			var got *http.Request
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte("invalid token\n"))
+			}))
+			defer srv.Close()
+
+			header := http.Header{}
+			header.Set("Authorization", "Bearer TOKEN")
+			err := Send(context.Background(), srv.Client(), http.MethodPut, srv.URL+"/message", header, []byte(`{"message":"hi"}`))
+
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, http.MethodPut, got.Method)
+			assert.Equal(t, "/message", got.URL.Path)
+			assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+			assert.Equal(t, "Bearer TOKEN", got.Header.Get("Authorization"))
+			assert.Equal(t, `{"message":"hi"}`, string(body))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+// Message is the flattened form of an event notifiers render, either as json
+// or as the data of a user provided template.
+type Message struct {
+	Event    string          `json:"event"`
+	Severity string          `json:"severity"`
+	Time     time.Time       `json:"time"`
+	Summary  string          `json:"summary"`
+	OldIPv4  string          `json:"old_ipv4,omitempty"`
+	OldIPv6  string          `json:"old_ipv6,omitempty"`
+	NewIPv4  string          `json:"new_ipv4,omitempty"`
+	NewIPv6  string          `json:"new_ipv6,omitempty"`
+	Provider string          `json:"provider,omitempty"`
+	Records  []MessageRecord `json:"records,omitempty"`
+	Errors   []string        `json:"errors,omitempty"`
+}
+
+type MessageRecord struct {
+	FQDN  string `json:"fqdn"`
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+func NewMessage(event notify.Event) Message {
+	message := Message{
+		Event:    string(event.Type),
+		Severity: string(event.Severity),
+		Time:     event.Time,
+		Summary:  event.Summary,
+		OldIPv4:  valueOrEmpty(event.OldIP.V4),
+		OldIPv6:  valueOrEmpty(event.OldIP.V6),
+		NewIPv4:  valueOrEmpty(event.NewIP.V4),
+		NewIPv6:  valueOrEmpty(event.NewIP.V6),
+		Provider: event.Provider,
+		Errors:   event.Errors,
+	}
+
+	for _, record := range event.Records {
+		message.Records = append(message.Records, MessageRecord{FQDN: record.FQDN, Type: string(record.Type), Value: record.Value})
+	}
+
+	return message
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+// Field is a labelled detail of a message, chat notifiers lay fields out the
+// way their service renders best.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields returns the details of the message besides its summary, addresses
+// show the change when the previous one is known.
+func (m Message) Fields() []Field {
+	fields := make([]Field, 0)
+	if ip := ipChange(m.OldIPv4, m.NewIPv4); ip != "" {
+		fields = append(fields, Field{Name: "IPv4", Value: ip})
+	}
+
+	if ip := ipChange(m.OldIPv6, m.NewIPv6); ip != "" {
+		fields = append(fields, Field{Name: "IPv6", Value: ip})
+	}
+
+	if m.Provider != "" {
+		fields = append(fields, Field{Name: "Provider", Value: m.Provider})
+	}
+
+	if len(m.Records) > 0 {
+		records := make([]string, 0, len(m.Records))
+		for _, record := range m.Records {
+			records = append(records, strings.TrimSpace(fmt.Sprintf("%s %s %s", record.FQDN, record.Type, record.Value)))
+		}
+		fields = append(fields, Field{Name: "Records", Value: strings.Join(records, "\n")})
+	}
+
+	if len(m.Errors) > 0 {
+		fields = append(fields, Field{Name: "Errors", Value: strings.Join(m.Errors, "\n")})
+	}
+
+	return fields
+}
+
+// Details renders the fields as plain text, a line per field.
+func (m Message) Details() string {
+	lines := make([]string, 0)
+	for _, field := range m.Fields() {
+		lines = append(lines, fmt.Sprintf("%s: %s", field.Name, strings.ReplaceAll(field.Value, "\n", ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Text renders the message as plain text, the summary followed by its
+// details.
+func (m Message) Text() string {
+	if details := m.Details(); details != "" {
+		return m.Summary + "\n" + details
+	}
+
+	return m.Summary
+}
+
+// Marshal encodes value as json without escaping html characters, bodies
+// aren't embedded in html and summaries like "A 10.0.0.1 -> 10.0.0.2" stay
+// readable.
+func Marshal(value any) ([]byte, error) {
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func ipChange(previous, current string) string {
+	switch {
+	case current == "":
+		return previous
+	case previous == "" || previous == current:
+		return current
+	}
+
+	return previous + " -> " + current
+}
@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMessage(t *testing.T) {
+	oldIP, newIP, newIPv6 := "10.0.0.1", "10.0.0.2", "fd00::2"
+	at := time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)
+
+	message := NewMessage(notify.Event{
+		Type:     notify.UpdateFailed,
+		Severity: notify.Error,
+		Time:     at,
+		Summary:  "1 records failed to update on aws/main",
+		OldIP:    publicip.IP{V4: &oldIP},
+		NewIP:    publicip.IP{V4: &newIP, V6: &newIPv6},
+		Provider: "aws/main",
+		Records:  []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+		Errors:   []string{"throttled"},
+	})
+
+	assert.Equal(t, Message{
+		Event:    "update-failed",
+		Severity: "error",
+		Time:     at,
+		Summary:  "1 records failed to update on aws/main",
+		OldIPv4:  "10.0.0.1",
+		NewIPv4:  "10.0.0.2",
+		NewIPv6:  "fd00::2",
+		Provider: "aws/main",
+		Records:  []MessageRecord{{FQDN: "vpn.home.com.", Type: "A", Value: "10.0.0.2"}},
+		Errors:   []string{"throttled"},
+	}, message)
+}
+
+func TestText(t *testing.T) {
+	testCases := []struct {
+		name         string
+		message      Message
+		expectedText string
+	}{
+		{
+			name: "ip-changed",
+			message: Message{
+				Summary: "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				OldIPv4: "10.0.0.1",
+				NewIPv4: "10.0.0.2",
+				OldIPv6: "fd00::1",
+				NewIPv6: "fd00::1",
+			},
+			expectedText: "public ip changed: A 10.0.0.1 -> 10.0.0.2\nIPv4: 10.0.0.1 -> 10.0.0.2\nIPv6: fd00::1",
+		},
+		{
+			name: "update-failed",
+			message: Message{
+				Summary:  "2 records failed to update on aws/main",
+				NewIPv4:  "10.0.0.2",
+				Provider: "aws/main",
+				Records: []MessageRecord{
+					{FQDN: "vpn.home.com.", Type: "A", Value: "10.0.0.2"},
+					{FQDN: "www.home.com.", Type: "A"},
+				},
+				Errors: []string{"throttled"},
+			},
+			expectedText: "2 records failed to update on aws/main\nIPv4: 10.0.0.2\nProvider: aws/main\nRecords: vpn.home.com. A 10.0.0.2, www.home.com. A\nErrors: throttled",
+		},
+	}
+
+	for _, tc := range testCases {
+		message := tc.message
+		expectedText := tc.expectedText
+
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, expectedText, message.Text())
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	body, err := Marshal(Message{Event: "ip-changed", Summary: "public ip changed: A 10.0.0.1 -> 10.0.0.2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"event":"ip-changed","severity":"","time":"0001-01-01T00:00:00Z","summary":"public ip changed: A 10.0.0.1 -> 10.0.0.2"}`, string(body))
+}
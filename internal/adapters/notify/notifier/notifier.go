@@ -0,0 +1,242 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+const (
+	notificationsPath  string = "ddns.notifications"
+	timeoutPath        string = notificationsPath + ".timeout-secs"
+	defaultTimeoutSecs int    = 10
+)
+
+// settings are the keys under ddns.notifications that don't name a notifier.
+var settings = []string{"timeout-secs", "source-outage-mins"}
+
+type ConfigDecoder interface {
+	Decode(node string, item any) error
+}
+
+type MessageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+// Factory builds one notify.Notifier per entry configured under
+// ddns.notifications.<name>.
+type Factory func(cnf ConfigDecoder, logger MessageLogger) ([]notify.Notifier, error)
+
+// Filter selects the events sent to a notifier, every event is sent when no
+// event type is listed and no minimum severity is set.
+type Filter struct {
+	Events      []string `yaml:"events"`
+	MinSeverity string   `yaml:"min-severity"`
+}
+
+type registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+var defaultRegistry = newRegistry()
+
+func newRegistry() *registry {
+	return &registry{factories: map[string]Factory{}}
+}
+
+// Register makes a notifier available under its config key. It is meant to be
+// called from the init function of each notifier adapter.
+func Register(name string, factory Factory) {
+	defaultRegistry.register(name, factory)
+}
+
+func Notifiers() []string {
+	return defaultRegistry.notifiers()
+}
+
+// New builds every notifier configured under ddns.notifications and returns a
+// notifier sending each event to all of them, giving each one up to
+// ddns.notifications.timeout-secs. Nothing is sent when none is configured.
+func New(cnf ConfigDecoder, logger MessageLogger) (notify.Notifier, error) {
+	return defaultRegistry.newNotifier(cnf, logger)
+}
+
+func (r *registry) register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if factory == nil {
+		panic("notifier: register factory is nil for " + name)
+	}
+
+	if _, exists := r.factories[name]; exists {
+		panic("notifier: register called twice for " + name)
+	}
+
+	r.factories[name] = factory
+}
+
+func (r *registry) notifiers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (r *registry) newNotifier(cnf ConfigDecoder, logger MessageLogger) (notify.Notifier, error) {
+	fanout := &fanout{timeout: time.Duration(defaultTimeoutSecs) * time.Second}
+
+	configured := map[string]any{}
+	if err := cnf.Decode(notificationsPath, &configured); err != nil {
+		return fanout, nil
+	}
+
+	timeoutSecs := defaultTimeoutSecs
+	if err := cnf.Decode(timeoutPath, &timeoutSecs); err == nil {
+		if timeoutSecs <= 0 {
+			return nil, fmt.Errorf("notifier: invalid timeout %d", timeoutSecs)
+		}
+		fanout.timeout = time.Duration(timeoutSecs) * time.Second
+	}
+
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		if !slices.Contains(settings, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, name := range names {
+		factory, ok := r.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("notifier: unknown notifier %s", name)
+		}
+
+		notifiers, err := factory(cnf, logger)
+		if err != nil {
+			return nil, fmt.Errorf("notifier: unable to create %s notifiers, err:%w", name, err)
+		}
+
+		fanout.notifiers = append(fanout.notifiers, notifiers...)
+	}
+
+	return fanout, nil
+}
+
+// Filtered only sends notifier the events filter accepts, filters listing
+// unknown event types or severities are rejected.
+func Filtered(notifier notify.Notifier, filter Filter) (notify.Notifier, error) {
+	if len(filter.Events) == 0 && filter.MinSeverity == "" {
+		return notifier, nil
+	}
+
+	f := &filtered{next: notifier, events: notify.EventTypes(), minSeverity: notify.Info}
+	if len(filter.Events) > 0 {
+		f.events = make([]notify.EventType, 0, len(filter.Events))
+	}
+
+	for _, event := range filter.Events {
+		eventType := notify.EventType(event)
+		if !slices.Contains(notify.EventTypes(), eventType) {
+			return nil, fmt.Errorf("notifier: unknown event %s for %s", event, notifier.Name())
+		}
+
+		f.events = append(f.events, eventType)
+	}
+
+	if filter.MinSeverity != "" {
+		f.minSeverity = notify.Severity(filter.MinSeverity)
+		if !slices.Contains(notify.Severities(), f.minSeverity) {
+			return nil, fmt.Errorf("notifier: unknown severity %s for %s", filter.MinSeverity, notifier.Name())
+		}
+	}
+
+	return f, nil
+}
+
+type filtered struct {
+	next        notify.Notifier
+	events      []notify.EventType
+	minSeverity notify.Severity
+}
+
+func (f *filtered) Name() string {
+	return f.next.Name()
+}
+
+func (f *filtered) Notify(ctx context.Context, event notify.Event) error {
+	if !slices.Contains(f.events, event.Type) || !event.Severity.AtLeast(f.minSeverity) {
+		return nil
+	}
+
+	return f.next.Notify(ctx, event)
+}
+
+func (f *filtered) Close() error {
+	return notify.Close(f.next)
+}
+
+type fanout struct {
+	notifiers []notify.Notifier
+	timeout   time.Duration
+}
+
+func (f *fanout) Name() string {
+	return "notifications"
+}
+
+// Notify sends event to every notifier concurrently, so a slow one delays the
+// sync cycle by its own timeout at most. One failing doesn't keep the event
+// from the others, errors are returned in notifier order.
+func (f *fanout) Notify(ctx context.Context, event notify.Event) error {
+	errs := make([]error, len(f.notifiers))
+
+	wg := sync.WaitGroup{}
+	for i, notifier := range f.notifiers {
+		wg.Add(1)
+		go func(i int, notifier notify.Notifier) {
+			defer wg.Done()
+
+			notifyCtx, cancel := context.WithTimeout(ctx, f.timeout)
+			defer cancel()
+
+			if err := notifier.Notify(notifyCtx, event); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", notifier.Name(), err)
+			}
+		}(i, notifier)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Close closes every notifier, one failing doesn't keep the others from
+// sending what they hold back.
+func (f *fanout) Close() error {
+	errs := make([]error, 0, len(f.notifiers))
+	for _, notifier := range f.notifiers {
+		if err := notify.Close(notifier); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,314 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	values map[string]any
+}
+
+func (c configMock) Decode(node string, item any) error {
+	value, exists := c.values[node]
+	if !exists {
+		return errors.New("node not found")
+	}
+
+	reflect.ValueOf(item).Elem().Set(reflect.ValueOf(value))
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+type notifierMock struct {
+	name     string
+	err      error
+	received []notify.Event
+	deadline time.Duration
+}
+
+func (n *notifierMock) Name() string {
+	return n.name
+}
+
+func (n *notifierMock) Notify(ctx context.Context, event notify.Event) error {
+	n.received = append(n.received, event)
+	if deadline, ok := ctx.Deadline(); ok {
+		n.deadline = time.Until(deadline).Round(time.Second)
+	}
+
+	return n.err
+}
+
+func factoryFor(names ...string) Factory {
+	return func(cnf ConfigDecoder, logger MessageLogger) ([]notify.Notifier, error) {
+		notifiers := []notify.Notifier{}
+		for _, name := range names {
+			notifiers = append(notifiers, &notifierMock{name: name})
+		}
+
+		return notifiers, nil
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	testCases := []struct {
+		name            string
+		config          configMock
+		expectedNames   []string
+		expectedTimeout time.Duration
+		expectedError   string
+	}{
+		{
+			name: "configured-notifiers",
+			config: configMock{values: map[string]any{
+				notificationsPath: map[string]any{"webhook": []any{}, "email": []any{}, "source-outage-mins": 30},
+			}},
+			expectedNames:   []string{"email/home", "webhook/ops", "webhook/pager"},
+			expectedTimeout: 10 * time.Second,
+		},
+		{
+			name: "custom-timeout",
+			config: configMock{values: map[string]any{
+				notificationsPath: map[string]any{"webhook": []any{}, "timeout-secs": 3},
+				timeoutPath:       3,
+			}},
+			expectedNames:   []string{"webhook/ops", "webhook/pager"},
+			expectedTimeout: 3 * time.Second,
+		},
+		{
+			name:            "not-configured",
+			config:          configMock{values: map[string]any{}},
+			expectedNames:   []string{},
+			expectedTimeout: 10 * time.Second,
+		},
+		{
+			name: "invalid-timeout",
+			config: configMock{values: map[string]any{
+				notificationsPath: map[string]any{"timeout-secs": 0},
+				timeoutPath:       0,
+			}},
+			expectedError: "notifier: invalid timeout 0",
+		},
+		{
+			name: "unknown-notifier",
+			config: configMock{values: map[string]any{
+				notificationsPath: map[string]any{"pigeon": []any{}},
+			}},
+			expectedError: "notifier: unknown notifier pigeon",
+		},
+		{
+			name: "failing-notifier",
+			config: configMock{values: map[string]any{
+				notificationsPath: map[string]any{"broken": []any{}},
+			}},
+			expectedError: "notifier: unable to create broken notifiers, err:invalid url",
+		},
+	}
+
+	reg := newRegistry()
+	reg.register("webhook", factoryFor("webhook/ops", "webhook/pager"))
+	reg.register("email", factoryFor("email/home"))
+	reg.register("broken", func(cnf ConfigDecoder, logger MessageLogger) ([]notify.Notifier, error) {
+		return nil, errors.New("invalid url")
+	})
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		expectedNames := tc.expectedNames
+		expectedTimeout := tc.expectedTimeout
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := reg.newNotifier(cnf, loggerMock{})
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			f := n.(*fanout)
+			names := []string{}
+			for _, notifier := range f.notifiers {
+				names = append(names, notifier.Name())
+			}
+			assert.Equal(t, expectedNames, names)
+			assert.Equal(t, expectedTimeout, f.timeout)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	reg := newRegistry()
+	reg.register("webhook", factoryFor())
+
+	assert.Equal(t, []string{"webhook"}, reg.notifiers())
+	assert.Panics(t, func() { reg.register("webhook", factoryFor()) })
+	assert.Panics(t, func() { reg.register("nil", nil) })
+}
+
+func TestFiltered(t *testing.T) {
+	testCases := []struct {
+		name          string
+		filter        Filter
+		event         notify.Event
+		expectedSent  bool
+		expectedError string
+	}{
+		{
+			name:         "no-filter",
+			event:        notify.Event{Type: notify.RecordsUpdated, Severity: notify.Info},
+			expectedSent: true,
+		},
+		{
+			name:         "listed-event",
+			filter:       Filter{Events: []string{"update-failed", "source-outage"}},
+			event:        notify.Event{Type: notify.SourceOutage, Severity: notify.Error},
+			expectedSent: true,
+		},
+		{
+			name:   "unlisted-event",
+			filter: Filter{Events: []string{"update-failed", "source-outage"}},
+			event:  notify.Event{Type: notify.RecordsUpdated, Severity: notify.Info},
+		},
+		{
+			name:         "severe-enough",
+			filter:       Filter{MinSeverity: "warning"},
+			event:        notify.Event{Type: notify.UpdateFailed, Severity: notify.Error},
+			expectedSent: true,
+		},
+		{
+			name:   "not-severe-enough",
+			filter: Filter{MinSeverity: "warning"},
+			event:  notify.Event{Type: notify.IPChanged, Severity: notify.Info},
+		},
+		{
+			name:   "listed-event-not-severe-enough",
+			filter: Filter{Events: []string{"ip-changed"}, MinSeverity: "error"},
+			event:  notify.Event{Type: notify.IPChanged, Severity: notify.Info},
+		},
+		{
+			name:          "unknown-event",
+			filter:        Filter{Events: []string{"ip-lost"}},
+			expectedError: "notifier: unknown event ip-lost for webhook/ops",
+		},
+		{
+			name:          "unknown-severity",
+			filter:        Filter{MinSeverity: "critical"},
+			expectedError: "notifier: unknown severity critical for webhook/ops",
+		},
+	}
+
+	for _, tc := range testCases {
+		filter := tc.filter
+		event := tc.event
+		expectedSent := tc.expectedSent
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &notifierMock{name: "webhook/ops"}
+			n, err := Filtered(mock, filter)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "webhook/ops", n.Name())
+			assert.NoError(t, n.Notify(context.Background(), event))
+			assert.Equal(t, expectedSent, len(mock.received) == 1)
+		})
+	}
+}
+
+func TestFanoutNotify(t *testing.T) {
+	ops := &notifierMock{name: "webhook/ops", err: errors.New("http error: code 502")}
+	home := &notifierMock{name: "email/home"}
+	f := &fanout{notifiers: []notify.Notifier{ops, home}, timeout: 5 * time.Second}
+
+	err := f.Notify(context.Background(), notify.Event{Type: notify.UpdateFailed})
+
+	assert.EqualError(t, err, "webhook/ops: http error: code 502")
+	assert.Len(t, ops.received, 1)
+	assert.Len(t, home.received, 1)
+	assert.Equal(t, 5*time.Second, home.deadline)
+}
+
+// closingNotifierMock counts the times it's closed.
+type closingNotifierMock struct {
+	notifierMock
+	closeErr error
+	closed   int
+}
+
+func (n *closingNotifierMock) Close() error {
+	n.closed++
+	return n.closeErr
+}
+
+func TestFanoutClose(t *testing.T) {
+	ops := &closingNotifierMock{notifierMock: notifierMock{name: "webhook/ops"}, closeErr: errors.New("smtp error")}
+	home := &closingNotifierMock{notifierMock: notifierMock{name: "email/home"}}
+	filteredHome, err := Filtered(home, Filter{MinSeverity: "error"})
+	assert.NoError(t, err)
+	f := &fanout{notifiers: []notify.Notifier{ops, &notifierMock{name: "script/log"}, filteredHome}}
+
+	assert.EqualError(t, f.Close(), "webhook/ops: smtp error")
+	assert.Equal(t, 1, ops.closed)
+	assert.Equal(t, 1, home.closed)
+}
+
+// blockingNotifierMock holds every event until release is closed.
+type blockingNotifierMock struct {
+	name    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (n *blockingNotifierMock) Name() string {
+	return n.name
+}
+
+func (n *blockingNotifierMock) Notify(ctx context.Context, event notify.Event) error {
+	n.started <- struct{}{}
+	<-n.release
+	return nil
+}
+
+func TestFanoutNotifyConcurrently(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	f := &fanout{
+		notifiers: []notify.Notifier{
+			&blockingNotifierMock{name: "webhook/ops", started: started, release: release},
+			&blockingNotifierMock{name: "email/home", started: started, release: release},
+		},
+		timeout: 5 * time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Notify(context.Background(), notify.Event{Type: notify.UpdateFailed})
+	}()
+
+	for range 2 {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("notifiers weren't called concurrently")
+		}
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,163 @@
+package ntfy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+const (
+	notifierName  string = "ntfy"
+	ntfyPath      string = "ddns.notifications." + notifierName
+	defaultServer string = "https://ntfy.sh"
+)
+
+func init() {
+	notifier.Register(notifierName, func(cnf notifier.ConfigDecoder, logger notifier.MessageLogger) ([]notify.Notifier, error) {
+		return newNotifiers(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type ntfyConfig struct {
+	Name            string   `yaml:"name"`
+	Server          string   `yaml:"server"`
+	Topic           string   `yaml:"topic"`
+	Token           string   `yaml:"token"`
+	Tags            []string `yaml:"tags"`
+	notifier.Filter `yaml:",inline"`
+}
+
+// publishMessage is the json form of an ntfy publish request, it's posted to
+// the server root since the topic travels in the body.
+type publishMessage struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags"`
+}
+
+// severityPriorities maps severities to ntfy priorities, 3 is the default and
+// 5 the urgent one, the only breaking through do not disturb.
+var severityPriorities = map[notify.Severity]int{
+	notify.Info:    3,
+	notify.Warning: 4,
+	notify.Error:   5,
+}
+
+// eventTags are rendered by ntfy clients as emojis in front of the title.
+var eventTags = map[notify.EventType]string{
+	notify.IPChanged:       "globe_with_meridians",
+	notify.RecordsUpdated:  "white_check_mark",
+	notify.UpdateFailed:    "rotating_light",
+	notify.SourceOutage:    "warning",
+	notify.SourceRecovered: "white_check_mark",
+}
+
+type ntfy struct {
+	config ntfyConfig
+	client notifier.HTTPRequestor
+	logger messageLogger
+}
+
+// New builds the ntfy topic configured as name under ddns.notifications.ntfy.
+// The server defaults to ntfy.sh, the token is only needed for protected
+// topics.
+func New(cnf configDecoder, logger messageLogger, name string) (notify.Notifier, error) {
+	topics := []ntfyConfig{}
+	if err := cnf.Decode(ntfyPath, &topics); err != nil {
+		return nil, err
+	}
+
+	for _, topic := range topics {
+		if topic.Name != name {
+			continue
+		}
+
+		if topic.Server == "" {
+			topic.Server = defaultServer
+		}
+
+		server, err := notifier.ParseURL(topic.Server)
+		if err != nil {
+			return nil, err
+		}
+		topic.Server = strings.TrimSuffix(server.String(), "/")
+
+		if topic.Topic == "" {
+			return nil, fmt.Errorf("missing topic")
+		}
+
+		return notifier.Filtered(&ntfy{config: topic, client: http.DefaultClient, logger: logger}, topic.Filter)
+	}
+
+	return nil, fmt.Errorf("ntfy %s doesn't exist", name)
+}
+
+func newNotifiers(cnf configDecoder, logger messageLogger) ([]notify.Notifier, error) {
+	topics := []ntfyConfig{}
+	if err := cnf.Decode(ntfyPath, &topics); err != nil {
+		return nil, err
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(topics))
+	for _, topic := range topics {
+		n, err := New(cnf, logger, topic.Name)
+		if err != nil {
+			return nil, fmt.Errorf("ntfy: unable to create ntfy %s, err:%w", topic.Name, err)
+		}
+
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+func (n *ntfy) Name() string {
+	return fmt.Sprintf("%s/%s", notifierName, n.config.Name)
+}
+
+func (n *ntfy) Notify(ctx context.Context, event notify.Event) error {
+	message := notifier.NewMessage(event)
+	msg := publishMessage{
+		Topic:    n.config.Topic,
+		Title:    message.Summary,
+		Message:  message.Details(),
+		Priority: severityPriorities[event.Severity],
+		Tags:     append([]string{eventTags[event.Type]}, n.config.Tags...),
+	}
+
+	if msg.Message == "" {
+		msg.Message = message.Summary
+	}
+
+	body, err := notifier.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	if n.config.Token != "" {
+		header.Set("Authorization", "Bearer "+n.config.Token)
+	}
+
+	if err = notifier.Send(ctx, n.client, http.MethodPost, n.config.Server, header, body); err != nil {
+		return err
+	}
+
+	n.logger.Debug(fmt.Sprintf("ntfy: %s sent to %s", event.Type, n.config.Name))
+	return nil
+}
@@ -0,0 +1,195 @@
+package ntfy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	topics []ntfyConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	*item.(*[]ntfyConfig) = c.topics
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+func TestNotify(t *testing.T) {
+	oldIP, newIP := "10.0.0.1", "10.0.0.2"
+	at := time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		topic           ntfyConfig
+		event           notify.Event
+		status          int
+		expectedToken   string
+		expectedMessage publishMessage
+		expectedError   string
+	}{
+		{
+			name:  "ip-changed",
+			topic: ntfyConfig{Name: "ops", Topic: "home-ddns", Tags: []string{"house"}},
+			event: notify.Event{
+				Type:     notify.IPChanged,
+				Severity: notify.Info,
+				Time:     at,
+				Summary:  "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				OldIP:    publicip.IP{V4: &oldIP},
+				NewIP:    publicip.IP{V4: &newIP},
+			},
+			expectedMessage: publishMessage{
+				Topic:    "home-ddns",
+				Title:    "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				Message:  "IPv4: 10.0.0.1 -> 10.0.0.2",
+				Priority: 3,
+				Tags:     []string{"globe_with_meridians", "house"},
+			},
+		},
+		{
+			name:  "update-failed-protected-topic",
+			topic: ntfyConfig{Name: "ops", Topic: "home-ddns", Token: "tk_TOKEN"},
+			event: notify.Event{
+				Type:     notify.UpdateFailed,
+				Severity: notify.Error,
+				Time:     at,
+				Summary:  "1 records failed to update on aws/main",
+				Provider: "aws/main",
+				Records:  []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+				Errors:   []string{"throttled"},
+			},
+			expectedToken: "Bearer tk_TOKEN",
+			expectedMessage: publishMessage{
+				Topic:    "home-ddns",
+				Title:    "1 records failed to update on aws/main",
+				Message:  "Provider: aws/main\nRecords: vpn.home.com. A 10.0.0.2\nErrors: throttled",
+				Priority: 5,
+				Tags:     []string{"rotating_light"},
+			},
+		},
+		{
+			name:  "source-outage-rejected",
+			topic: ntfyConfig{Name: "ops", Topic: "home-ddns"},
+			event: notify.Event{
+				Type:     notify.SourceOutage,
+				Severity: notify.Error,
+				Time:     at,
+				Summary:  "no public ip source answered for 15m0s",
+			},
+			status: http.StatusTooManyRequests,
+			expectedMessage: publishMessage{
+				Topic:    "home-ddns",
+				Title:    "no public ip source answered for 15m0s",
+				Message:  "no public ip source answered for 15m0s",
+				Priority: 5,
+				Tags:     []string{"warning"},
+			},
+			expectedError: `http error: code 429: {"code":42901,"error":"limit reached"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		topic := tc.topic
+		event := tc.event
+		status := tc.status
+		expectedToken := tc.expectedToken
+		expectedMessage := tc.expectedMessage
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			var got publishMessage
+			var path, token string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, token = r.URL.Path, r.Header.Get("Authorization")
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				if status != 0 {
+					w.WriteHeader(status)
+					_, _ = w.Write([]byte(`{"code":42901,"error":"limit reached"}`))
+				}
+			}))
+			defer srv.Close()
+
+			topic.Server = srv.URL
+			n, err := New(configMock{topics: []ntfyConfig{topic}}, loggerMock{}, "ops")
+			assert.NoError(t, err)
+
+			err = n.Notify(context.Background(), event)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, "/", path)
+			assert.Equal(t, expectedToken, token)
+			assert.Equal(t, expectedMessage, got)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name           string
+		topics         []ntfyConfig
+		expectedServer string
+		expectedError  string
+	}{
+		{
+			name:           "default-server",
+			topics:         []ntfyConfig{{Name: "ops", Topic: "home-ddns"}},
+			expectedServer: "https://ntfy.sh",
+		},
+		{
+			name:           "self-hosted",
+			topics:         []ntfyConfig{{Name: "ops", Server: "https://ntfy.home.com/", Topic: "home-ddns"}},
+			expectedServer: "https://ntfy.home.com",
+		},
+		{
+			name:          "missing",
+			topics:        []ntfyConfig{},
+			expectedError: "ntfy ops doesn't exist",
+		},
+		{
+			name:          "missing-topic",
+			topics:        []ntfyConfig{{Name: "ops"}},
+			expectedError: "missing topic",
+		},
+		{
+			name:          "invalid-server",
+			topics:        []ntfyConfig{{Name: "ops", Server: "ntfy.home.com", Topic: "home-ddns"}},
+			expectedError: `invalid url "ntfy.home.com"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		topics := tc.topics
+		expectedServer := tc.expectedServer
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := New(configMock{topics: topics}, loggerMock{}, "ops")
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "ntfy/ops", n.Name())
+			assert.Equal(t, expectedServer, n.(*ntfy).config.Server)
+		})
+	}
+}
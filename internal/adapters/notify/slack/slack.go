@@ -0,0 +1,163 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+const (
+	notifierName string = "slack"
+	slackPath    string = "ddns.notifications." + notifierName
+)
+
+func init() {
+	notifier.Register(notifierName, func(cnf notifier.ConfigDecoder, logger notifier.MessageLogger) ([]notify.Notifier, error) {
+		return newNotifiers(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type slackConfig struct {
+	Name            string `yaml:"name"`
+	URL             string `yaml:"url"`
+	notifier.Filter `yaml:",inline"`
+}
+
+// slackMessage is an incoming webhook payload, text is shown in
+// notifications and by clients unable to render blocks.
+type slackMessage struct {
+	Text   string  `json:"text"`
+	Blocks []block `json:"blocks"`
+}
+
+type block struct {
+	Type     string `json:"type"`
+	Text     *text  `json:"text,omitempty"`
+	Fields   []text `json:"fields,omitempty"`
+	Elements []text `json:"elements,omitempty"`
+}
+
+type text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+var severityEmojis = map[notify.Severity]string{
+	notify.Info:    ":information_source:",
+	notify.Warning: ":warning:",
+	notify.Error:   ":rotating_light:",
+}
+
+type slack struct {
+	config slackConfig
+	client notifier.HTTPRequestor
+	logger messageLogger
+}
+
+// New builds the slack incoming webhook configured as name under
+// ddns.notifications.slack.
+func New(cnf configDecoder, logger messageLogger, name string) (notify.Notifier, error) {
+	channels := []slackConfig{}
+	if err := cnf.Decode(slackPath, &channels); err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		if channel.Name != name {
+			continue
+		}
+
+		endpoint, err := notifier.ParseURL(channel.URL)
+		if err != nil {
+			return nil, err
+		}
+		channel.URL = endpoint.String()
+
+		return notifier.Filtered(&slack{config: channel, client: http.DefaultClient, logger: logger}, channel.Filter)
+	}
+
+	return nil, fmt.Errorf("slack %s doesn't exist", name)
+}
+
+func newNotifiers(cnf configDecoder, logger messageLogger) ([]notify.Notifier, error) {
+	channels := []slackConfig{}
+	if err := cnf.Decode(slackPath, &channels); err != nil {
+		return nil, err
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(channels))
+	for _, channel := range channels {
+		n, err := New(cnf, logger, channel.Name)
+		if err != nil {
+			return nil, fmt.Errorf("slack: unable to create slack %s, err:%w", channel.Name, err)
+		}
+
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+func (s *slack) Name() string {
+	return fmt.Sprintf("%s/%s", notifierName, s.config.Name)
+}
+
+func (s *slack) Notify(ctx context.Context, event notify.Event) error {
+	body, err := notifier.Marshal(newSlackMessage(notifier.NewMessage(event)))
+	if err != nil {
+		return err
+	}
+
+	if err = notifier.Send(ctx, s.client, http.MethodPost, s.config.URL, nil, body); err != nil {
+		return err
+	}
+
+	s.logger.Debug(fmt.Sprintf("slack: %s sent to %s", event.Type, s.config.Name))
+	return nil
+}
+
+// newSlackMessage lays message out as a headline, a section with a field per
+// detail and a context line with the event type and time.
+func newSlackMessage(message notifier.Message) slackMessage {
+	headline := fmt.Sprintf("%s *%s*", severityEmojis[notify.Severity(message.Severity)], escape(message.Summary))
+	msg := slackMessage{
+		Text:   message.Summary,
+		Blocks: []block{{Type: "section", Text: &text{Type: "mrkdwn", Text: strings.TrimSpace(headline)}}},
+	}
+
+	if fields := message.Fields(); len(fields) > 0 {
+		section := block{Type: "section"}
+		for _, field := range fields {
+			section.Fields = append(section.Fields, text{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", field.Name, escape(field.Value))})
+		}
+		msg.Blocks = append(msg.Blocks, section)
+	}
+
+	msg.Blocks = append(msg.Blocks, block{
+		Type: "context",
+		Elements: []text{{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("simple-ddns · %s · <!date^%d^{date_short_pretty} {time_secs}|%s>", message.Event, message.Time.Unix(), message.Time.UTC().Format("2006-01-02 15:04:05 UTC")),
+		}},
+	})
+
+	return msg
+}
+
+// escape escapes the characters slack treats as control sequences in mrkdwn.
+func escape(value string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(value)
+}
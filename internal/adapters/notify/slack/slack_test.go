@@ -0,0 +1,161 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	channels []slackConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	*item.(*[]slackConfig) = c.channels
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+func TestNotify(t *testing.T) {
+	oldIP, newIP := "10.0.0.1", "10.0.0.2"
+	at := time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		event           notify.Event
+		status          int
+		expectedMessage slackMessage
+		expectedError   string
+	}{
+		{
+			name: "ip-changed",
+			event: notify.Event{
+				Type:     notify.IPChanged,
+				Severity: notify.Info,
+				Time:     at,
+				Summary:  "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				OldIP:    publicip.IP{V4: &oldIP},
+				NewIP:    publicip.IP{V4: &newIP},
+			},
+			expectedMessage: slackMessage{
+				Text: "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+				Blocks: []block{
+					{Type: "section", Text: &text{Type: "mrkdwn", Text: ":information_source: *public ip changed: A 10.0.0.1 -&gt; 10.0.0.2*"}},
+					{Type: "section", Fields: []text{{Type: "mrkdwn", Text: "*IPv4*\n10.0.0.1 -&gt; 10.0.0.2"}}},
+					{Type: "context", Elements: []text{{Type: "mrkdwn", Text: "simple-ddns · ip-changed · <!date^1735898400^{date_short_pretty} {time_secs}|2025-01-03 10:00:00 UTC>"}}},
+				},
+			},
+		},
+		{
+			name: "update-failed",
+			event: notify.Event{
+				Type:     notify.UpdateFailed,
+				Severity: notify.Error,
+				Time:     at,
+				Summary:  "1 records failed to update on aws/main",
+				Provider: "aws/main",
+				Records:  []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+				Errors:   []string{"throttled"},
+			},
+			status: http.StatusForbidden,
+			expectedMessage: slackMessage{
+				Text: "1 records failed to update on aws/main",
+				Blocks: []block{
+					{Type: "section", Text: &text{Type: "mrkdwn", Text: ":rotating_light: *1 records failed to update on aws/main*"}},
+					{Type: "section", Fields: []text{
+						{Type: "mrkdwn", Text: "*Provider*\naws/main"},
+						{Type: "mrkdwn", Text: "*Records*\nvpn.home.com. A 10.0.0.2"},
+						{Type: "mrkdwn", Text: "*Errors*\nthrottled"},
+					}},
+					{Type: "context", Elements: []text{{Type: "mrkdwn", Text: "simple-ddns · update-failed · <!date^1735898400^{date_short_pretty} {time_secs}|2025-01-03 10:00:00 UTC>"}}},
+				},
+			},
+			expectedError: "http error: code 403: invalid_token",
+		},
+	}
+
+	for _, tc := range testCases {
+		event := tc.event
+		status := tc.status
+		expectedMessage := tc.expectedMessage
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			var got slackMessage
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				if status != 0 {
+					w.WriteHeader(status)
+					_, _ = w.Write([]byte("invalid_token"))
+				}
+			}))
+			defer srv.Close()
+
+			n, err := New(configMock{channels: []slackConfig{{Name: "ops", URL: srv.URL + "/services/T000/B000/XXX"}}}, loggerMock{}, "ops")
+			assert.NoError(t, err)
+
+			err = n.Notify(context.Background(), event)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, expectedMessage, got)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name          string
+		channels      []slackConfig
+		expectedName  string
+		expectedError string
+	}{
+		{
+			name:         "configured",
+			channels:     []slackConfig{{Name: "ops", URL: "https://hooks.slack.com/services/T000/B000/XXX"}},
+			expectedName: "slack/ops",
+		},
+		{
+			name:          "missing",
+			channels:      []slackConfig{},
+			expectedError: "slack ops doesn't exist",
+		},
+		{
+			name:          "invalid-url",
+			channels:      []slackConfig{{Name: "ops", URL: "hooks.slack.com"}},
+			expectedError: `invalid url "hooks.slack.com"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		channels := tc.channels
+		expectedName := tc.expectedName
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := New(configMock{channels: channels}, loggerMock{}, "ops")
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedName, n.Name())
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+)
+
+const (
+	notifierName           string = "webhook"
+	webhooksPath           string = "ddns.notifications." + notifierName
+	defaultSignatureHeader string = "X-Simple-DDNS-Signature"
+	eventHeader            string = "X-Simple-DDNS-Event"
+)
+
+func init() {
+	notifier.Register(notifierName, func(cnf notifier.ConfigDecoder, logger notifier.MessageLogger) ([]notify.Notifier, error) {
+		return newNotifiers(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type webhookConfig struct {
+	Name            string            `yaml:"name"`
+	URL             string            `yaml:"url"`
+	Method          string            `yaml:"method"`
+	Headers         map[string]string `yaml:"headers"`
+	Secret          string            `yaml:"secret"`
+	SignatureHeader string            `yaml:"signature-header"`
+	Template        string            `yaml:"template"`
+	notifier.Filter `yaml:",inline"`
+}
+
+type webhook struct {
+	config   webhookConfig
+	template *template.Template
+	client   notifier.HTTPRequestor
+	logger   messageLogger
+}
+
+// New builds the webhook configured as name under ddns.notifications.webhook.
+// The body is the json encoded notifier.Message unless a template is set, the
+// template is executed with the message as data and must render valid json.
+// When a secret is set the body is signed with HMAC-SHA256 and sent in the
+// signature header as sha256=<hex>.
+func New(cnf configDecoder, logger messageLogger, name string) (notify.Notifier, error) {
+	hooks := []webhookConfig{}
+	if err := cnf.Decode(webhooksPath, &hooks); err != nil {
+		return nil, err
+	}
+
+	for _, hook := range hooks {
+		if hook.Name != name {
+			continue
+		}
+
+		w, err := newWebhook(hook, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		return notifier.Filtered(w, hook.Filter)
+	}
+
+	return nil, fmt.Errorf("webhook %s doesn't exist", name)
+}
+
+func newNotifiers(cnf configDecoder, logger messageLogger) ([]notify.Notifier, error) {
+	hooks := []webhookConfig{}
+	if err := cnf.Decode(webhooksPath, &hooks); err != nil {
+		return nil, err
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(hooks))
+	for _, hook := range hooks {
+		n, err := New(cnf, logger, hook.Name)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: unable to create webhook %s, err:%w", hook.Name, err)
+		}
+
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+func newWebhook(hook webhookConfig, logger messageLogger) (*webhook, error) {
+	endpoint, err := notifier.ParseURL(hook.URL)
+	if err != nil {
+		return nil, err
+	}
+	hook.URL = endpoint.String()
+
+	if hook.Method == "" {
+		hook.Method = http.MethodPost
+	}
+
+	if hook.SignatureHeader == "" {
+		hook.SignatureHeader = defaultSignatureHeader
+	}
+
+	w := &webhook{config: hook, client: http.DefaultClient, logger: logger}
+	if hook.Template != "" {
+		w.template, err = template.New(hook.Name).Funcs(template.FuncMap{"json": toJSON}).Parse(hook.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template, err:%w", err)
+		}
+	}
+
+	return w, nil
+}
+
+func (w *webhook) Name() string {
+	return fmt.Sprintf("%s/%s", notifierName, w.config.Name)
+}
+
+func (w *webhook) Notify(ctx context.Context, event notify.Event) error {
+	body, err := w.body(notifier.NewMessage(event))
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set(eventHeader, string(event.Type))
+	for name, value := range w.config.Headers {
+		header.Set(name, value)
+	}
+
+	if w.config.Secret != "" {
+		header.Set(w.config.SignatureHeader, Sign(w.config.Secret, body))
+	}
+
+	if err = notifier.Send(ctx, w.client, w.config.Method, w.config.URL, header, body); err != nil {
+		return err
+	}
+
+	w.logger.Debug(fmt.Sprintf("webhook: %s sent to %s", event.Type, w.config.Name))
+	return nil
+}
+
+func (w *webhook) body(message notifier.Message) ([]byte, error) {
+	if w.template == nil {
+		return notifier.Marshal(message)
+	}
+
+	buf := bytes.Buffer{}
+	if err := w.template.Execute(&buf, message); err != nil {
+		return nil, fmt.Errorf("template error: %w", err)
+	}
+
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("template error: rendered body isn't valid json")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Sign returns the signature header value receivers can verify the body with.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func toJSON(value any) (string, error) {
+	buf, err := notifier.Marshal(value)
+	return string(buf), err
+}
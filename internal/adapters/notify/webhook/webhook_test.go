@@ -0,0 +1,255 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/notify/notifier"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	hooks []webhookConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	*item.(*[]webhookConfig) = c.hooks
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+type receivedCall struct {
+	Method    string
+	Event     string
+	Signature string
+	Token     string
+	Body      string
+}
+
+type fakeReceiver struct {
+	mu     sync.Mutex
+	calls  []receivedCall
+	status int
+}
+
+func (f *fakeReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	f.calls = append(f.calls, receivedCall{
+		Method:    r.Method,
+		Event:     r.Header.Get(eventHeader),
+		Signature: r.Header.Get(defaultSignatureHeader),
+		Token:     r.Header.Get("Authorization"),
+		Body:      string(body),
+	})
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		_, _ = w.Write([]byte("receiver down"))
+	}
+}
+
+func ipChangedEvent() notify.Event {
+	oldIP, newIP := "10.0.0.1", "10.0.0.2"
+
+	return notify.Event{
+		Type:     notify.IPChanged,
+		Severity: notify.Info,
+		Time:     time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC),
+		Summary:  "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+		OldIP:    publicip.IP{V4: &oldIP},
+		NewIP:    publicip.IP{V4: &newIP, Source: "ipify"},
+	}
+}
+
+func TestNotify(t *testing.T) {
+	testCases := []struct {
+		name          string
+		hook          webhookConfig
+		receiver      *fakeReceiver
+		event         notify.Event
+		expectedCalls []receivedCall
+		expectedError string
+	}{
+		{
+			name:     "default-body",
+			hook:     webhookConfig{Name: "ops"},
+			receiver: &fakeReceiver{},
+			event:    ipChangedEvent(),
+			expectedCalls: []receivedCall{
+				{
+					Method: http.MethodPost,
+					Event:  "ip-changed",
+					Body:   `{"event":"ip-changed","severity":"info","time":"2025-01-03T10:00:00Z","summary":"public ip changed: A 10.0.0.1 -> 10.0.0.2","old_ipv4":"10.0.0.1","new_ipv4":"10.0.0.2"}`,
+				},
+			},
+		},
+		{
+			name: "signed-template-with-headers",
+			hook: webhookConfig{
+				Name:     "ops",
+				Method:   http.MethodPut,
+				Headers:  map[string]string{"Authorization": "Bearer TOKEN"},
+				Secret:   "s3cr3t",
+				Template: `{"text": {{ json .Summary }}, "ip": {{ json .NewIPv4 }}}`,
+			},
+			receiver: &fakeReceiver{},
+			event:    ipChangedEvent(),
+			expectedCalls: []receivedCall{
+				{
+					Method:    http.MethodPut,
+					Event:     "ip-changed",
+					Signature: Sign("s3cr3t", []byte(`{"text": "public ip changed: A 10.0.0.1 -> 10.0.0.2", "ip": "10.0.0.2"}`)),
+					Token:     "Bearer TOKEN",
+					Body:      `{"text": "public ip changed: A 10.0.0.1 -> 10.0.0.2", "ip": "10.0.0.2"}`,
+				},
+			},
+		},
+		{
+			name:          "template-rendering-invalid-json",
+			hook:          webhookConfig{Name: "ops", Template: `{"text": {{ .Summary }}}`},
+			receiver:      &fakeReceiver{},
+			event:         ipChangedEvent(),
+			expectedCalls: []receivedCall{},
+			expectedError: "template error: rendered body isn't valid json",
+		},
+		{
+			name:     "filtered-event",
+			hook:     webhookConfig{Name: "ops", Filter: notifier.Filter{Events: []string{"update-failed"}}},
+			receiver: &fakeReceiver{},
+			event:    ipChangedEvent(),
+		},
+		{
+			name:     "receiver-error",
+			hook:     webhookConfig{Name: "ops"},
+			receiver: &fakeReceiver{status: http.StatusBadGateway},
+			event: notify.Event{
+				Type:     notify.UpdateFailed,
+				Severity: notify.Error,
+				Time:     time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC),
+				Summary:  "1 records failed to update on aws/main",
+				Provider: "aws/main",
+				Records:  []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+				Errors:   []string{"throttled"},
+			},
+			expectedCalls: []receivedCall{
+				{
+					Method: http.MethodPost,
+					Event:  "update-failed",
+					Body:   `{"event":"update-failed","severity":"error","time":"2025-01-03T10:00:00Z","summary":"1 records failed to update on aws/main","provider":"aws/main","records":[{"fqdn":"vpn.home.com.","type":"A","value":"10.0.0.2"}],"errors":["throttled"]}`,
+				},
+			},
+			expectedError: "http error: code 502: receiver down",
+		},
+	}
+
+	for _, tc := range testCases {
+		hook := tc.hook
+		receiver := tc.receiver
+		event := tc.event
+		expectedCalls := tc.expectedCalls
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(receiver)
+			defer srv.Close()
+
+			hook.URL = srv.URL
+			n, err := New(configMock{hooks: []webhookConfig{hook}}, loggerMock{}, hook.Name)
+			assert.NoError(t, err)
+
+			err = n.Notify(context.Background(), event)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, len(expectedCalls), len(receiver.calls))
+			for i, expected := range expectedCalls {
+				assert.Equal(t, expected, receiver.calls[i])
+				assert.True(t, json.Valid([]byte(receiver.calls[i].Body)))
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name          string
+		hooks         []webhookConfig
+		webhook       string
+		expectedName  string
+		expectedError string
+	}{
+		{
+			name:         "configured",
+			hooks:        []webhookConfig{{Name: "ops", URL: "https://hooks.example.com/ddns"}},
+			webhook:      "ops",
+			expectedName: "webhook/ops",
+		},
+		{
+			name:          "missing",
+			hooks:         []webhookConfig{{Name: "ops", URL: "https://hooks.example.com/ddns"}},
+			webhook:       "pager",
+			expectedError: "webhook pager doesn't exist",
+		},
+		{
+			name:          "invalid-url",
+			hooks:         []webhookConfig{{Name: "ops", URL: "hooks.example.com/ddns"}},
+			webhook:       "ops",
+			expectedError: `invalid url "hooks.example.com/ddns"`,
+		},
+		{
+			name:          "invalid-template",
+			hooks:         []webhookConfig{{Name: "ops", URL: "https://hooks.example.com/ddns", Template: "{{ .Summary"}},
+			webhook:       "ops",
+			expectedError: "invalid template, err:template: ops:1: unclosed action",
+		},
+		{
+			name: "unknown-event",
+			hooks: []webhookConfig{
+				{Name: "ops", URL: "https://hooks.example.com/ddns", Filter: notifier.Filter{Events: []string{"ip-lost"}}},
+			},
+			webhook:       "ops",
+			expectedError: "notifier: unknown event ip-lost for webhook/ops",
+		},
+	}
+
+	for _, tc := range testCases {
+		hooks := tc.hooks
+		name := tc.webhook
+		expectedName := tc.expectedName
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := New(configMock{hooks: hooks}, loggerMock{}, name)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedName, n.Name())
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "sha256=608b0c406f3dda19702d71a048483b8c331283106d80a208e3cf43dbde505286", Sign("s3cr3t", []byte(`{}`)))
+}
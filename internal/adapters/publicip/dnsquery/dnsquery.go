@@ -0,0 +1,317 @@
+package dnsquery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/source"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const (
+	sourceName         string = "dns"
+	configNode         string = "ddns.public-ip-api." + sourceName
+	defaultPort        uint16 = 53
+	defaultTimeoutSecs int    = 3
+
+	familyIPv4 string = "ipv4"
+	familyIPv6 string = "ipv6"
+
+	typeA    string = "A"
+	typeAAAA string = "AAAA"
+	typeTXT  string = "TXT"
+
+	// initialRTO is the first retransmission timeout of a query, doubled on
+	// every retransmission.
+	initialRTO time.Duration = time.Second
+)
+
+func init() {
+	source.Register(sourceName, func(cnf source.ConfigDecoder, logger source.MessageLogger) (publicip.Getter, error) {
+		return New(cnf, logger)
+	})
+}
+
+// defaultQueries ask OpenDNS first and Google's authoritative servers after,
+// the TXT record of o-o.myaddr.l.google.com holds the address the query came
+// from. Resolvers are the addresses of resolver1.opendns.com and
+// ns1.google.com, so finding them doesn't depend on the system resolver.
+var defaultQueries = []query{
+	{Name: "myip.opendns.com", Type: typeA, Resolver: "208.67.222.222"},
+	{Name: "myip.opendns.com", Type: typeAAAA, Resolver: "2620:119:35::35"},
+	{Name: "o-o.myaddr.l.google.com", Type: typeTXT, Resolver: "216.239.32.10"},
+	{Name: "o-o.myaddr.l.google.com", Type: typeTXT, Resolver: "2001:4860:4802:32::a"},
+}
+
+// recordTypes are the record types queries may ask for.
+var recordTypes = map[string]dnsmessage.Type{
+	typeA:    dnsmessage.TypeA,
+	typeAAAA: dnsmessage.TypeAAAA,
+	typeTXT:  dnsmessage.TypeTXT,
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Error(err error)
+	Debug(msg string)
+}
+
+type dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+type query struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Resolver string `yaml:"resolver"`
+}
+
+type dnsConfig struct {
+	Queries     []query  `yaml:"queries"`
+	Families    []string `yaml:"families"`
+	TimeoutSecs int      `yaml:"timeout-secs"`
+}
+
+type dnsGetter struct {
+	config  dnsConfig
+	timeout time.Duration
+	dialer  dialer
+	logger  messageLogger
+}
+
+// New builds a getter that sends the queries configured under
+// ddns.public-ip-api.dns, in order, straight to their own resolver, neither
+// the system resolver nor the hosts file are used. Resolvers are ip
+// addresses, those without a port are reached on 53. A queries are sent to
+// ipv4 resolvers and AAAA queries to ipv6 ones as they answer with the
+// address the query came from. Each query is given timeout-secs to answer.
+func New(cnf configDecoder, logger messageLogger) (publicip.Getter, error) {
+	config := dnsConfig{}
+	if err := cnf.Decode(configNode, &config); err != nil {
+		return nil, fmt.Errorf("dnsquery: unable to create new dns instance, err:%w", err)
+	}
+
+	if len(config.Queries) == 0 {
+		config.Queries = slices.Clone(defaultQueries)
+	}
+
+	for i, q := range config.Queries {
+		if q.Name == "" {
+			return nil, errors.New("dnsquery: missing query name")
+		}
+
+		q.Type = strings.ToUpper(q.Type)
+		if _, ok := recordTypes[q.Type]; !ok {
+			return nil, fmt.Errorf("dnsquery: unknown record type %s for %s", q.Type, q.Name)
+		}
+
+		if q.Resolver == "" {
+			return nil, fmt.Errorf("dnsquery: missing resolver for %s", q.Name)
+		}
+
+		resolver, err := parseResolver(q.Resolver)
+		if err != nil {
+			return nil, fmt.Errorf("dnsquery: invalid resolver %s, an ip address is expected, err:%w", q.Resolver, err)
+		}
+
+		q.Resolver = resolver.String()
+		if family := q.family(); (q.Type == typeA && family != familyIPv4) || (q.Type == typeAAAA && family != familyIPv6) {
+			return nil, fmt.Errorf("dnsquery: %s query for %s can't be sent to the %s resolver %s", q.Type, q.Name, family, q.Resolver)
+		}
+
+		config.Queries[i] = q
+	}
+
+	if len(config.Families) == 0 {
+		config.Families = []string{familyIPv4, familyIPv6}
+	}
+
+	for _, family := range config.Families {
+		if family != familyIPv4 && family != familyIPv6 {
+			return nil, fmt.Errorf("dnsquery: unknown address family %s", family)
+		}
+	}
+
+	if config.TimeoutSecs < 0 {
+		return nil, fmt.Errorf("dnsquery: invalid timeout %d", config.TimeoutSecs)
+	}
+
+	if config.TimeoutSecs == 0 {
+		config.TimeoutSecs = defaultTimeoutSecs
+	}
+
+	return &dnsGetter{
+		config:  config,
+		timeout: time.Duration(config.TimeoutSecs) * time.Second,
+		dialer:  &net.Dialer{},
+		logger:  logger,
+	}, nil
+}
+
+func (d *dnsGetter) GetIP(ctx context.Context) publicip.IP {
+	ip := publicip.IP{}
+	if slices.Contains(d.config.Families, familyIPv4) {
+		ip.V4 = d.address(ctx, familyIPv4)
+	}
+
+	if slices.Contains(d.config.Families, familyIPv6) {
+		ip.V6 = d.address(ctx, familyIPv6)
+	}
+
+	return ip
+}
+
+// address runs the queries sent to resolvers of family in order until one of
+// them returns an address of that family.
+func (d *dnsGetter) address(ctx context.Context, family string) *string {
+	for _, q := range d.config.Queries {
+		if q.family() != family {
+			continue
+		}
+
+		addr, err := d.lookup(ctx, q, family)
+		if err != nil {
+			d.logger.Error(fmt.Errorf("dnsquery: resolver=%s %s %s %s query error: %w", q.Resolver, family, q.Name, q.Type, err))
+			continue
+		}
+
+		value := addr.String()
+		d.logger.Debug(fmt.Sprintf("dnsquery: %s: %s %s answered %s", q.Resolver, q.Name, q.Type, value))
+		return &value
+	}
+
+	return nil
+}
+
+// lookup sends q to its resolver and returns the first address of family in
+// the answer.
+func (d *dnsGetter) lookup(ctx context.Context, q query, family string) (netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	// a fully qualified name keeps the search domains of the host out.
+	name, err := dnsmessage.NewName(strings.TrimSuffix(q.Name, ".") + ".")
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	id := [2]byte{}
+	if _, err := rand.Read(id[:]); err != nil {
+		return netip.Addr{}, err
+	}
+
+	question := dnsmessage.Question{Name: name, Type: recordTypes[q.Type], Class: dnsmessage.ClassINET}
+	request, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: binary.BigEndian.Uint16(id[:]), RecursionDesired: true},
+		Questions: []dnsmessage.Question{question},
+	}).Pack()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	network := "udp4"
+	if family == familyIPv6 {
+		network = "udp6"
+	}
+
+	conn, err := d.dialer.DialContext(ctx, network, q.Resolver)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer conn.Close()
+
+	var answers []netip.Addr
+	err = udp.Exchange(ctx, conn, request, initialRTO, func(msg []byte) error {
+		response := dnsmessage.Message{}
+		if err := response.Unpack(msg); err != nil || !response.Response || response.ID != binary.BigEndian.Uint16(id[:]) ||
+			len(response.Questions) != 1 || !sameQuestion(response.Questions[0], question) {
+			return udp.ErrUnrelated
+		}
+
+		if response.RCode == dnsmessage.RCodeNameError {
+			return &net.DNSError{Err: "no such host", Name: name.String(), Server: q.Resolver, IsNotFound: true}
+		}
+
+		if response.RCode != dnsmessage.RCodeSuccess {
+			return &net.DNSError{Err: "server answered " + response.RCode.String(), Name: name.String(), Server: q.Resolver}
+		}
+
+		answers = addresses(response.Answers)
+		return nil
+	})
+	if errors.Is(err, udp.ErrNoAnswer) {
+		return netip.Addr{}, &net.DNSError{Err: "i/o timeout", Name: name.String(), Server: q.Resolver, IsTimeout: true}
+	}
+
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	for _, addr := range answers {
+		addr = addr.Unmap()
+		if addr.Is4() == (family == familyIPv4) {
+			return addr, nil
+		}
+	}
+
+	return netip.Addr{}, fmt.Errorf("no %s address in answer", family)
+}
+
+// sameQuestion tells whether an answer is for question, names are compared
+// ignoring case as resolvers may echo them otherwise.
+func sameQuestion(a, question dnsmessage.Question) bool {
+	return a.Type == question.Type && a.Class == question.Class && strings.EqualFold(a.Name.String(), question.Name.String())
+}
+
+// addresses returns the addresses held by the A and AAAA records of
+// resources, and by the TXT records holding one.
+func addresses(resources []dnsmessage.Resource) []netip.Addr {
+	var addrs []netip.Addr
+	for _, resource := range resources {
+		switch body := resource.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, netip.AddrFrom4(body.A))
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, netip.AddrFrom16(body.AAAA))
+		case *dnsmessage.TXTResource:
+			if addr, err := netip.ParseAddr(strings.TrimSpace(strings.Join(body.TXT, ""))); err == nil {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	return addrs
+}
+
+// family is the address family the resolver of q is reached over.
+func (q query) family() string {
+	if netip.MustParseAddrPort(q.Resolver).Addr().Is4() {
+		return familyIPv4
+	}
+
+	return familyIPv6
+}
+
+// parseResolver reads an ip address with an optional port, the default port
+// is used when it's missing.
+func parseResolver(resolver string) (netip.AddrPort, error) {
+	if ip, err := netip.ParseAddr(strings.Trim(resolver, "[]")); err == nil {
+		return netip.AddrPortFrom(ip.Unmap(), defaultPort), nil
+	}
+
+	return netip.ParseAddrPort(resolver)
+}
@@ -0,0 +1,390 @@
+package dnsquery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type configMock struct {
+	config *dnsConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	if c.config == nil {
+		return errors.New("node ddns.public-ip-api.dns not found")
+	}
+
+	*item.(*dnsConfig) = *c.config
+	return nil
+}
+
+type messageLoggerMock struct {
+	mu            sync.Mutex
+	errorMessages []string
+}
+
+func (l *messageLoggerMock) Error(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errorMessages = append(l.errorMessages, err.Error())
+}
+
+func (l *messageLoggerMock) Debug(msg string) {}
+
+// records are the answers of the fake resolver by name and type, the address
+// the query came from stands in for TXT records set to "client".
+type records map[string]map[dnsmessage.Type][]string
+
+// resolver is an in-process dns server listening on address until the test
+// ends. Names without records are answered with NXDOMAIN, nothing is sent
+// back when silent is set.
+func resolver(t *testing.T, network, address string, records records, silent bool) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		t.Skipf("unable to listen on %s: %s", address, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			if silent {
+				continue
+			}
+
+			answer, err := answer(buf[:n], records, from.(*net.UDPAddr))
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(answer, from)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func answer(request []byte, records records, from *net.UDPAddr) ([]byte, error) {
+	parser := dnsmessage.Parser{}
+	header, err := parser.Start(request)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := records[question.Name.String()][question.Type]
+	header.Response = true
+	header.RecursionAvailable = true
+	if _, known := records[question.Name.String()]; !known {
+		header.RCode = dnsmessage.RCodeNameError
+	}
+
+	builder := dnsmessage.NewBuilder(nil, header)
+	builder.EnableCompression()
+	builder.StartQuestions()
+	builder.Question(question)
+	builder.StartAnswers()
+
+	resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 0}
+	for _, value := range values {
+		if !ok {
+			break
+		}
+
+		switch question.Type {
+		case dnsmessage.TypeA:
+			builder.AResource(resource, dnsmessage.AResource{A: netip.MustParseAddr(value).As4()})
+		case dnsmessage.TypeAAAA:
+			builder.AAAAResource(resource, dnsmessage.AAAAResource{AAAA: netip.MustParseAddr(value).As16()})
+		case dnsmessage.TypeTXT:
+			if value == "client" {
+				value = from.AddrPort().Addr().Unmap().String()
+			}
+			builder.TXTResource(resource, dnsmessage.TXTResource{TXT: []string{value}})
+		}
+	}
+
+	return builder.Finish()
+}
+
+func TestGetIP(t *testing.T) {
+	testCases := []struct {
+		name           string
+		families       []string
+		queries        []query
+		records        records
+		silent         bool
+		expectedIP     publicip.IP
+		expectedErrors []string
+	}{
+		{
+			name:     "address-records",
+			families: []string{"ipv4"},
+			queries:  []query{{Name: "myip.test", Type: "A"}},
+			records: records{"myip.test.": {
+				dnsmessage.TypeA:    {"203.0.113.7"},
+				dnsmessage.TypeAAAA: {"2001:db8:1::1"},
+			}},
+			expectedIP: publicip.IP{V4: stringPointer("203.0.113.7")},
+		},
+		{
+			name:     "txt-record",
+			families: []string{"ipv4"},
+			queries:  []query{{Name: "o-o.myaddr.test", Type: "TXT"}},
+			records: records{"o-o.myaddr.test.": {
+				dnsmessage.TypeTXT: {"edns0-client-subnet 192.0.2.0/24", "client"},
+			}},
+			expectedIP: publicip.IP{V4: stringPointer("127.0.0.1")},
+		},
+		{
+			name:     "txt-record-other-family",
+			families: []string{"ipv4", "ipv6"},
+			queries:  []query{{Name: "o-o.myaddr.test", Type: "TXT"}},
+			records: records{"o-o.myaddr.test.": {
+				dnsmessage.TypeTXT: {"client"},
+			}},
+			expectedIP: publicip.IP{V4: stringPointer("127.0.0.1")},
+		},
+		{
+			name:     "next-query",
+			families: []string{"ipv4"},
+			queries:  []query{{Name: "missing.test", Type: "A"}, {Name: "myip.test", Type: "A"}},
+			records: records{"myip.test.": {
+				dnsmessage.TypeA: {"203.0.113.7"},
+			}},
+			expectedIP: publicip.IP{V4: stringPointer("203.0.113.7")},
+			expectedErrors: []string{
+				"dnsquery: resolver=%s ipv4 missing.test A query error: lookup missing.test. on %s: no such host",
+			},
+		},
+		{
+			name:     "hosts-file-skipped",
+			families: []string{"ipv4"},
+			queries:  []query{{Name: "localhost", Type: "A"}},
+			records:  records{},
+			expectedErrors: []string{
+				"dnsquery: resolver=%s ipv4 localhost A query error: lookup localhost. on %s: no such host",
+			},
+		},
+		{
+			name:     "txt-without-address",
+			families: []string{"ipv4"},
+			queries:  []query{{Name: "o-o.myaddr.test", Type: "TXT"}},
+			records: records{"o-o.myaddr.test.": {
+				dnsmessage.TypeTXT: {"not an address"},
+			}},
+			expectedErrors: []string{"dnsquery: resolver=%s ipv4 o-o.myaddr.test TXT query error: no ipv4 address in answer"},
+		},
+		{
+			name:           "no-answer",
+			families:       []string{"ipv4"},
+			queries:        []query{{Name: "myip.test", Type: "A"}},
+			silent:         true,
+			expectedErrors: []string{"dnsquery: resolver=%s ipv4 myip.test A query error: lookup myip.test. on %s: i/o timeout"},
+		},
+	}
+
+	for _, tc := range testCases {
+		families := tc.families
+		queries := tc.queries
+		records := tc.records
+		silent := tc.silent
+		expectedIP := tc.expectedIP
+		expectedErrors := tc.expectedErrors
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := resolver(t, "udp4", "127.0.0.1:0", records, silent)
+			for i := range queries {
+				queries[i].Resolver = server
+			}
+
+			logger := &messageLoggerMock{}
+			getter, err := New(configMock{config: &dnsConfig{Queries: queries, Families: families}}, logger)
+			require.NoError(t, err)
+			getter.(*dnsGetter).timeout = time.Second
+
+			assert.Equal(t, expectedIP, getter.GetIP(context.Background()))
+			for i := range expectedErrors {
+				expectedErrors[i] = strings.ReplaceAll(expectedErrors[i], "%s", server)
+			}
+			assert.Equal(t, expectedErrors, logger.errorMessages)
+		})
+	}
+}
+
+func TestGetIPv6(t *testing.T) {
+	server := resolver(t, "udp6", "[::1]:0", records{
+		"myip.test.":       {dnsmessage.TypeAAAA: {"2001:db8:1::1"}},
+		"o-o.myaddr.test.": {dnsmessage.TypeTXT: {"client"}},
+	}, false)
+
+	testCases := []struct {
+		name       string
+		query      query
+		expectedV6 *string
+	}{
+		{
+			name:       "aaaa-record",
+			query:      query{Name: "myip.test", Type: "aaaa", Resolver: server},
+			expectedV6: stringPointer("2001:db8:1::1"),
+		},
+		{
+			name:       "txt-record",
+			query:      query{Name: "o-o.myaddr.test", Type: "TXT", Resolver: server},
+			expectedV6: stringPointer("::1"),
+		},
+	}
+
+	for _, tc := range testCases {
+		q := tc.query
+		expectedV6 := tc.expectedV6
+
+		t.Run(tc.name, func(t *testing.T) {
+			getter, err := New(configMock{config: &dnsConfig{Queries: []query{q}, Families: []string{"ipv6"}}}, &messageLoggerMock{})
+			require.NoError(t, err)
+			getter.(*dnsGetter).timeout = time.Second
+
+			assert.Equal(t, publicip.IP{V6: expectedV6}, getter.GetIP(context.Background()))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         *dnsConfig
+		expectedConfig dnsConfig
+		expectedError  string
+	}{
+		{
+			name:   "defaults",
+			config: &dnsConfig{},
+			expectedConfig: dnsConfig{
+				Queries: []query{
+					{Name: "myip.opendns.com", Type: "A", Resolver: "208.67.222.222:53"},
+					{Name: "myip.opendns.com", Type: "AAAA", Resolver: "[2620:119:35::35]:53"},
+					{Name: "o-o.myaddr.l.google.com", Type: "TXT", Resolver: "216.239.32.10:53"},
+					{Name: "o-o.myaddr.l.google.com", Type: "TXT", Resolver: "[2001:4860:4802:32::a]:53"},
+				},
+				Families:    []string{"ipv4", "ipv6"},
+				TimeoutSecs: 3,
+			},
+		},
+		{
+			name: "configured",
+			config: &dnsConfig{
+				Queries: []query{
+					{Name: "myip.home.com", Type: "a", Resolver: "192.0.2.53:5353"},
+					{Name: "myip.home.com", Type: "AAAA", Resolver: "2001:db8::53"},
+				},
+				Families:    []string{"ipv6"},
+				TimeoutSecs: 5,
+			},
+			expectedConfig: dnsConfig{
+				Queries: []query{
+					{Name: "myip.home.com", Type: "A", Resolver: "192.0.2.53:5353"},
+					{Name: "myip.home.com", Type: "AAAA", Resolver: "[2001:db8::53]:53"},
+				},
+				Families:    []string{"ipv6"},
+				TimeoutSecs: 5,
+			},
+		},
+		{
+			name:          "not-configured",
+			expectedError: "dnsquery: unable to create new dns instance, err:node ddns.public-ip-api.dns not found",
+		},
+		{
+			name:          "missing-name",
+			config:        &dnsConfig{Queries: []query{{Type: "A", Resolver: "192.0.2.53"}}},
+			expectedError: "dnsquery: missing query name",
+		},
+		{
+			name:          "unknown-type",
+			config:        &dnsConfig{Queries: []query{{Name: "myip.home.com", Type: "MX", Resolver: "192.0.2.53"}}},
+			expectedError: "dnsquery: unknown record type MX for myip.home.com",
+		},
+		{
+			name:          "missing-resolver",
+			config:        &dnsConfig{Queries: []query{{Name: "myip.home.com", Type: "A"}}},
+			expectedError: "dnsquery: missing resolver for myip.home.com",
+		},
+		{
+			name:          "invalid-resolver",
+			config:        &dnsConfig{Queries: []query{{Name: "myip.home.com", Type: "A", Resolver: "192.0.2.53:53:1"}}},
+			expectedError: `dnsquery: invalid resolver 192.0.2.53:53:1, an ip address is expected, err:ParseAddr("192.0.2.53:53"): unexpected character (at ":53")`,
+		},
+		{
+			name:          "host-name-resolver",
+			config:        &dnsConfig{Queries: []query{{Name: "myip.home.com", Type: "A", Resolver: "dns.home.com:53"}}},
+			expectedError: `dnsquery: invalid resolver dns.home.com:53, an ip address is expected, err:ParseAddr("dns.home.com"): unexpected character (at "dns.home.com")`,
+		},
+		{
+			name:          "a-query-to-ipv6-resolver",
+			config:        &dnsConfig{Queries: []query{{Name: "myip.home.com", Type: "A", Resolver: "2001:db8::53"}}},
+			expectedError: "dnsquery: A query for myip.home.com can't be sent to the ipv6 resolver [2001:db8::53]:53",
+		},
+		{
+			name:          "aaaa-query-to-ipv4-resolver",
+			config:        &dnsConfig{Queries: []query{{Name: "myip.home.com", Type: "AAAA", Resolver: "192.0.2.53"}}},
+			expectedError: "dnsquery: AAAA query for myip.home.com can't be sent to the ipv4 resolver 192.0.2.53:53",
+		},
+		{
+			name:          "unknown-family",
+			config:        &dnsConfig{Families: []string{"inet"}},
+			expectedError: "dnsquery: unknown address family inet",
+		},
+		{
+			name:          "invalid-timeout",
+			config:        &dnsConfig{TimeoutSecs: -1},
+			expectedError: "dnsquery: invalid timeout -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		config := tc.config
+		expectedConfig := tc.expectedConfig
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			getter, err := New(configMock{config: config}, &messageLoggerMock{})
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedConfig, getter.(*dnsGetter).config)
+		})
+	}
+
+	// the defaults are copied, not resolved in place.
+	assert.Equal(t, "208.67.222.222", defaultQueries[0].Resolver)
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
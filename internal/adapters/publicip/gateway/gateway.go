@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/source"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const (
+	sourceName         string = "gateway"
+	configNode         string = "ddns.public-ip-api." + sourceName
+	defaultPort        string = "5351"
+	defaultTimeoutSecs int    = 3
+
+	protocolUPnP   string = "upnp"
+	protocolNATPMP string = "natpmp"
+	protocolPCP    string = "pcp"
+
+	// initialRTO is the first retransmission timeout of RFC 6886 and RFC
+	// 6887, doubled on every retransmission.
+	initialRTO time.Duration = 250 * time.Millisecond
+)
+
+func init() {
+	source.Register(sourceName, func(cnf source.ConfigDecoder, logger source.MessageLogger) (publicip.Getter, error) {
+		return New(cnf, logger)
+	})
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, gateways
+// reporting an address in it sit behind another NAT.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Error(err error)
+	Debug(msg string)
+}
+
+type dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+type httpRequestor interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type gatewayConfig struct {
+	Protocols   []string `yaml:"protocols"`
+	Address     string   `yaml:"address"`
+	TimeoutSecs int      `yaml:"timeout-secs"`
+}
+
+type gatewayGetter struct {
+	config         gatewayConfig
+	timeout        time.Duration
+	ssdpAddr       string
+	defaultGateway func() (netip.Addr, error)
+	lan            func(ip netip.Addr) bool
+	dialer         dialer
+	client         httpRequestor
+	logger         messageLogger
+}
+
+// New builds a getter that asks the LAN gateway for its WAN address using the
+// protocols configured under ddns.public-ip-api.gateway, in order, until one
+// answers. NAT-PMP and PCP requests go to address, the default gateway when
+// it isn't set. Each protocol is given timeout-secs to answer.
+func New(cnf configDecoder, logger messageLogger) (publicip.Getter, error) {
+	config := gatewayConfig{}
+	if err := cnf.Decode(configNode, &config); err != nil {
+		return nil, fmt.Errorf("gateway: unable to create new gateway instance, err:%w", err)
+	}
+
+	if len(config.Protocols) == 0 {
+		config.Protocols = []string{protocolUPnP, protocolNATPMP, protocolPCP}
+	}
+
+	for _, protocol := range config.Protocols {
+		if protocol != protocolUPnP && protocol != protocolNATPMP && protocol != protocolPCP {
+			return nil, fmt.Errorf("gateway: unknown protocol %s", protocol)
+		}
+	}
+
+	if config.Address != "" {
+		address, err := udp.WithDefaultPort(config.Address, defaultPort)
+		if err != nil {
+			return nil, fmt.Errorf("gateway: invalid address %s, err:%w", config.Address, err)
+		}
+
+		config.Address = address
+	}
+
+	if config.TimeoutSecs < 0 {
+		return nil, fmt.Errorf("gateway: invalid timeout %d", config.TimeoutSecs)
+	}
+
+	if config.TimeoutSecs == 0 {
+		config.TimeoutSecs = defaultTimeoutSecs
+	}
+
+	return &gatewayGetter{
+		config:         config,
+		timeout:        time.Duration(config.TimeoutSecs) * time.Second,
+		ssdpAddr:       ssdpAddr,
+		defaultGateway: defaultGateway,
+		lan:            isLAN,
+		dialer:         &net.Dialer{},
+		client:         newHTTPClient(),
+		logger:         logger,
+	}, nil
+}
+
+func (g *gatewayGetter) GetIP(ctx context.Context) publicip.IP {
+	for _, protocol := range g.config.Protocols {
+		addr, err := g.externalAddress(ctx, protocol)
+		if err != nil {
+			g.logger.Error(fmt.Errorf("gateway: %s external address error: %w", protocol, err))
+			continue
+		}
+
+		if !isPublic(addr) {
+			g.logger.Error(fmt.Errorf("gateway: %s external address %s isn't public, the gateway is behind another nat", protocol, addr))
+			continue
+		}
+
+		value := addr.String()
+		g.logger.Debug(fmt.Sprintf("gateway: %s: external address %s", protocol, value))
+		if addr.Is4() {
+			return publicip.IP{V4: &value}
+		}
+
+		return publicip.IP{V6: &value}
+	}
+
+	return publicip.IP{}
+}
+
+func (g *gatewayGetter) externalAddress(ctx context.Context, protocol string) (netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	switch protocol {
+	case protocolNATPMP:
+		return g.natpmp(ctx)
+	case protocolPCP:
+		return g.pcp(ctx)
+	default:
+		return g.upnp(ctx)
+	}
+}
+
+// gatewayAddress is where NAT-PMP and PCP requests are sent to.
+func (g *gatewayGetter) gatewayAddress() (string, error) {
+	if g.config.Address != "" {
+		return g.config.Address, nil
+	}
+
+	ip, err := g.defaultGateway()
+	if err != nil {
+		return "", fmt.Errorf("unable to find the default gateway, err:%w", err)
+	}
+
+	return net.JoinHostPort(ip.String(), defaultPort), nil
+}
+
+// exchange sends request over conn until parse accepts an answer or ctx
+// expires.
+func exchange(ctx context.Context, conn net.Conn, request []byte, parse func(msg []byte) (netip.Addr, error)) (addr netip.Addr, err error) {
+	err = udp.Exchange(ctx, conn, request, initialRTO, func(msg []byte) error {
+		addr, err = parse(msg)
+		return err
+	})
+	if errors.Is(err, udp.ErrNoAnswer) {
+		return addr, fmt.Errorf("no answer from %s", conn.RemoteAddr())
+	}
+
+	return addr, err
+}
+
+// newHTTPClient builds the client talking to UPnP gateways, requests go
+// straight to the LAN and redirects elsewhere aren't followed.
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{Proxy: nil},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPublic tells whether ip can be reached from the internet, a gateway
+// answering with a private or carrier-grade NAT address isn't the last hop.
+func isPublic(ip netip.Addr) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
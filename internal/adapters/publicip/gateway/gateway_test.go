@@ -0,0 +1,281 @@
+package gateway
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type configMock struct {
+	config *gatewayConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	if c.config == nil {
+		return errors.New("node ddns.public-ip-api.gateway not found")
+	}
+
+	*item.(*gatewayConfig) = *c.config
+	return nil
+}
+
+type messageLoggerMock struct {
+	mu            sync.Mutex
+	errorMessages []string
+}
+
+func (l *messageLoggerMock) Error(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errorMessages = append(l.errorMessages, err.Error())
+}
+
+func (l *messageLoggerMock) Debug(msg string) {}
+
+// answer builds the datagrams sent back for the n-th request received by a
+// fake gateway, nothing is sent when it returns none.
+type answer func(n int, request []byte) [][]byte
+
+// udpServer is an in-process fake gateway listening on the loopback until
+// the test ends.
+func udpServer(t *testing.T, answer answer) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, udp.MaxPacketSize)
+		for n := 1; ; n++ {
+			size, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			for _, msg := range answer(n, append([]byte{}, buf[:size]...)) {
+				conn.WriteTo(msg, from)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func natpmpAnswer(ip string, code uint16) []byte {
+	msg := make([]byte, natpmpResponseSize)
+	msg[1] = natpmpResponseBit | natpmpOpExternalAddr
+	binary.BigEndian.PutUint16(msg[2:4], code)
+	binary.BigEndian.PutUint32(msg[4:8], 3600)
+	addr := netip.MustParseAddr(ip).As4()
+	copy(msg[8:12], addr[:])
+
+	return msg
+}
+
+// pcpAnswer answers a MAP request echoing its opcode data, as gateways do.
+func pcpAnswer(request []byte, ip string, code byte) []byte {
+	msg := append([]byte{}, request[:pcpMapSize]...)
+	msg[1] |= pcpResponseBit
+	msg[3] = code
+	binary.BigEndian.PutUint32(msg[8:12], 3600)
+	clear(msg[12:24])
+	binary.BigEndian.PutUint16(msg[42:44], 61000)
+	addr := netip.MustParseAddr(ip).As16()
+	copy(msg[44:60], addr[:])
+
+	return msg
+}
+
+// gatewayAnswer answers NAT-PMP and PCP requests with ip, or with a private
+// address for the protocols in private.
+func gatewayAnswer(ip string, private ...byte) answer {
+	return func(n int, request []byte) [][]byte {
+		answerIP := ip
+		for _, version := range private {
+			if request[0] == version {
+				answerIP = "10.0.0.2"
+			}
+		}
+
+		if request[0] == natpmpVersion {
+			return [][]byte{natpmpAnswer(answerIP, 0)}
+		}
+
+		return [][]byte{pcpAnswer(request, answerIP, 0)}
+	}
+}
+
+func silent(n int, request []byte) [][]byte {
+	return nil
+}
+
+func TestGetIP(t *testing.T) {
+	testCases := []struct {
+		name           string
+		protocols      []string
+		ssdp           answer
+		gateway        answer
+		expectedIP     publicip.IP
+		expectedErrors []string
+	}{
+		{
+			name:       "first-protocol",
+			protocols:  []string{"natpmp", "pcp"},
+			gateway:    gatewayAnswer("203.0.113.7"),
+			expectedIP: publicip.IP{V4: stringPointer("203.0.113.7")},
+		},
+		{
+			name:       "next-protocol",
+			protocols:  []string{"upnp", "natpmp"},
+			ssdp:       silent,
+			gateway:    gatewayAnswer("203.0.113.7"),
+			expectedIP: publicip.IP{V4: stringPointer("203.0.113.7")},
+			expectedErrors: []string{
+				"gateway: upnp external address error: no gateway answered the ssdp search",
+			},
+		},
+		{
+			name:       "behind-another-nat",
+			protocols:  []string{"natpmp", "pcp"},
+			gateway:    gatewayAnswer("203.0.113.7", natpmpVersion),
+			expectedIP: publicip.IP{V4: stringPointer("203.0.113.7")},
+			expectedErrors: []string{
+				"gateway: natpmp external address 10.0.0.2 isn't public, the gateway is behind another nat",
+			},
+		},
+		{
+			name:      "no-answer",
+			protocols: []string{"natpmp"},
+			gateway:   silent,
+			expectedErrors: []string{
+				"gateway: natpmp external address error: no answer from %s",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		protocols := tc.protocols
+		ssdp := tc.ssdp
+		gateway := tc.gateway
+		expectedIP := tc.expectedIP
+		expectedErrors := tc.expectedErrors
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			address := udpServer(t, gateway)
+			logger := &messageLoggerMock{}
+			getter, err := New(configMock{config: &gatewayConfig{Protocols: protocols, Address: address}}, logger)
+			require.NoError(t, err)
+			getter.(*gatewayGetter).timeout = 700 * time.Millisecond
+			if ssdp != nil {
+				getter.(*gatewayGetter).ssdpAddr = udpServer(t, ssdp)
+			}
+
+			assert.Equal(t, expectedIP, getter.GetIP(context.Background()))
+
+			for i := range expectedErrors {
+				expectedErrors[i] = strings.Replace(expectedErrors[i], "%s", address, 1)
+			}
+			assert.Equal(t, expectedErrors, logger.errorMessages)
+		})
+	}
+}
+
+func TestGatewayAddress(t *testing.T) {
+	getter, err := New(configMock{config: &gatewayConfig{}}, &messageLoggerMock{})
+	require.NoError(t, err)
+
+	getter.(*gatewayGetter).defaultGateway = func() (netip.Addr, error) {
+		return netip.MustParseAddr("192.168.1.1"), nil
+	}
+	address, err := getter.(*gatewayGetter).gatewayAddress()
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.1.1:5351", address)
+
+	getter.(*gatewayGetter).defaultGateway = func() (netip.Addr, error) {
+		return netip.Addr{}, errors.New("no default route")
+	}
+	_, err = getter.(*gatewayGetter).gatewayAddress()
+	assert.EqualError(t, err, "unable to find the default gateway, err:no default route")
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         *gatewayConfig
+		expectedConfig gatewayConfig
+		expectedError  string
+	}{
+		{
+			name:   "defaults",
+			config: &gatewayConfig{},
+			expectedConfig: gatewayConfig{
+				Protocols:   []string{"upnp", "natpmp", "pcp"},
+				TimeoutSecs: 3,
+			},
+		},
+		{
+			name:   "configured",
+			config: &gatewayConfig{Protocols: []string{"pcp"}, Address: "192.168.1.1", TimeoutSecs: 1},
+			expectedConfig: gatewayConfig{
+				Protocols:   []string{"pcp"},
+				Address:     "192.168.1.1:5351",
+				TimeoutSecs: 1,
+			},
+		},
+		{
+			name:          "not-configured",
+			expectedError: "gateway: unable to create new gateway instance, err:node ddns.public-ip-api.gateway not found",
+		},
+		{
+			name:          "unknown-protocol",
+			config:        &gatewayConfig{Protocols: []string{"igd"}},
+			expectedError: "gateway: unknown protocol igd",
+		},
+		{
+			name:          "invalid-address",
+			config:        &gatewayConfig{Address: "router.home:5351:1"},
+			expectedError: "gateway: invalid address router.home:5351:1, err:address router.home:5351:1: too many colons in address",
+		},
+		{
+			name:          "invalid-timeout",
+			config:        &gatewayConfig{TimeoutSecs: -1},
+			expectedError: "gateway: invalid timeout -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		config := tc.config
+		expectedConfig := tc.expectedConfig
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			getter, err := New(configMock{config: config}, &messageLoggerMock{})
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedConfig, getter.(*gatewayGetter).config)
+		})
+	}
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
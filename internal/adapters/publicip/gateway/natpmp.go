@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+)
+
+// NAT-PMP external address request and answer from RFC 6886.
+const (
+	natpmpVersion        byte = 0
+	natpmpOpExternalAddr byte = 0
+	natpmpResponseBit    byte = 0x80
+	natpmpResponseSize   int  = 12
+)
+
+// PCP MAP request and answer from RFC 6887, a short lived udp mapping of the
+// local port is asked for to learn the external address and deleted after.
+const (
+	pcpVersion     byte   = 2
+	pcpOpMap       byte   = 1
+	pcpResponseBit byte   = 0x80
+	pcpMapSize     int    = 60
+	pcpProtocolUDP byte   = 17
+	pcpMapLifetime uint32 = 60
+)
+
+var natpmpResults = map[uint16]string{
+	1: "unsupported version",
+	2: "not authorized",
+	3: "network failure",
+	4: "out of resources",
+	5: "unsupported opcode",
+}
+
+var pcpResults = map[byte]string{
+	1:  "unsupported version",
+	2:  "not authorized",
+	3:  "malformed request",
+	4:  "unsupported opcode",
+	5:  "unsupported option",
+	6:  "malformed option",
+	7:  "network failure",
+	8:  "no resources",
+	9:  "unsupported protocol",
+	10: "user exceeded quota",
+	11: "cannot provide external address",
+	12: "address mismatch",
+	13: "excessive remote peers",
+}
+
+func (g *gatewayGetter) natpmp(ctx context.Context) (netip.Addr, error) {
+	address, err := g.gatewayAddress()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	conn, err := g.dialer.DialContext(ctx, "udp4", address)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer conn.Close()
+
+	return exchange(ctx, conn, []byte{natpmpVersion, natpmpOpExternalAddr}, parseNATPMP)
+}
+
+func parseNATPMP(msg []byte) (netip.Addr, error) {
+	if len(msg) < 4 || msg[0] != natpmpVersion || msg[1] != natpmpResponseBit|natpmpOpExternalAddr {
+		return netip.Addr{}, udp.ErrUnrelated
+	}
+
+	if code := binary.BigEndian.Uint16(msg[2:4]); code != 0 {
+		return netip.Addr{}, fmt.Errorf("result code %d %s", code, natpmpResults[code])
+	}
+
+	if len(msg) < natpmpResponseSize {
+		return netip.Addr{}, fmt.Errorf("truncated answer, %d bytes", len(msg))
+	}
+
+	return netip.AddrFrom4([4]byte(msg[8:12])), nil
+}
+
+func (g *gatewayGetter) pcp(ctx context.Context) (netip.Addr, error) {
+	address, err := g.gatewayAddress()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	conn, err := g.dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer conn.Close()
+
+	nonce := [12]byte{}
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return netip.Addr{}, err
+	}
+
+	local := conn.LocalAddr().(*net.UDPAddr).AddrPort()
+	addr, err := exchange(ctx, conn, newPCPMapRequest(local, nonce, pcpMapLifetime), func(msg []byte) (netip.Addr, error) {
+		return parsePCPMap(msg, nonce)
+	})
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	// a zero lifetime deletes the mapping, the answer isn't waited for.
+	conn.Write(newPCPMapRequest(local, nonce, 0))
+
+	return addr, nil
+}
+
+// newPCPMapRequest encodes a MAP request of the udp port of client without
+// suggesting any external address or port.
+func newPCPMapRequest(client netip.AddrPort, nonce [12]byte, lifetime uint32) []byte {
+	msg := make([]byte, pcpMapSize)
+	msg[0] = pcpVersion
+	msg[1] = pcpOpMap
+	binary.BigEndian.PutUint32(msg[4:8], lifetime)
+	ip := client.Addr().As16()
+	copy(msg[8:24], ip[:])
+
+	copy(msg[24:36], nonce[:])
+	msg[36] = pcpProtocolUDP
+	binary.BigEndian.PutUint16(msg[40:42], client.Port())
+	// ::ffff:0.0.0.0 asks for any ipv4 address.
+	msg[54], msg[55] = 0xff, 0xff
+
+	return msg
+}
+
+func parsePCPMap(msg []byte, nonce [12]byte) (netip.Addr, error) {
+	if len(msg) < 4 {
+		return netip.Addr{}, udp.ErrUnrelated
+	}
+
+	// NAT-PMP only gateways answer with their own version.
+	if msg[0] != pcpVersion {
+		return netip.Addr{}, fmt.Errorf("unsupported version %d", msg[0])
+	}
+
+	if msg[1] != pcpResponseBit|pcpOpMap {
+		return netip.Addr{}, udp.ErrUnrelated
+	}
+
+	if len(msg) >= pcpMapSize && [12]byte(msg[24:36]) != nonce {
+		return netip.Addr{}, udp.ErrUnrelated
+	}
+
+	if code := msg[3]; code != 0 {
+		return netip.Addr{}, fmt.Errorf("result code %d %s", code, pcpResults[code])
+	}
+
+	if len(msg) < pcpMapSize {
+		return netip.Addr{}, fmt.Errorf("truncated answer, %d bytes", len(msg))
+	}
+
+	return netip.AddrFrom16([16]byte(msg[44:60])).Unmap(), nil
+}
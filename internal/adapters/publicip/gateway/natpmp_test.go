@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATPMP(t *testing.T) {
+	testCases := []struct {
+		name          string
+		answer        answer
+		expectedAddr  netip.Addr
+		expectedError string
+	}{
+		{
+			name: "external-address",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{natpmpAnswer("203.0.113.7", 0)}
+			},
+			expectedAddr: netip.MustParseAddr("203.0.113.7"),
+		},
+		{
+			name: "retransmission",
+			answer: func(n int, request []byte) [][]byte {
+				if n == 1 {
+					return nil
+				}
+
+				return [][]byte{natpmpAnswer("203.0.113.7", 0)}
+			},
+			expectedAddr: netip.MustParseAddr("203.0.113.7"),
+		},
+		{
+			name: "unrelated-datagrams-skipped",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{{0, 129, 0, 0}, []byte("garbage"), natpmpAnswer("203.0.113.7", 0)}
+			},
+			expectedAddr: netip.MustParseAddr("203.0.113.7"),
+		},
+		{
+			name: "result-code",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{natpmpAnswer("0.0.0.0", 3)}
+			},
+			expectedError: "result code 3 network failure",
+		},
+		{
+			name: "truncated",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{natpmpAnswer("203.0.113.7", 0)[:8]}
+			},
+			expectedError: "truncated answer, 8 bytes",
+		},
+	}
+
+	for _, tc := range testCases {
+		answer := tc.answer
+		expectedAddr := tc.expectedAddr
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			requests := make(chan []byte, 10)
+			address := udpServer(t, func(n int, request []byte) [][]byte {
+				requests <- request
+				return answer(n, request)
+			})
+
+			getter, err := New(configMock{config: &gatewayConfig{Address: address}}, &messageLoggerMock{})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			addr, err := getter.(*gatewayGetter).natpmp(ctx)
+			assert.Equal(t, []byte{0, 0}, <-requests)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedAddr, addr)
+		})
+	}
+}
+
+func TestPCP(t *testing.T) {
+	testCases := []struct {
+		name          string
+		answer        answer
+		expectedAddr  netip.Addr
+		expectedError string
+	}{
+		{
+			name: "external-address",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{pcpAnswer(request, "203.0.113.7", 0)}
+			},
+			expectedAddr: netip.MustParseAddr("203.0.113.7"),
+		},
+		{
+			name: "other-nonce-skipped",
+			answer: func(n int, request []byte) [][]byte {
+				other := pcpAnswer(request, "198.51.100.1", 0)
+				other[24] ^= 0xff
+				return [][]byte{other, pcpAnswer(request, "203.0.113.7", 0)}
+			},
+			expectedAddr: netip.MustParseAddr("203.0.113.7"),
+		},
+		{
+			name: "result-code",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{pcpAnswer(request, "::", 11)}
+			},
+			expectedError: "result code 11 cannot provide external address",
+		},
+		{
+			name: "natpmp-only-gateway",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{{0, 129, 0, 1, 0, 0, 0, 0}}
+			},
+			expectedError: "unsupported version 0",
+		},
+	}
+
+	for _, tc := range testCases {
+		answer := tc.answer
+		expectedAddr := tc.expectedAddr
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			requests := make(chan []byte, 10)
+			address := udpServer(t, func(n int, request []byte) [][]byte {
+				requests <- request
+				return answer(n, request)
+			})
+
+			getter, err := New(configMock{config: &gatewayConfig{Address: address}}, &messageLoggerMock{})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			addr, err := getter.(*gatewayGetter).pcp(ctx)
+
+			request := <-requests
+			require.Len(t, request, pcpMapSize)
+			assert.Equal(t, []byte{pcpVersion, pcpOpMap}, request[0:2])
+			assert.Equal(t, pcpMapLifetime, binary.BigEndian.Uint32(request[4:8]))
+			assert.Equal(t, netip.MustParseAddr("127.0.0.1"), netip.AddrFrom16([16]byte(request[8:24])).Unmap())
+			assert.Equal(t, pcpProtocolUDP, request[36])
+			assert.NotZero(t, binary.BigEndian.Uint16(request[40:42]))
+			assert.Equal(t, netip.MustParseAddr("::ffff:0.0.0.0"), netip.AddrFrom16([16]byte(request[44:60])))
+
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedAddr, addr)
+
+			// the mapping is deleted once the address is known.
+			deletion := <-requests
+			assert.Zero(t, binary.BigEndian.Uint32(deletion[4:8]))
+			assert.Equal(t, request[24:44], deletion[24:44])
+		})
+	}
+}
@@ -0,0 +1,77 @@
+//go:build linux
+
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	routesPath string = "/proc/net/route"
+
+	rtfUp      uint64 = 0x1
+	rtfGateway uint64 = 0x2
+)
+
+// defaultGateway returns the ipv4 gateway of the default route with the
+// lowest metric.
+func defaultGateway() (netip.Addr, error) {
+	f, err := os.Open(routesPath)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer f.Close()
+
+	return parseRoutes(f)
+}
+
+// parseRoutes reads a /proc/net/route table, addresses are written there as
+// little endian hex numbers.
+func parseRoutes(r io.Reader) (netip.Addr, error) {
+	gateway, metric := netip.Addr{}, uint64(0)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[3], 16, 16)
+		if err != nil || flags&(rtfUp|rtfGateway) != rtfUp|rtfGateway {
+			continue
+		}
+
+		ip, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil {
+			continue
+		}
+
+		routeMetric, err := strconv.ParseUint(fields[6], 10, 32)
+		if err != nil || gateway.IsValid() && routeMetric >= metric {
+			continue
+		}
+
+		addr := [4]byte{}
+		binary.LittleEndian.PutUint32(addr[:], uint32(ip))
+		gateway, metric = netip.AddrFrom4(addr), routeMetric
+	}
+
+	if err := scanner.Err(); err != nil {
+		return netip.Addr{}, err
+	}
+
+	if !gateway.IsValid() {
+		return netip.Addr{}, errors.New("no default route through a gateway")
+	}
+
+	return gateway, nil
+}
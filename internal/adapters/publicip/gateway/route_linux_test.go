@@ -0,0 +1,68 @@
+//go:build linux
+
+package gateway
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const routesHeader = "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n"
+
+func TestParseRoutes(t *testing.T) {
+	testCases := []struct {
+		name            string
+		routes          string
+		expectedGateway netip.Addr
+		expectedError   string
+	}{
+		{
+			name: "default-route",
+			routes: "eth0\t0001A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n" +
+				"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n",
+			expectedGateway: netip.MustParseAddr("192.168.1.1"),
+		},
+		{
+			name: "lowest-metric",
+			routes: "wlan0\t00000000\t0100000A\t0003\t0\t0\t600\t00000000\t0\t0\t0\n" +
+				"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+				"ppp0\t00000000\t0102A8C0\t0003\t0\t0\t300\t00000000\t0\t0\t0\n",
+			expectedGateway: netip.MustParseAddr("192.168.1.1"),
+		},
+		{
+			name: "down-route-skipped",
+			routes: "eth0\t00000000\t0101A8C0\t0002\t0\t0\t0\t00000000\t0\t0\t0\n" +
+				"eth1\t00000000\t0100000A\t0003\t0\t0\t100\t00000000\t0\t0\t0\n",
+			expectedGateway: netip.MustParseAddr("10.0.0.1"),
+		},
+		{
+			name:          "point-to-point-only",
+			routes:        "ppp0\t00000000\t00000000\t0001\t0\t0\t0\t00000000\t0\t0\t0\n",
+			expectedError: "no default route through a gateway",
+		},
+		{
+			name:          "empty",
+			expectedError: "no default route through a gateway",
+		},
+	}
+
+	for _, tc := range testCases {
+		routes := tc.routes
+		expectedGateway := tc.expectedGateway
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			gateway, err := parseRoutes(strings.NewReader(routesHeader + routes))
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedGateway, gateway)
+		})
+	}
+}
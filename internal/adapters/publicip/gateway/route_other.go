@@ -0,0 +1,14 @@
+//go:build !linux
+
+package gateway
+
+import (
+	"errors"
+	"net/netip"
+)
+
+// defaultGateway isn't looked up outside linux, address must be configured
+// for NAT-PMP and PCP there.
+func defaultGateway() (netip.Addr, error) {
+	return netip.Addr{}, errors.New("default gateway lookup unsupported, set address")
+}
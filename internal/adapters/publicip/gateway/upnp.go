@@ -0,0 +1,262 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+)
+
+// SSDP discovery of an Internet Gateway Device and the WAN connection
+// services able to answer GetExternalIPAddress, from the UPnP IGD spec.
+const (
+	ssdpAddr          string = "239.255.255.250:1900"
+	igdSearchTarget   string = "urn:schemas-upnp-org:device:InternetGatewayDevice:1"
+	getExternalAction string = "GetExternalIPAddress"
+	maxDescriptionLen int64  = 1 << 20
+)
+
+var errNoGateway = errors.New("no gateway answered the ssdp search")
+
+var wanServices = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+type description struct {
+	URLBase string `xml:"URLBase"`
+	Device  device `xml:"device"`
+}
+
+type device struct {
+	Services []service `xml:"serviceList>service"`
+	Devices  []device  `xml:"deviceList>device"`
+}
+
+type service struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+func (g *gatewayGetter) upnp(ctx context.Context) (netip.Addr, error) {
+	location, gateway, err := g.discover(ctx)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	if !g.lan(gateway) {
+		return netip.Addr{}, fmt.Errorf("gateway %s isn't on the lan", gateway)
+	}
+
+	serviceType, controlURL, err := g.wanService(ctx, location, gateway)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	return g.getExternalIPAddress(ctx, serviceType, controlURL)
+}
+
+// discover multicasts an M-SEARCH for gateways, retransmitted with a
+// doubling timeout, and returns the description location of the first one
+// answering along the address it answered from.
+func (g *gatewayGetter) discover(ctx context.Context) (string, netip.Addr, error) {
+	to, err := net.ResolveUDPAddr("udp4", g.ssdpAddr)
+	if err != nil {
+		return "", netip.Addr{}, err
+	}
+
+	conn, err := (&net.ListenConfig{}).ListenPacket(ctx, "udp4", ":0")
+	if err != nil {
+		return "", netip.Addr{}, err
+	}
+	defer conn.Close()
+
+	request := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\nHOST: %s\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: %s\r\n\r\n", ssdpAddr, igdSearchTarget)
+
+	location, gateway := "", netip.Addr{}
+	err = udp.ExchangeTo(ctx, conn, to, []byte(request), initialRTO, func(msg []byte, from net.Addr) error {
+		response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(msg)), nil)
+		if err != nil || response.StatusCode != http.StatusOK {
+			return udp.ErrUnrelated
+		}
+
+		location = response.Header.Get("Location")
+		if location == "" || response.Header.Get("ST") != igdSearchTarget {
+			return udp.ErrUnrelated
+		}
+
+		gateway = from.(*net.UDPAddr).AddrPort().Addr().Unmap()
+		return nil
+	})
+	if errors.Is(err, udp.ErrNoAnswer) {
+		return "", netip.Addr{}, errNoGateway
+	}
+
+	return location, gateway, err
+}
+
+// wanService reads the gateway description at location and returns the first
+// WAN connection service found with the url its actions are posted to, both
+// must be served by gateway.
+func (g *gatewayGetter) wanService(ctx context.Context, location string, gateway netip.Addr) (string, string, error) {
+	if err := onGateway(location, gateway); err != nil {
+		return "", "", fmt.Errorf("description %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		return "", "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("description %s status %s", location, response.Status)
+	}
+
+	desc := description{}
+	if err := xml.NewDecoder(io.LimitReader(response.Body, maxDescriptionLen)).Decode(&desc); err != nil {
+		return "", "", fmt.Errorf("invalid description %s, err:%w", location, err)
+	}
+
+	for _, serviceType := range wanServices {
+		svc, ok := findService(desc.Device, serviceType)
+		if !ok {
+			continue
+		}
+
+		base := location
+		if desc.URLBase != "" {
+			base = desc.URLBase
+		}
+
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid description base url %s, err:%w", base, err)
+		}
+
+		controlURL, err := baseURL.Parse(svc.ControlURL)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid control url %s, err:%w", svc.ControlURL, err)
+		}
+
+		if err := onGateway(controlURL.String(), gateway); err != nil {
+			return "", "", fmt.Errorf("control url %w", err)
+		}
+
+		return serviceType, controlURL.String(), nil
+	}
+
+	return "", "", fmt.Errorf("no wan connection service in %s", location)
+}
+
+// onGateway makes sure rawURL points at gateway, forged ssdp answers and
+// descriptions can't send requests anywhere else.
+func onGateway(rawURL string, gateway netip.Addr) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%s is invalid, err:%w", rawURL, err)
+	}
+
+	host, err := netip.ParseAddr(u.Hostname())
+	if err != nil || host.Unmap() != gateway {
+		return fmt.Errorf("%s isn't on the gateway %s", rawURL, gateway)
+	}
+
+	return nil
+}
+
+// isLAN tells whether the gateway answering the ssdp search is on the local
+// network.
+func isLAN(ip netip.Addr) bool {
+	return ip.IsPrivate() || ip.IsLinkLocalUnicast()
+}
+
+func findService(dev device, serviceType string) (service, bool) {
+	for _, svc := range dev.Services {
+		if strings.TrimSpace(svc.ServiceType) == serviceType {
+			return svc, true
+		}
+	}
+
+	for _, child := range dev.Devices {
+		if svc, ok := findService(child, serviceType); ok {
+			return svc, true
+		}
+	}
+
+	return service{}, false
+}
+
+// getExternalIPAddress calls the GetExternalIPAddress action of serviceType
+// at controlURL.
+func (g *gatewayGetter) getExternalIPAddress(ctx context.Context, serviceType, controlURL string) (netip.Addr, error) {
+	body := fmt.Sprintf(`<?xml version="1.0"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`+
+		`<s:Body><u:%s xmlns:u="%s"></u:%s></s:Body></s:Envelope>`, getExternalAction, serviceType, getExternalAction)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL, strings.NewReader(body))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	request.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	request.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, serviceType, getExternalAction))
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return netip.Addr{}, fmt.Errorf("%s status %s", getExternalAction, response.Status)
+	}
+
+	value, err := elementText(io.LimitReader(response.Body, maxDescriptionLen), "NewExternalIPAddress")
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid %s answer, err:%w", getExternalAction, err)
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("gateway has no external address, err:%w", err)
+	}
+
+	return addr.Unmap(), nil
+}
+
+// elementText returns the text of the first element called name, whatever
+// its namespace.
+func elementText(r io.Reader, name string) (string, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("%s not found, err:%w", name, err)
+		}
+
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == name {
+			value := ""
+			if err := decoder.DecodeElement(&value, &start); err != nil {
+				return "", err
+			}
+
+			return value, nil
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rootDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  %s
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <serviceList>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:Layer3Forwarding:1</serviceType>
+        <controlURL>/ctl/L3F</controlURL>
+      </service>
+    </serviceList>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>%s</serviceType>
+                <controlURL>%s</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+const externalAddressAnswer = `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+  <s:Body>
+    <u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">
+      <NewExternalIPAddress>%s</NewExternalIPAddress>
+    </u:GetExternalIPAddressResponse>
+  </s:Body>
+</s:Envelope>`
+
+// igd is an in-process gateway answering the ssdp search with the location
+// of its description, served along the WAN connection service over http.
+// Descriptions carry a URLBase when urlBase is set, the ssdp answer points at
+// location instead when it's set.
+type igd struct {
+	location    string
+	urlBase     string
+	serviceType string
+	controlURL  string
+	external    string
+	soapStatus  int
+}
+
+func (d igd) start(t *testing.T) (string, string, <-chan *http.Request) {
+	t.Helper()
+
+	actions := make(chan *http.Request, 1)
+	mux := http.NewServeMux()
+	server := httptest.NewUnstartedServer(mux)
+	mux.HandleFunc("GET /rootDesc.xml", func(w http.ResponseWriter, r *http.Request) {
+		urlBase := ""
+		if d.urlBase != "" {
+			urlBase = "<URLBase>" + server.URL + d.urlBase + "</URLBase>"
+		}
+		fmt.Fprintf(w, rootDescription, urlBase, d.serviceType, d.controlURL)
+	})
+	mux.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		actions <- r
+
+		if d.soapStatus != 0 {
+			w.WriteHeader(d.soapStatus)
+			return
+		}
+		fmt.Fprintf(w, externalAddressAnswer, d.external)
+	})
+	server.Start()
+	t.Cleanup(server.Close)
+
+	location := server.URL + "/rootDesc.xml"
+	if d.location != "" {
+		location = d.location
+	}
+
+	ssdp := udpServer(t, func(n int, request []byte) [][]byte {
+		if !strings.HasPrefix(string(request), "M-SEARCH * HTTP/1.1\r\n") {
+			return nil
+		}
+
+		return [][]byte{
+			[]byte("HTTP/1.1 200 OK\r\nST: upnp:rootdevice\r\nLOCATION: http://192.0.2.1/other.xml\r\n\r\n"),
+			[]byte("NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\n\r\n"),
+			[]byte(fmt.Sprintf("HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=120\r\nST: %s\r\nUSN: uuid:igd::%s\r\nLOCATION: %s\r\n\r\n",
+				igdSearchTarget, igdSearchTarget, location)),
+		}
+	})
+
+	return ssdp, server.URL, actions
+}
+
+func TestIsLAN(t *testing.T) {
+	assert.True(t, isLAN(netip.MustParseAddr("192.168.1.1")))
+	assert.True(t, isLAN(netip.MustParseAddr("10.0.0.1")))
+	assert.True(t, isLAN(netip.MustParseAddr("169.254.1.1")))
+	assert.True(t, isLAN(netip.MustParseAddr("fe80::1")))
+	assert.False(t, isLAN(netip.MustParseAddr("127.0.0.1")))
+	assert.False(t, isLAN(netip.MustParseAddr("203.0.113.7")))
+}
+
+func TestUPnP(t *testing.T) {
+	testCases := []struct {
+		name           string
+		igd            igd
+		lan            func(ip netip.Addr) bool
+		expectedAddr   netip.Addr
+		expectedAction string
+		expectedPath   string
+		expectedError  string
+	}{
+		{
+			name: "wan-ip-connection",
+			igd: igd{
+				serviceType: "urn:schemas-upnp-org:service:WANIPConnection:1",
+				controlURL:  "/ctl/IPConn",
+				external:    "203.0.113.7",
+			},
+			expectedAddr:   netip.MustParseAddr("203.0.113.7"),
+			expectedAction: `"urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress"`,
+			expectedPath:   "/ctl/IPConn",
+		},
+		{
+			name: "wan-ppp-connection",
+			igd: igd{
+				serviceType: "urn:schemas-upnp-org:service:WANPPPConnection:1",
+				controlURL:  "/ctl/PPPConn",
+				external:    "203.0.113.7",
+			},
+			expectedAddr:   netip.MustParseAddr("203.0.113.7"),
+			expectedAction: `"urn:schemas-upnp-org:service:WANPPPConnection:1#GetExternalIPAddress"`,
+			expectedPath:   "/ctl/PPPConn",
+		},
+		{
+			name: "relative-control-url",
+			igd: igd{
+				serviceType: "urn:schemas-upnp-org:service:WANIPConnection:2",
+				controlURL:  "ctl/IPConn",
+				external:    "203.0.113.7",
+			},
+			expectedAddr:   netip.MustParseAddr("203.0.113.7"),
+			expectedAction: `"urn:schemas-upnp-org:service:WANIPConnection:2#GetExternalIPAddress"`,
+			expectedPath:   "/ctl/IPConn",
+		},
+		{
+			name: "url-base",
+			igd: igd{
+				urlBase:     "/upnp/",
+				serviceType: "urn:schemas-upnp-org:service:WANIPConnection:1",
+				controlURL:  "ctl/IPConn",
+				external:    "203.0.113.7",
+			},
+			expectedAddr:   netip.MustParseAddr("203.0.113.7"),
+			expectedAction: `"urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress"`,
+			expectedPath:   "/upnp/ctl/IPConn",
+		},
+		{
+			name: "no-wan-service",
+			igd: igd{
+				serviceType: "urn:schemas-upnp-org:service:WANCommonInterfaceConfig:1",
+				controlURL:  "/ctl/CmnIfCfg",
+			},
+			expectedError: "no wan connection service in %s/rootDesc.xml",
+		},
+		{
+			name: "soap-fault",
+			igd: igd{
+				serviceType: "urn:schemas-upnp-org:service:WANIPConnection:1",
+				controlURL:  "/ctl/IPConn",
+				soapStatus:  http.StatusInternalServerError,
+			},
+			expectedAction: `"urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress"`,
+			expectedPath:   "/ctl/IPConn",
+			expectedError:  "GetExternalIPAddress status 500 Internal Server Error",
+		},
+		{
+			name: "wan-down",
+			igd: igd{
+				serviceType: "urn:schemas-upnp-org:service:WANIPConnection:1",
+				controlURL:  "/ctl/IPConn",
+			},
+			expectedAction: `"urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress"`,
+			expectedPath:   "/ctl/IPConn",
+			expectedError:  `gateway has no external address, err:ParseAddr(""): unable to parse IP`,
+		},
+		{
+			name: "location-on-another-host",
+			igd: igd{
+				location:    "http://192.168.1.50/rootDesc.xml",
+				serviceType: "urn:schemas-upnp-org:service:WANIPConnection:1",
+				controlURL:  "/ctl/IPConn",
+			},
+			expectedError: "description http://192.168.1.50/rootDesc.xml isn't on the gateway 127.0.0.1",
+		},
+		{
+			name: "control-url-on-another-host",
+			igd: igd{
+				serviceType: "urn:schemas-upnp-org:service:WANIPConnection:1",
+				controlURL:  "http://192.168.1.50/ctl/IPConn",
+			},
+			expectedError: "control url http://192.168.1.50/ctl/IPConn isn't on the gateway 127.0.0.1",
+		},
+		{
+			name: "gateway-not-on-lan",
+			igd: igd{
+				serviceType: "urn:schemas-upnp-org:service:WANIPConnection:1",
+				controlURL:  "/ctl/IPConn",
+			},
+			lan:           isLAN,
+			expectedError: "gateway 127.0.0.1 isn't on the lan",
+		},
+	}
+
+	for _, tc := range testCases {
+		igd := tc.igd
+		lan := tc.lan
+		expectedAddr := tc.expectedAddr
+		expectedAction := tc.expectedAction
+		expectedPath := tc.expectedPath
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ssdp, location, actions := igd.start(t)
+			getter, err := New(configMock{config: &gatewayConfig{}}, &messageLoggerMock{})
+			require.NoError(t, err)
+			getter.(*gatewayGetter).ssdpAddr = ssdp
+			// the fake gateway listens on the loopback.
+			getter.(*gatewayGetter).lan = netip.Addr.IsLoopback
+			if lan != nil {
+				getter.(*gatewayGetter).lan = lan
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			addr, err := getter.(*gatewayGetter).upnp(ctx)
+
+			if expectedAction != "" {
+				action := <-actions
+				assert.Equal(t, expectedPath, action.URL.Path)
+				assert.Equal(t, expectedAction, action.Header.Get("SOAPAction"))
+				body, _ := io.ReadAll(action.Body)
+				assert.Contains(t, string(body), "<u:GetExternalIPAddress xmlns:u=")
+			}
+
+			if expectedError != "" {
+				assert.EqualError(t, err, strings.Replace(expectedError, "%s", location, 1))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedAddr, addr)
+		})
+	}
+}
@@ -9,15 +9,23 @@ import (
 
 	"github.com/go-playground/validator/v10"
 
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/source"
 	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
 )
 
 const (
-	configNode string = "ddns.storage.public-ip-api.ipify"
+	sourceName string = "ipify"
+	configNode string = "ddns.public-ip-api." + sourceName
 	ipifyIPV4  int    = iota
 	ipifyIPV6
 )
 
+func init() {
+	source.Register(sourceName, func(cnf source.ConfigDecoder, logger source.MessageLogger) (publicip.Getter, error) {
+		return New(cnf, logger)
+	})
+}
+
 var ErrInvalidIpType = errors.New("invalid ip type argument")
 
 type endpoint struct {
@@ -27,7 +35,7 @@ type endpoint struct {
 type ipifyConfig struct {
 	CheckPeriodInMins int      `yaml:"check-period-mins"`
 	IPV4              endpoint `yaml:"ipv4"`
-	IPV6              endpoint `yaml:"ipv4"`
+	IPV6              endpoint `yaml:"ipv6"`
 }
 
 type configDecoder interface {
@@ -0,0 +1,93 @@
+//go:build linux
+
+package netif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// interfaceAddrs dumps the addresses of name over rtnetlink, unlike
+// net.Interface.Addrs it keeps the flags telling privacy and deprecated ipv6
+// addresses apart.
+func interfaceAddrs(name string) ([]address, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETADDR, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("netlink dump error: %w", err)
+	}
+
+	messages, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, fmt.Errorf("netlink parse error: %w", err)
+	}
+
+	addrs := make([]address, 0)
+	for _, message := range messages {
+		if message.Header.Type != syscall.RTM_NEWADDR {
+			continue
+		}
+
+		addr, index, ok := parseAddrMessage(message)
+		if !ok || index != uint32(ifi.Index) {
+			continue
+		}
+
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, nil
+}
+
+// parseAddrMessage reads an RTM_NEWADDR message, an ifaddrmsg header followed
+// by its attributes. IFA_LOCAL holds the local address of point to point
+// links, where IFA_ADDRESS is the peer one.
+func parseAddrMessage(message syscall.NetlinkMessage) (address, uint32, bool) {
+	if len(message.Data) < syscall.SizeofIfAddrmsg {
+		return address{}, 0, false
+	}
+
+	flags := uint32(message.Data[2])
+	index := binary.NativeEndian.Uint32(message.Data[4:8])
+
+	attrs, err := syscall.ParseNetlinkRouteAttr(&message)
+	if err != nil {
+		return address{}, 0, false
+	}
+
+	var ip, local netip.Addr
+	for _, attr := range attrs {
+		value, ok := netip.AddrFromSlice(attr.Value)
+		if !ok {
+			continue
+		}
+
+		switch attr.Attr.Type {
+		case syscall.IFA_ADDRESS:
+			ip = value
+		case syscall.IFA_LOCAL:
+			local = value
+		}
+	}
+
+	if local.IsValid() {
+		ip = local
+	}
+
+	if !ip.IsValid() {
+		return address{}, 0, false
+	}
+
+	return address{
+		ip:          ip.Unmap(),
+		temporary:   flags&syscall.IFA_F_TEMPORARY != 0,
+		unpreferred: flags&(syscall.IFA_F_DEPRECATED|syscall.IFA_F_TENTATIVE|syscall.IFA_F_DADFAILED) != 0,
+	}, index, true
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package netif
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// addrMessage builds an RTM_NEWADDR message the way the kernel sends it.
+func addrMessage(family, flags uint8, index uint32, attrs map[uint16][]byte) syscall.NetlinkMessage {
+	data := make([]byte, syscall.SizeofIfAddrmsg)
+	data[0], data[2] = family, flags
+	binary.NativeEndian.PutUint32(data[4:8], index)
+
+	for _, attrType := range []uint16{syscall.IFA_ADDRESS, syscall.IFA_LOCAL} {
+		value, ok := attrs[attrType]
+		if !ok {
+			continue
+		}
+
+		attr := make([]byte, syscall.SizeofRtAttr, syscall.SizeofRtAttr+len(value))
+		binary.NativeEndian.PutUint16(attr[0:2], uint16(syscall.SizeofRtAttr+len(value)))
+		binary.NativeEndian.PutUint16(attr[2:4], attrType)
+		attr = append(attr, value...)
+		for len(attr)%syscall.NLMSG_ALIGNTO != 0 {
+			attr = append(attr, 0)
+		}
+
+		data = append(data, attr...)
+	}
+
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: syscall.RTM_NEWADDR}, Data: data}
+}
+
+func TestParseAddrMessage(t *testing.T) {
+	testCases := []struct {
+		name            string
+		message         syscall.NetlinkMessage
+		expectedAddress address
+		expectedIndex   uint32
+		expectedOK      bool
+	}{
+		{
+			name: "ipv4",
+			message: addrMessage(syscall.AF_INET, 0, 2, map[uint16][]byte{
+				syscall.IFA_ADDRESS: {203, 0, 113, 7},
+				syscall.IFA_LOCAL:   {203, 0, 113, 7},
+			}),
+			expectedAddress: address{ip: netip.MustParseAddr("203.0.113.7")},
+			expectedIndex:   2,
+			expectedOK:      true,
+		},
+		{
+			name: "point-to-point",
+			message: addrMessage(syscall.AF_INET, 0, 5, map[uint16][]byte{
+				syscall.IFA_ADDRESS: {10, 8, 0, 1},
+				syscall.IFA_LOCAL:   {203, 0, 113, 9},
+			}),
+			expectedAddress: address{ip: netip.MustParseAddr("203.0.113.9")},
+			expectedIndex:   5,
+			expectedOK:      true,
+		},
+		{
+			name: "temporary-ipv6",
+			message: addrMessage(syscall.AF_INET6, syscall.IFA_F_TEMPORARY, 2, map[uint16][]byte{
+				syscall.IFA_ADDRESS: netip.MustParseAddr("2001:db8:1::8c2f").AsSlice(),
+			}),
+			expectedAddress: address{ip: netip.MustParseAddr("2001:db8:1::8c2f"), temporary: true},
+			expectedIndex:   2,
+			expectedOK:      true,
+		},
+		{
+			name: "deprecated-ipv6",
+			message: addrMessage(syscall.AF_INET6, syscall.IFA_F_DEPRECATED, 2, map[uint16][]byte{
+				syscall.IFA_ADDRESS: netip.MustParseAddr("2001:db8:1::10").AsSlice(),
+			}),
+			expectedAddress: address{ip: netip.MustParseAddr("2001:db8:1::10"), unpreferred: true},
+			expectedIndex:   2,
+			expectedOK:      true,
+		},
+		{
+			name:    "no-address",
+			message: addrMessage(syscall.AF_INET, 0, 2, nil),
+		},
+		{
+			name:    "truncated",
+			message: syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: syscall.RTM_NEWADDR}, Data: []byte{2, 24}},
+		},
+	}
+
+	for _, tc := range testCases {
+		message := tc.message
+		expectedAddress := tc.expectedAddress
+		expectedIndex := tc.expectedIndex
+		expectedOK := tc.expectedOK
+
+		t.Run(tc.name, func(t *testing.T) {
+			addr, index, ok := parseAddrMessage(message)
+
+			assert.Equal(t, expectedOK, ok)
+			assert.Equal(t, expectedAddress, addr)
+			assert.Equal(t, expectedIndex, index)
+		})
+	}
+}
+
+func TestInterfaceAddrs(t *testing.T) {
+	addrs, err := interfaceAddrs("lo")
+	if err != nil {
+		t.Skipf("loopback not available: %s", err)
+	}
+
+	assert.Contains(t, addrs, address{ip: netip.MustParseAddr("127.0.0.1")})
+
+	_, err = interfaceAddrs("missing0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+//go:build !linux
+
+package netif
+
+import (
+	"net"
+	"net/netip"
+)
+
+// interfaceAddrs returns the addresses of name. Address flags aren't exposed
+// outside linux, so temporary ipv6 addresses can't be told apart here.
+func interfaceAddrs(name string) ([]address, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ifaddrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]address, 0, len(ifaddrs))
+	for _, ifaddr := range ifaddrs {
+		prefix, err := netip.ParsePrefix(ifaddr.String())
+		if err != nil {
+			continue
+		}
+
+		addrs = append(addrs, address{ip: prefix.Addr().Unmap()})
+	}
+
+	return addrs, nil
+}
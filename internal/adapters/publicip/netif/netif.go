@@ -0,0 +1,135 @@
+package netif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/source"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const (
+	sourceName string = "interface"
+	configNode string = "ddns.public-ip-api." + sourceName
+
+	familyIPv4 string = "ipv4"
+	familyIPv6 string = "ipv6"
+)
+
+func init() {
+	source.Register(sourceName, func(cnf source.ConfigDecoder, logger source.MessageLogger) (publicip.Getter, error) {
+		return New(cnf, logger)
+	})
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, addresses in
+// it aren't reachable from the internet either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Error(err error)
+	Debug(msg string)
+}
+
+type netifConfig struct {
+	Name     string   `yaml:"name"`
+	Families []string `yaml:"families"`
+}
+
+// address is an address assigned to an interface. Temporary is set for ipv6
+// privacy addresses, unpreferred for addresses that are deprecated or still
+// going through duplicate address detection.
+type address struct {
+	ip          netip.Addr
+	temporary   bool
+	unpreferred bool
+}
+
+type netifGetter struct {
+	config netifConfig
+	addrs  func(name string) ([]address, error)
+	logger messageLogger
+}
+
+// New builds a getter that reads the public addresses assigned to the
+// interface configured under ddns.public-ip-api.interface, both families are
+// read unless families says otherwise.
+func New(cnf configDecoder, logger messageLogger) (publicip.Getter, error) {
+	config := netifConfig{}
+	if err := cnf.Decode(configNode, &config); err != nil {
+		return nil, fmt.Errorf("netif: unable to create new interface instance, err:%w", err)
+	}
+
+	if config.Name == "" {
+		return nil, errors.New("netif: missing interface name")
+	}
+
+	if len(config.Families) == 0 {
+		config.Families = []string{familyIPv4, familyIPv6}
+	}
+
+	for _, family := range config.Families {
+		if family != familyIPv4 && family != familyIPv6 {
+			return nil, fmt.Errorf("netif: unknown address family %s", family)
+		}
+	}
+
+	return &netifGetter{
+		config: config,
+		addrs:  interfaceAddrs,
+		logger: logger,
+	}, nil
+}
+
+func (n *netifGetter) GetIP(ctx context.Context) publicip.IP {
+	addrs, err := n.addrs(n.config.Name)
+	if err != nil {
+		n.logger.Error(fmt.Errorf("netif: unable to read addresses of %s, err:%w", n.config.Name, err))
+		return publicip.IP{}
+	}
+
+	ip := publicip.IP{}
+	if slices.Contains(n.config.Families, familyIPv4) {
+		ip.V4 = n.publicAddress(addrs, netip.Addr.Is4)
+	}
+
+	if slices.Contains(n.config.Families, familyIPv6) {
+		ip.V6 = n.publicAddress(addrs, netip.Addr.Is6)
+	}
+
+	return ip
+}
+
+// publicAddress returns the first public address of the family picked by is,
+// temporary and unpreferred addresses are never returned.
+func (n *netifGetter) publicAddress(addrs []address, is func(netip.Addr) bool) *string {
+	for _, addr := range addrs {
+		if !is(addr.ip) {
+			continue
+		}
+
+		if !isPublic(addr.ip) || addr.temporary || addr.unpreferred {
+			n.logger.Debug(fmt.Sprintf("netif: %s: skipping %s", n.config.Name, addr.ip))
+			continue
+		}
+
+		value := addr.ip.String()
+		n.logger.Debug(fmt.Sprintf("netif: %s: public address %s", n.config.Name, value))
+		return &value
+	}
+
+	return nil
+}
+
+// isPublic tells whether ip can be reached from the internet, ruling out
+// private, unique local, link-local, loopback and carrier-grade NAT addresses.
+func isPublic(ip netip.Addr) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,169 @@
+package netif
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type configMock struct {
+	config *netifConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	if c.config == nil {
+		return errors.New("node ddns.public-ip-api.interface not found")
+	}
+
+	*item.(*netifConfig) = *c.config
+	return nil
+}
+
+type messageLoggerMock struct {
+	errorMessages []string
+}
+
+func (l *messageLoggerMock) Error(err error)  { l.errorMessages = append(l.errorMessages, err.Error()) }
+func (l *messageLoggerMock) Debug(msg string) {}
+
+func addr(ip string) address {
+	return address{ip: netip.MustParseAddr(ip)}
+}
+
+func TestGetIP(t *testing.T) {
+	temporary := addr("2001:db8:1::8c2f:11ff:fe22:3344")
+	temporary.temporary = true
+	deprecated := addr("2001:db8:1::10")
+	deprecated.unpreferred = true
+
+	testCases := []struct {
+		name           string
+		families       []string
+		addrs          []address
+		err            error
+		expectedIP     publicip.IP
+		expectedErrors []string
+	}{
+		{
+			name: "public-addresses",
+			addrs: []address{
+				addr("127.0.0.1"),
+				addr("203.0.113.7"),
+				addr("::1"),
+				addr("fe80::1"),
+				addr("2001:db8:1::1"),
+			},
+			expectedIP: publicip.IP{V4: stringPointer("203.0.113.7"), V6: stringPointer("2001:db8:1::1")},
+		},
+		{
+			name: "private-addresses",
+			addrs: []address{
+				addr("10.0.0.2"),
+				addr("172.16.4.1"),
+				addr("192.168.1.10"),
+				addr("100.72.0.1"),
+				addr("169.254.10.1"),
+				addr("fd12:3456:789a::1"),
+				addr("fe80::1"),
+			},
+		},
+		{
+			name:       "temporary-and-deprecated-skipped",
+			addrs:      []address{temporary, deprecated, addr("2001:db8:1::1")},
+			expectedIP: publicip.IP{V6: stringPointer("2001:db8:1::1")},
+		},
+		{
+			name:       "ipv4-only",
+			families:   []string{"ipv4"},
+			addrs:      []address{addr("2001:db8:1::1"), addr("203.0.113.7")},
+			expectedIP: publicip.IP{V4: stringPointer("203.0.113.7")},
+		},
+		{
+			name:           "unknown-interface",
+			err:            errors.New("route ip+net: no such network interface"),
+			expectedErrors: []string{"netif: unable to read addresses of eth0, err:route ip+net: no such network interface"},
+		},
+	}
+
+	for _, tc := range testCases {
+		families := tc.families
+		addrs := tc.addrs
+		err := tc.err
+		expectedIP := tc.expectedIP
+		expectedErrors := tc.expectedErrors
+
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &messageLoggerMock{}
+			getter, newErr := New(configMock{config: &netifConfig{Name: "eth0", Families: families}}, logger)
+			assert.NoError(t, newErr)
+
+			getter.(*netifGetter).addrs = func(name string) ([]address, error) {
+				assert.Equal(t, "eth0", name)
+				return addrs, err
+			}
+
+			assert.Equal(t, expectedIP, getter.GetIP(context.Background()))
+			assert.Equal(t, expectedErrors, logger.errorMessages)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         *netifConfig
+		expectedConfig netifConfig
+		expectedError  string
+	}{
+		{
+			name:           "default-families",
+			config:         &netifConfig{Name: "eth0"},
+			expectedConfig: netifConfig{Name: "eth0", Families: []string{"ipv4", "ipv6"}},
+		},
+		{
+			name:           "ipv6-only",
+			config:         &netifConfig{Name: "eth0", Families: []string{"ipv6"}},
+			expectedConfig: netifConfig{Name: "eth0", Families: []string{"ipv6"}},
+		},
+		{
+			name:          "not-configured",
+			expectedError: "netif: unable to create new interface instance, err:node ddns.public-ip-api.interface not found",
+		},
+		{
+			name:          "missing-name",
+			config:        &netifConfig{},
+			expectedError: "netif: missing interface name",
+		},
+		{
+			name:          "unknown-family",
+			config:        &netifConfig{Name: "eth0", Families: []string{"inet"}},
+			expectedError: "netif: unknown address family inet",
+		},
+	}
+
+	for _, tc := range testCases {
+		config := tc.config
+		expectedConfig := tc.expectedConfig
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			getter, err := New(configMock{config: config}, &messageLoggerMock{})
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedConfig, getter.(*netifGetter).config)
+		})
+	}
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
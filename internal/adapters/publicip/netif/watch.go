@@ -0,0 +1,129 @@
+package netif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+)
+
+const (
+	watchPath           string = "ddns.address-watch"
+	defaultDebounceSecs int    = 2
+)
+
+var ErrWatchDisabled = errors.New("address watch disabled")
+
+type watchLogger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warning(msg string)
+}
+
+type watchConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Interfaces   []string `yaml:"interfaces"`
+	DebounceSecs int      `yaml:"debounce-secs"`
+}
+
+type watcher struct {
+	interfaces    []string
+	debounce      time.Duration
+	interfaceName func(index int) (string, error)
+	listen        func(ctx context.Context, changes chan<- string) error
+	logger        watchLogger
+}
+
+// NewWatcher builds the address watcher configured under ddns.address-watch,
+// ErrWatchDisabled is returned when it isn't enabled. Changes on every
+// interface are watched unless interfaces names some of them.
+func NewWatcher(cnf configDecoder, logger watchLogger) (*watcher, error) {
+	config := watchConfig{}
+	if err := cnf.Decode(watchPath, &config); err != nil || !config.Enabled {
+		return nil, ErrWatchDisabled
+	}
+
+	if config.DebounceSecs < 0 {
+		return nil, fmt.Errorf("netif: invalid address watch debounce %d", config.DebounceSecs)
+	}
+
+	if config.DebounceSecs == 0 {
+		config.DebounceSecs = defaultDebounceSecs
+	}
+
+	if err := watchSupported(); err != nil {
+		return nil, err
+	}
+
+	w := &watcher{
+		interfaces:    config.Interfaces,
+		debounce:      time.Duration(config.DebounceSecs) * time.Second,
+		interfaceName: interfaceName,
+		logger:        logger,
+	}
+	w.listen = w.subscribe
+
+	return w, nil
+}
+
+// Run calls trigger once the addresses of the watched interfaces settle after
+// a change, until ctx is cancelled.
+func (w *watcher) Run(ctx context.Context, trigger func()) error {
+	changes := make(chan string, 1)
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- w.listen(ctx, changes)
+	}()
+
+	w.logger.Info(fmt.Sprintf("netif: watching address changes on %s", w.watched()))
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			<-subscribed
+			return nil
+		case err := <-subscribed:
+			return fmt.Errorf("netif: address watch stopped, err:%w", err)
+		case change := <-changes:
+			w.logger.Debug(fmt.Sprintf("netif: %s, syncing in %s", change, w.debounce))
+			settled = time.After(w.debounce)
+		case <-settled:
+			settled = nil
+			w.logger.Info("netif: addresses changed, triggering sync")
+			trigger()
+		}
+	}
+}
+
+// notify hands a change to Run without blocking, changes arriving before the
+// last one was read are folded into it.
+func notify(changes chan<- string, change string) {
+	select {
+	case changes <- change:
+	default:
+	}
+}
+
+func interfaceName(index int) (string, error) {
+	ifi, err := net.InterfaceByIndex(index)
+	if err != nil {
+		return "", err
+	}
+
+	return ifi.Name, nil
+}
+
+func (w *watcher) watches(name string) bool {
+	return len(w.interfaces) == 0 || slices.Contains(w.interfaces, name)
+}
+
+func (w *watcher) watched() string {
+	if len(w.interfaces) == 0 {
+		return "every interface"
+	}
+
+	return fmt.Sprintf("%v", w.interfaces)
+}
@@ -0,0 +1,112 @@
+//go:build linux
+
+package netif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+const (
+	// receiveBufferSize fits the largest datagram rtnetlink sends.
+	receiveBufferSize int = 1 << 16
+
+	// rtnetlink multicast groups from linux/rtnetlink.h, syscall doesn't
+	// define them.
+	rtmgrpIPv4IfAddr uint32 = 0x10
+	rtmgrpIPv6IfAddr uint32 = 0x100
+)
+
+func watchSupported() error {
+	return nil
+}
+
+// subscribe listens to the rtnetlink ipv4 and ipv6 address groups and sends
+// every relevant change to changes until ctx is cancelled.
+func (w *watcher) subscribe(ctx context.Context, changes chan<- string) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("netlink socket error: %w", err)
+	}
+
+	addr := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: rtmgrpIPv4IfAddr | rtmgrpIPv6IfAddr}
+	if err = syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return fmt.Errorf("netlink bind error: %w", err)
+	}
+
+	// a non blocking descriptor is handed to the runtime poller, so closing
+	// it unblocks the pending read.
+	conn := os.NewFile(uintptr(fd), "rtnetlink")
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	buf := make([]byte, receiveBufferSize)
+	for {
+		n, err := conn.Read(buf)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.Is(err, syscall.ENOBUFS):
+			notify(changes, "netlink notifications dropped")
+			continue
+		case err != nil:
+			return fmt.Errorf("netlink read error: %w", err)
+		}
+
+		messages, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			w.logger.Warning(fmt.Sprintf("netif: invalid netlink message, err:%s", err))
+			continue
+		}
+
+		for _, message := range messages {
+			if change, ok := w.change(message); ok {
+				notify(changes, change)
+			}
+		}
+	}
+}
+
+// change describes the address change message announces, only global
+// addresses added to or removed from watched interfaces are reported.
+// Temporary addresses come and go on their own, and new addresses are only
+// reported once usable.
+func (w *watcher) change(message syscall.NetlinkMessage) (string, bool) {
+	action := ""
+	switch message.Header.Type {
+	case syscall.RTM_NEWADDR:
+		action = "added"
+	case syscall.RTM_DELADDR:
+		action = "removed"
+	default:
+		return "", false
+	}
+
+	addr, index, ok := parseAddrMessage(message)
+	if !ok || !isPublic(addr.ip) || addr.temporary {
+		return "", false
+	}
+
+	if message.Header.Type == syscall.RTM_NEWADDR && addr.unpreferred {
+		return "", false
+	}
+
+	name, err := w.interfaceName(int(index))
+	if err != nil {
+		if len(w.interfaces) > 0 {
+			return "", false
+		}
+		name = fmt.Sprintf("interface %d", index)
+	}
+
+	if !w.watches(name) {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s %s on %s", addr.ip, action, name), true
+}
@@ -0,0 +1,234 @@
+//go:build linux
+
+package netif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type watchConfigMock struct {
+	config *watchConfig
+}
+
+func (c watchConfigMock) Decode(node string, item any) error {
+	if c.config == nil {
+		return fmt.Errorf("node %s not found", node)
+	}
+
+	*item.(*watchConfig) = *c.config
+	return nil
+}
+
+type watchLoggerMock struct {
+	mu    sync.Mutex
+	infos []string
+}
+
+func (l *watchLoggerMock) Debug(msg string)   {}
+func (l *watchLoggerMock) Warning(msg string) {}
+func (l *watchLoggerMock) Info(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.infos = append(l.infos, msg)
+}
+
+func TestNewWatcher(t *testing.T) {
+	testCases := []struct {
+		name               string
+		config             *watchConfig
+		expectedInterfaces []string
+		expectedDebounce   time.Duration
+		expectedError      error
+	}{
+		{
+			name:          "not-configured",
+			expectedError: ErrWatchDisabled,
+		},
+		{
+			name:          "disabled",
+			config:        &watchConfig{Interfaces: []string{"ppp0"}},
+			expectedError: ErrWatchDisabled,
+		},
+		{
+			name:             "every-interface",
+			config:           &watchConfig{Enabled: true},
+			expectedDebounce: 2 * time.Second,
+		},
+		{
+			name:               "named-interfaces",
+			config:             &watchConfig{Enabled: true, Interfaces: []string{"ppp0"}, DebounceSecs: 5},
+			expectedInterfaces: []string{"ppp0"},
+			expectedDebounce:   5 * time.Second,
+		},
+		{
+			name:          "invalid-debounce",
+			config:        &watchConfig{Enabled: true, DebounceSecs: -1},
+			expectedError: errors.New("netif: invalid address watch debounce -1"),
+		},
+	}
+
+	for _, tc := range testCases {
+		config := tc.config
+		expectedInterfaces := tc.expectedInterfaces
+		expectedDebounce := tc.expectedDebounce
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := NewWatcher(watchConfigMock{config: config}, &watchLoggerMock{})
+			if expectedError != nil {
+				assert.Equal(t, expectedError, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedInterfaces, w.interfaces)
+			assert.Equal(t, expectedDebounce, w.debounce)
+		})
+	}
+}
+
+func TestWatcherChange(t *testing.T) {
+	public6 := netip.MustParseAddr("2001:db8:1::1").AsSlice()
+
+	testCases := []struct {
+		name           string
+		interfaces     []string
+		message        syscall.NetlinkMessage
+		expectedChange string
+		expectedOK     bool
+	}{
+		{
+			name: "address-added",
+			message: addrMessage(syscall.AF_INET, 0, 7, map[uint16][]byte{
+				syscall.IFA_LOCAL: {203, 0, 113, 7},
+			}),
+			expectedChange: "203.0.113.7 added on ppp0",
+			expectedOK:     true,
+		},
+		{
+			name:           "address-removed",
+			interfaces:     []string{"ppp0"},
+			message:        deleted(addrMessage(syscall.AF_INET6, 0, 7, map[uint16][]byte{syscall.IFA_ADDRESS: public6})),
+			expectedChange: "2001:db8:1::1 removed on ppp0",
+			expectedOK:     true,
+		},
+		{
+			name:       "other-interface",
+			interfaces: []string{"eth1"},
+			message: addrMessage(syscall.AF_INET, 0, 7, map[uint16][]byte{
+				syscall.IFA_LOCAL: {203, 0, 113, 7},
+			}),
+		},
+		{
+			name: "private-address",
+			message: addrMessage(syscall.AF_INET, 0, 7, map[uint16][]byte{
+				syscall.IFA_LOCAL: {192, 168, 1, 10},
+			}),
+		},
+		{
+			name:    "temporary-address",
+			message: addrMessage(syscall.AF_INET6, syscall.IFA_F_TEMPORARY, 7, map[uint16][]byte{syscall.IFA_ADDRESS: public6}),
+		},
+		{
+			name:    "tentative-address",
+			message: addrMessage(syscall.AF_INET6, syscall.IFA_F_TENTATIVE, 7, map[uint16][]byte{syscall.IFA_ADDRESS: public6}),
+		},
+		{
+			name:    "not-an-address",
+			message: syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: syscall.RTM_NEWLINK}},
+		},
+	}
+
+	for _, tc := range testCases {
+		interfaces := tc.interfaces
+		message := tc.message
+		expectedChange := tc.expectedChange
+		expectedOK := tc.expectedOK
+
+		t.Run(tc.name, func(t *testing.T) {
+			w := &watcher{
+				interfaces: interfaces,
+				interfaceName: func(index int) (string, error) {
+					assert.Equal(t, 7, index)
+					return "ppp0", nil
+				},
+			}
+
+			change, ok := w.change(message)
+
+			assert.Equal(t, expectedOK, ok)
+			assert.Equal(t, expectedChange, change)
+		})
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	logger := &watchLoggerMock{}
+	w := &watcher{
+		debounce: 50 * time.Millisecond,
+		logger:   logger,
+		listen: func(ctx context.Context, changes chan<- string) error {
+			// a reconnect adds and removes several addresses at once.
+			for _, change := range []string{"203.0.113.7 removed on ppp0", "203.0.113.9 added on ppp0"} {
+				notify(changes, change)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			<-ctx.Done()
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	triggered := make(chan struct{}, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func() { triggered <- struct{}{} })
+	}()
+
+	select {
+	case <-triggered:
+	case <-time.After(time.Second):
+		t.Fatal("sync not triggered")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Len(t, triggered, 0)
+}
+
+func TestWatcherSubscribe(t *testing.T) {
+	w, err := NewWatcher(watchConfigMock{config: &watchConfig{Enabled: true}}, &watchLoggerMock{})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func() {})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err = <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("watcher didn't stop")
+	}
+}
+
+func deleted(message syscall.NetlinkMessage) syscall.NetlinkMessage {
+	message.Header.Type = syscall.RTM_DELADDR
+	return message
+}
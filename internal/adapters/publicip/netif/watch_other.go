@@ -0,0 +1,18 @@
+//go:build !linux
+
+package netif
+
+import (
+	"context"
+	"errors"
+)
+
+var errWatchUnsupported = errors.New("netif: address watch is only supported on linux")
+
+func watchSupported() error {
+	return errWatchUnsupported
+}
+
+func (w *watcher) subscribe(ctx context.Context, changes chan<- string) error {
+	return errWatchUnsupported
+}
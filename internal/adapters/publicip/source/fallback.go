@@ -0,0 +1,73 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type NamedGetter struct {
+	Name   string
+	Getter publicip.Getter
+}
+
+type fallback struct {
+	getters []NamedGetter
+	timeout time.Duration
+	logger  MessageLogger
+}
+
+// NewFallback returns a getter that asks each source in order and returns the
+// first answer with at least one address. A source that doesn't answer within
+// timeout is skipped.
+func NewFallback(getters []NamedGetter, timeout time.Duration, logger MessageLogger) publicip.Getter {
+	return &fallback{
+		getters: getters,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+func (f *fallback) GetIP(ctx context.Context) publicip.IP {
+	for _, getter := range f.getters {
+		ip, err := query(ctx, getter, f.timeout)
+		if err != nil {
+			f.logger.Warning(err.Error())
+			continue
+		}
+
+		f.logger.Debug(fmt.Sprintf("source: public ip obtained from %s", getter.Name))
+		return ip
+	}
+
+	return publicip.IP{}
+}
+
+// query runs a single source bounded by timeout, so a getter that ignores
+// context cancellation can't block the caller.
+func query(ctx context.Context, getter NamedGetter, timeout time.Duration) (publicip.IP, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result := make(chan publicip.IP, 1)
+	go func() {
+		result <- getter.Getter.GetIP(ctx)
+	}()
+
+	select {
+	case ip := <-result:
+		if ip.V4 == nil && ip.V6 == nil {
+			return ip, fmt.Errorf("source: %s returned no address", getter.Name)
+		}
+
+		ip.Source = getter.Name
+		return ip, nil
+	case <-ctx.Done():
+		return publicip.IP{}, fmt.Errorf("source: %s didn't answer, err:%w", getter.Name, ctx.Err())
+	}
+}
@@ -0,0 +1,83 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingGetter struct {
+	release chan struct{}
+}
+
+func (g blockingGetter) GetIP(ctx context.Context) publicip.IP {
+	<-g.release
+	return publicip.IP{V4: stringPointer("10.0.0.9")}
+}
+
+func TestFallbackGetIP(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	testCases := []struct {
+		name             string
+		getters          []NamedGetter
+		expectedIP       publicip.IP
+		expectedWarnings []string
+	}{
+		{
+			name: "first-source-answers",
+			getters: []NamedGetter{
+				{Name: "ipify", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}}},
+				{Name: "icanhazip", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.2")}}},
+			},
+			expectedIP:       publicip.IP{V4: stringPointer("10.0.0.1"), Source: "ipify"},
+			expectedWarnings: nil,
+		},
+		{
+			name: "fallback-on-empty-answer",
+			getters: []NamedGetter{
+				{Name: "ipify", Getter: getterMock{}},
+				{Name: "icanhazip", Getter: getterMock{ip: publicip.IP{V6: stringPointer("::1")}}},
+			},
+			expectedIP:       publicip.IP{V6: stringPointer("::1"), Source: "icanhazip"},
+			expectedWarnings: []string{"source: ipify returned no address"},
+		},
+		{
+			name: "fallback-on-timeout",
+			getters: []NamedGetter{
+				{Name: "slow", Getter: blockingGetter{release: release}},
+				{Name: "icanhazip", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.2")}}},
+			},
+			expectedIP:       publicip.IP{V4: stringPointer("10.0.0.2"), Source: "icanhazip"},
+			expectedWarnings: []string{"source: slow didn't answer, err:context deadline exceeded"},
+		},
+		{
+			name: "no-source-answers",
+			getters: []NamedGetter{
+				{Name: "ipify", Getter: getterMock{}},
+			},
+			expectedIP:       publicip.IP{},
+			expectedWarnings: []string{"source: ipify returned no address"},
+		},
+	}
+
+	for _, tc := range testCases {
+		getters := tc.getters
+		expectedIP := tc.expectedIP
+		expectedWarnings := tc.expectedWarnings
+
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &loggerMock{}
+			getter := NewFallback(getters, 20*time.Millisecond, logger)
+
+			ip := getter.GetIP(context.Background())
+
+			assert.Equal(t, expectedIP, ip)
+			assert.Equal(t, expectedWarnings, logger.warningMessages)
+		})
+	}
+}
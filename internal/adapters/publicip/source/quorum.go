@@ -0,0 +1,169 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type quorum struct {
+	getters  []NamedGetter
+	required int
+	timeout  time.Duration
+	logger   MessageLogger
+}
+
+type vote struct {
+	value   string
+	sources []string
+}
+
+// NewQuorum returns a getter that queries every source concurrently and only
+// accepts an address once at least required sources agree on it. Each family
+// is decided on its own, so a source without ipv6 doesn't block ipv4.
+func NewQuorum(getters []NamedGetter, required int, timeout time.Duration, logger MessageLogger) publicip.Getter {
+	return &quorum{
+		getters:  getters,
+		required: required,
+		timeout:  timeout,
+		logger:   logger,
+	}
+}
+
+func (q *quorum) GetIP(ctx context.Context) publicip.IP {
+	answers := make([]publicip.IP, len(q.getters))
+
+	wg := sync.WaitGroup{}
+	for i, getter := range q.getters {
+		wg.Add(1)
+		go func(i int, getter NamedGetter) {
+			defer wg.Done()
+
+			ip, err := query(ctx, getter, q.timeout)
+			if err != nil {
+				q.logger.Warning(err.Error())
+				return
+			}
+			answers[i] = ip
+		}(i, getter)
+	}
+	wg.Wait()
+
+	ipv4Votes := make([]vote, 0)
+	ipv6Votes := make([]vote, 0)
+	for i, answer := range answers {
+		ipv4Votes = addVote(ipv4Votes, answer.V4, q.getters[i].Name)
+		ipv6Votes = addVote(ipv6Votes, answer.V6, q.getters[i].Name)
+	}
+
+	ip := publicip.IP{}
+	sources := []string{}
+
+	if winner, ok := q.decide("ipv4", ipv4Votes); ok {
+		ip.V4 = &winner.value
+		sources = appendMissing(sources, winner.sources...)
+	}
+
+	if winner, ok := q.decide("ipv6", ipv6Votes); ok {
+		ip.V6 = &winner.value
+		sources = appendMissing(sources, winner.sources...)
+	}
+
+	if len(sources) > 0 {
+		ip.Source = fmt.Sprintf("quorum(%s)", strings.Join(sources, ","))
+	}
+
+	return ip
+}
+
+func (q *quorum) decide(family string, votes []vote) (vote, bool) {
+	if len(votes) == 0 {
+		return vote{}, false
+	}
+
+	winner, tied := votes[0], false
+	for _, v := range votes[1:] {
+		switch {
+		case len(v.sources) > len(winner.sources):
+			winner, tied = v, false
+		case len(v.sources) == len(winner.sources):
+			tied = true
+		}
+	}
+
+	if len(votes) > 1 {
+		q.logger.Warning(fmt.Sprintf("source: %s disagreement between sources: %s", family, describeVotes(votes)))
+	}
+
+	if len(winner.sources) < q.required {
+		q.logger.Warning(fmt.Sprintf(
+			"source: %s quorum not reached, %d sources required, best answer %s had %d",
+			family, q.required, winner.value, len(winner.sources),
+		))
+		return vote{}, false
+	}
+
+	// with several answers sharing the top count there's no way to tell the
+	// right one, picking by order would depend on which source is listed first.
+	if tied {
+		q.logger.Warning(fmt.Sprintf(
+			"source: %s quorum not reached, answers tied with %d sources each",
+			family, len(winner.sources),
+		))
+		return vote{}, false
+	}
+
+	return winner, true
+}
+
+func addVote(votes []vote, address *string, source string) []vote {
+	if address == nil {
+		return votes
+	}
+
+	value := *address
+	if parsed := net.ParseIP(value); parsed != nil {
+		value = parsed.String()
+	}
+
+	for i := range votes {
+		if votes[i].value == value {
+			votes[i].sources = append(votes[i].sources, source)
+			return votes
+		}
+	}
+
+	return append(votes, vote{value: value, sources: []string{source}})
+}
+
+func describeVotes(votes []vote) string {
+	parts := make([]string, 0, len(votes))
+	for _, v := range votes {
+		parts = append(parts, fmt.Sprintf("%s from [%s]", v.value, strings.Join(v.sources, ",")))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
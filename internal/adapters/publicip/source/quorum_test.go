@@ -0,0 +1,113 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuorumGetIP(t *testing.T) {
+	testCases := []struct {
+		name             string
+		getters          []NamedGetter
+		required         int
+		expectedIP       publicip.IP
+		expectedWarnings []string
+	}{
+		{
+			name: "all-sources-agree",
+			getters: []NamedGetter{
+				{Name: "ipify", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1"), V6: stringPointer("2001:0db8::1")}}},
+				{Name: "stun", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1"), V6: stringPointer("2001:db8::1")}}},
+			},
+			required: 2,
+			expectedIP: publicip.IP{
+				V4:     stringPointer("10.0.0.1"),
+				V6:     stringPointer("2001:db8::1"),
+				Source: "quorum(ipify,stun)",
+			},
+			expectedWarnings: nil,
+		},
+		{
+			name: "majority-wins-and-disagreement-logged",
+			getters: []NamedGetter{
+				{Name: "ipify", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}}},
+				{Name: "portal", Getter: getterMock{ip: publicip.IP{V4: stringPointer("192.168.0.1")}}},
+				{Name: "stun", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}}},
+			},
+			required: 2,
+			expectedIP: publicip.IP{
+				V4:     stringPointer("10.0.0.1"),
+				Source: "quorum(ipify,stun)",
+			},
+			expectedWarnings: []string{
+				"source: ipv4 disagreement between sources: 10.0.0.1 from [ipify,stun], 192.168.0.1 from [portal]",
+			},
+		},
+		{
+			name: "quorum-not-reached",
+			getters: []NamedGetter{
+				{Name: "ipify", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}}},
+				{Name: "portal", Getter: getterMock{ip: publicip.IP{V4: stringPointer("192.168.0.1")}}},
+				{Name: "stun", Getter: getterMock{}},
+			},
+			required:   2,
+			expectedIP: publicip.IP{},
+			expectedWarnings: []string{
+				"source: stun returned no address",
+				"source: ipv4 disagreement between sources: 10.0.0.1 from [ipify], 192.168.0.1 from [portal]",
+				"source: ipv4 quorum not reached, 2 sources required, best answer 10.0.0.1 had 1",
+			},
+		},
+		{
+			name: "tie-not-decided",
+			getters: []NamedGetter{
+				{Name: "ipify", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}}},
+				{Name: "portal", Getter: getterMock{ip: publicip.IP{V4: stringPointer("192.168.0.1")}}},
+				{Name: "stun", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}}},
+				{Name: "gateway", Getter: getterMock{ip: publicip.IP{V4: stringPointer("192.168.0.1")}}},
+			},
+			required:   2,
+			expectedIP: publicip.IP{},
+			expectedWarnings: []string{
+				"source: ipv4 disagreement between sources: 10.0.0.1 from [ipify,stun], 192.168.0.1 from [portal,gateway]",
+				"source: ipv4 quorum not reached, answers tied with 2 sources each",
+			},
+		},
+		{
+			name: "families-decided-independently",
+			getters: []NamedGetter{
+				{Name: "ipify", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1"), V6: stringPointer("::1")}}},
+				{Name: "stun", Getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}}},
+			},
+			required: 2,
+			expectedIP: publicip.IP{
+				V4:     stringPointer("10.0.0.1"),
+				Source: "quorum(ipify,stun)",
+			},
+			expectedWarnings: []string{
+				"source: ipv6 quorum not reached, 2 sources required, best answer ::1 had 1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		getters := tc.getters
+		required := tc.required
+		expectedIP := tc.expectedIP
+		expectedWarnings := tc.expectedWarnings
+
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &loggerMock{}
+			getter := NewQuorum(getters, required, time.Second, logger)
+
+			ip := getter.GetIP(context.Background())
+
+			assert.Equal(t, expectedIP, ip)
+			assert.Equal(t, expectedWarnings, logger.warningMessages)
+		})
+	}
+}
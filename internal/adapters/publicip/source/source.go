@@ -0,0 +1,208 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const (
+	sourcesPath        string = "ddns.public-ip-api"
+	policyPath         string = "ddns.public-ip"
+	defaultTimeoutSecs int    = 10
+
+	modeFallback string = "fallback"
+	modeQuorum   string = "quorum"
+)
+
+type ConfigDecoder interface {
+	Decode(node string, item any) error
+}
+
+type MessageLogger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warning(msg string)
+	Error(err error)
+}
+
+// Factory builds the getter configured under ddns.public-ip-api.<name>.
+type Factory func(cnf ConfigDecoder, logger MessageLogger) (publicip.Getter, error)
+
+// Decorator wraps the getter of a single source, e.g. to instrument it, before
+// it's combined with the others.
+type Decorator func(name string, getter publicip.Getter) publicip.Getter
+
+type policyConfig struct {
+	Mode        string   `yaml:"mode"`
+	Order       []string `yaml:"order"`
+	Quorum      int      `yaml:"quorum"`
+	TimeoutSecs int      `yaml:"timeout-secs"`
+}
+
+type registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+var defaultRegistry = newRegistry()
+
+func newRegistry() *registry {
+	return &registry{factories: map[string]Factory{}}
+}
+
+// Register makes a public ip source available under its config key. It is
+// meant to be called from the init function of each source adapter.
+func Register(name string, factory Factory) {
+	defaultRegistry.register(name, factory)
+}
+
+func Sources() []string {
+	return defaultRegistry.sources()
+}
+
+// NewGetter builds every configured source and combines them according to
+// ddns.public-ip.mode. In fallback mode (the default) sources are tried in the
+// order set under ddns.public-ip.order, or alphabetically when no order is set.
+// In quorum mode every source is queried and ddns.public-ip.quorum of them must
+// agree on an address. Decorators are applied to each source in order.
+func NewGetter(cnf ConfigDecoder, logger MessageLogger, decorators ...Decorator) (publicip.Getter, error) {
+	return defaultRegistry.newGetter(cnf, logger, decorators...)
+}
+
+func (r *registry) register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if factory == nil {
+		panic("source: register factory is nil for " + name)
+	}
+
+	if _, exists := r.factories[name]; exists {
+		panic("source: register called twice for " + name)
+	}
+
+	r.factories[name] = factory
+}
+
+func (r *registry) sources() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (r *registry) newGetter(cnf ConfigDecoder, logger MessageLogger, decorators ...Decorator) (publicip.Getter, error) {
+	policy, err := readPolicy(cnf)
+	if err != nil {
+		return nil, err
+	}
+
+	getters, err := r.newGetters(cnf, logger, policy.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, getter := range getters {
+		for _, decorate := range decorators {
+			getter.Getter = decorate(getter.Name, getter.Getter)
+		}
+		getters[i] = getter
+	}
+
+	timeout := time.Duration(policy.TimeoutSecs) * time.Second
+	if policy.Mode == modeQuorum {
+		required := policy.Quorum
+		if required == 0 {
+			required = len(getters)/2 + 1
+		}
+
+		if required > len(getters) {
+			return nil, fmt.Errorf("source: quorum %d is greater than the %d configured sources", required, len(getters))
+		}
+
+		return NewQuorum(getters, required, timeout, logger), nil
+	}
+
+	return NewFallback(getters, timeout, logger), nil
+}
+
+func (r *registry) newGetters(cnf ConfigDecoder, logger MessageLogger, order []string) ([]NamedGetter, error) {
+	configured := map[string]any{}
+	if err := cnf.Decode(sourcesPath, &configured); err != nil {
+		return nil, err
+	}
+
+	if len(order) == 0 {
+		for name := range configured {
+			order = append(order, name)
+		}
+		sort.Strings(order)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	getters := make([]NamedGetter, 0, len(order))
+	for _, name := range order {
+		if _, ok := configured[name]; !ok {
+			return nil, fmt.Errorf("source: %s is not configured under %s", name, sourcesPath)
+		}
+
+		factory, ok := r.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("source: unknown public ip source %s", name)
+		}
+
+		getter, err := factory(cnf, logger)
+		if err != nil {
+			return nil, fmt.Errorf("source: unable to create %s getter, err:%w", name, err)
+		}
+
+		getters = append(getters, NamedGetter{Name: name, Getter: getter})
+	}
+
+	if len(getters) == 0 {
+		return nil, fmt.Errorf("source: no public ip sources configured under %s", sourcesPath)
+	}
+
+	return getters, nil
+}
+
+func readPolicy(cnf ConfigDecoder) (policyConfig, error) {
+	policy := policyConfig{}
+	if err := cnf.Decode(policyPath, &policy); err != nil {
+		policy = policyConfig{}
+	}
+
+	switch policy.Mode {
+	case "":
+		policy.Mode = modeFallback
+	case modeFallback, modeQuorum:
+	default:
+		return policy, fmt.Errorf("source: unknown mode %s", policy.Mode)
+	}
+
+	if policy.Quorum < 0 {
+		return policy, fmt.Errorf("source: invalid quorum %d", policy.Quorum)
+	}
+
+	if policy.TimeoutSecs < 0 {
+		return policy, fmt.Errorf("source: invalid timeout %d", policy.TimeoutSecs)
+	}
+
+	if policy.TimeoutSecs == 0 {
+		policy.TimeoutSecs = defaultTimeoutSecs
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,280 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+type configMock struct {
+	sources map[string]any
+	policy  *policyConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	switch node {
+	case sourcesPath:
+		if c.sources == nil {
+			return errors.New("node ddns.public-ip-api not found")
+		}
+		*item.(*map[string]any) = c.sources
+	case policyPath:
+		if c.policy == nil {
+			return errors.New("node ddns.public-ip not found")
+		}
+		*item.(*policyConfig) = *c.policy
+	default:
+		return errors.New("unexpected node " + node)
+	}
+
+	return nil
+}
+
+type loggerMock struct {
+	mu              sync.Mutex
+	debugMessages   []string
+	warningMessages []string
+}
+
+func (l *loggerMock) Debug(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debugMessages = append(l.debugMessages, msg)
+}
+
+func (l *loggerMock) Info(msg string) {}
+
+func (l *loggerMock) Warning(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warningMessages = append(l.warningMessages, msg)
+}
+
+func (l *loggerMock) Error(err error) {}
+
+type getterMock struct {
+	ip publicip.IP
+}
+
+func (g getterMock) GetIP(ctx context.Context) publicip.IP {
+	return g.ip
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
+
+func factoryFor(ip publicip.IP) Factory {
+	return func(cnf ConfigDecoder, logger MessageLogger) (publicip.Getter, error) {
+		return getterMock{ip: ip}, nil
+	}
+}
+
+func TestNewGetters(t *testing.T) {
+	reg := newRegistry()
+	reg.register("ipify", factoryFor(publicip.IP{}))
+	reg.register("icanhazip", factoryFor(publicip.IP{}))
+	reg.register("broken", func(cnf ConfigDecoder, logger MessageLogger) (publicip.Getter, error) {
+		return nil, errors.New("missing endpoint")
+	})
+
+	testCases := []struct {
+		name          string
+		config        configMock
+		order         []string
+		expectedNames []string
+		expectedError string
+	}{
+		{
+			name:          "alphabetical-without-order",
+			config:        configMock{sources: map[string]any{"ipify": nil, "icanhazip": nil}},
+			expectedNames: []string{"icanhazip", "ipify"},
+		},
+		{
+			name:          "configured-order",
+			config:        configMock{sources: map[string]any{"ipify": nil, "icanhazip": nil}},
+			order:         []string{"ipify", "icanhazip"},
+			expectedNames: []string{"ipify", "icanhazip"},
+		},
+		{
+			name:          "order-references-unconfigured-source",
+			config:        configMock{sources: map[string]any{"ipify": nil}},
+			order:         []string{"icanhazip"},
+			expectedError: "source: icanhazip is not configured under ddns.public-ip-api",
+		},
+		{
+			name:          "unknown-source",
+			config:        configMock{sources: map[string]any{"whatismyip": nil}},
+			expectedError: "source: unknown public ip source whatismyip",
+		},
+		{
+			name:          "failing-source",
+			config:        configMock{sources: map[string]any{"broken": nil}},
+			expectedError: "source: unable to create broken getter, err:missing endpoint",
+		},
+		{
+			name:          "no-sources",
+			config:        configMock{sources: map[string]any{}},
+			expectedError: "source: no public ip sources configured under ddns.public-ip-api",
+		},
+	}
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		order := tc.order
+		expectedNames := tc.expectedNames
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			getters, err := reg.newGetters(cnf, &loggerMock{}, order)
+
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			names := []string{}
+			for _, getter := range getters {
+				names = append(names, getter.Name)
+			}
+			assert.Equal(t, expectedNames, names)
+		})
+	}
+}
+
+func TestReadPolicy(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         configMock
+		expectedPolicy policyConfig
+		expectedError  string
+	}{
+		{
+			name:           "defaults",
+			config:         configMock{},
+			expectedPolicy: policyConfig{Mode: modeFallback, TimeoutSecs: defaultTimeoutSecs},
+		},
+		{
+			name:           "configured",
+			config:         configMock{policy: &policyConfig{Order: []string{"ipify"}, TimeoutSecs: 3}},
+			expectedPolicy: policyConfig{Mode: modeFallback, Order: []string{"ipify"}, TimeoutSecs: 3},
+		},
+		{
+			name:           "quorum",
+			config:         configMock{policy: &policyConfig{Mode: modeQuorum, Quorum: 2}},
+			expectedPolicy: policyConfig{Mode: modeQuorum, Quorum: 2, TimeoutSecs: defaultTimeoutSecs},
+		},
+		{
+			name:          "unknown-mode",
+			config:        configMock{policy: &policyConfig{Mode: "random"}},
+			expectedError: "source: unknown mode random",
+		},
+		{
+			name:          "invalid-quorum",
+			config:        configMock{policy: &policyConfig{Mode: modeQuorum, Quorum: -2}},
+			expectedError: "source: invalid quorum -2",
+		},
+		{
+			name:          "invalid-timeout",
+			config:        configMock{policy: &policyConfig{TimeoutSecs: -1}},
+			expectedError: "source: invalid timeout -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		expectedPolicy := tc.expectedPolicy
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := readPolicy(cnf)
+
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedPolicy, policy)
+		})
+	}
+}
+
+func TestNewGetter(t *testing.T) {
+	reg := newRegistry()
+	reg.register("ipify", factoryFor(publicip.IP{}))
+	reg.register("icanhazip", factoryFor(publicip.IP{}))
+
+	sources := map[string]any{"ipify": nil, "icanhazip": nil}
+
+	testCases := []struct {
+		name             string
+		config           configMock
+		expectedType     publicip.Getter
+		expectedRequired int
+		expectedError    string
+	}{
+		{
+			name:         "fallback-by-default",
+			config:       configMock{sources: sources},
+			expectedType: &fallback{},
+		},
+		{
+			name:             "quorum-majority-by-default",
+			config:           configMock{sources: sources, policy: &policyConfig{Mode: modeQuorum}},
+			expectedType:     &quorum{},
+			expectedRequired: 2,
+		},
+		{
+			name:          "quorum-greater-than-sources",
+			config:        configMock{sources: sources, policy: &policyConfig{Mode: modeQuorum, Quorum: 3}},
+			expectedError: "source: quorum 3 is greater than the 2 configured sources",
+		},
+	}
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		expectedType := tc.expectedType
+		expectedRequired := tc.expectedRequired
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			getter, err := reg.newGetter(cnf, &loggerMock{})
+
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.IsType(t, expectedType, getter)
+			if q, ok := getter.(*quorum); ok {
+				assert.Equal(t, expectedRequired, q.required)
+			}
+		})
+	}
+}
+
+func TestNewGetterDecorators(t *testing.T) {
+	reg := newRegistry()
+	reg.register("ipify", factoryFor(publicip.IP{V4: stringPointer("10.0.0.1")}))
+
+	decorated := []string{}
+	decorator := func(name string, getter publicip.Getter) publicip.Getter {
+		decorated = append(decorated, name)
+		return getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.2")}}
+	}
+
+	getter, err := reg.newGetter(configMock{sources: map[string]any{"ipify": nil}}, &loggerMock{}, decorator)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ipify"}, decorated)
+
+	ip := getter.GetIP(context.Background())
+	assert.Equal(t, "10.0.0.2", *ip.V4)
+	assert.Equal(t, "ipify", ip.Source)
+}
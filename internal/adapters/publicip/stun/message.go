@@ -0,0 +1,157 @@
+package stun
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+)
+
+// STUN message layout from RFC 5389, only what a Binding request needs.
+const (
+	headerSize  int    = 20
+	magicCookie uint32 = 0x2112a442
+
+	bindingRequest  uint16 = 0x0001
+	bindingSuccess  uint16 = 0x0101
+	bindingError    uint16 = 0x0111
+	attrMapped      uint16 = 0x0001
+	attrErrorCode   uint16 = 0x0009
+	attrXorMapped   uint16 = 0x0020
+	addrFamilyIPv4  byte   = 0x01
+	addrFamilyIPv6  byte   = 0x02
+	attrHeaderSize  int    = 4
+	attrPaddingSize int    = 4
+)
+
+type transactionID [12]byte
+
+func newTransactionID() (transactionID, error) {
+	id := transactionID{}
+	_, err := rand.Read(id[:])
+
+	return id, err
+}
+
+// newBindingRequest encodes a Binding request without attributes.
+func newBindingRequest(id transactionID) []byte {
+	msg := make([]byte, headerSize)
+	binary.BigEndian.PutUint16(msg[0:2], bindingRequest)
+	binary.BigEndian.PutUint16(msg[2:4], 0)
+	binary.BigEndian.PutUint32(msg[4:8], magicCookie)
+	copy(msg[8:20], id[:])
+
+	return msg
+}
+
+// parseBindingResponse returns the address the server saw the request coming
+// from. XOR-MAPPED-ADDRESS is preferred, MAPPED-ADDRESS is only read from
+// servers that don't send it.
+func parseBindingResponse(msg []byte, id transactionID) (netip.AddrPort, error) {
+	if len(msg) < headerSize || binary.BigEndian.Uint32(msg[4:8]) != magicCookie || [12]byte(msg[8:20]) != id {
+		return netip.AddrPort{}, udp.ErrUnrelated
+	}
+
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	if len(msg) < headerSize+length {
+		return netip.AddrPort{}, fmt.Errorf("truncated message, %d of %d bytes", len(msg)-headerSize, length)
+	}
+
+	attrs := attributes(msg[headerSize : headerSize+length])
+
+	switch msgType := binary.BigEndian.Uint16(msg[0:2]); msgType {
+	case bindingSuccess:
+	case bindingError:
+		return netip.AddrPort{}, errorCode(attrs[attrErrorCode])
+	default:
+		return netip.AddrPort{}, fmt.Errorf("unexpected message type 0x%04x", msgType)
+	}
+
+	if value, ok := attrs[attrXorMapped]; ok {
+		return parseAddress(value, id, true)
+	}
+
+	if value, ok := attrs[attrMapped]; ok {
+		return parseAddress(value, id, false)
+	}
+
+	return netip.AddrPort{}, errors.New("response without mapped address")
+}
+
+// attributes indexes the attributes of a message body by type, the first
+// occurrence of each type wins.
+func attributes(body []byte) map[uint16][]byte {
+	attrs := map[uint16][]byte{}
+	for len(body) >= attrHeaderSize {
+		attrType := binary.BigEndian.Uint16(body[0:2])
+		length := int(binary.BigEndian.Uint16(body[2:4]))
+		if len(body) < attrHeaderSize+length {
+			break
+		}
+
+		if _, ok := attrs[attrType]; !ok {
+			attrs[attrType] = body[attrHeaderSize : attrHeaderSize+length]
+		}
+
+		padded := (length + attrPaddingSize - 1) / attrPaddingSize * attrPaddingSize
+		if len(body) < attrHeaderSize+padded {
+			break
+		}
+		body = body[attrHeaderSize+padded:]
+	}
+
+	return attrs
+}
+
+// parseAddress decodes a (XOR-)MAPPED-ADDRESS value. XOR'd ports and ipv4
+// addresses are masked with the magic cookie, ipv6 ones with the cookie
+// followed by the transaction id.
+func parseAddress(value []byte, id transactionID, xored bool) (netip.AddrPort, error) {
+	if len(value) < 4 {
+		return netip.AddrPort{}, errors.New("invalid mapped address")
+	}
+
+	mask := make([]byte, 0, 16)
+	mask = binary.BigEndian.AppendUint32(mask, magicCookie)
+	mask = append(mask, id[:]...)
+
+	port := binary.BigEndian.Uint16(value[2:4])
+	ip := value[4:]
+	if xored {
+		port ^= uint16(magicCookie >> 16)
+		ip = xor(ip, mask)
+	}
+
+	switch {
+	case value[1] == addrFamilyIPv4 && len(ip) == 4:
+	case value[1] == addrFamilyIPv6 && len(ip) == 16:
+	default:
+		return netip.AddrPort{}, fmt.Errorf("invalid mapped address family 0x%02x", value[1])
+	}
+
+	addr, _ := netip.AddrFromSlice(ip)
+	return netip.AddrPortFrom(addr, port), nil
+}
+
+func xor(value, mask []byte) []byte {
+	out := make([]byte, len(value))
+	for i := range value {
+		out[i] = value[i] ^ mask[i%len(mask)]
+	}
+
+	return out
+}
+
+// errorCode decodes an ERROR-CODE value: class and number make up the code,
+// a reason phrase follows.
+func errorCode(value []byte) error {
+	if len(value) < 4 {
+		return errors.New("binding error")
+	}
+
+	code := int(value[2]&0x07)*100 + int(value[3])
+	return fmt.Errorf("binding error %d %s", code, value[4:])
+}
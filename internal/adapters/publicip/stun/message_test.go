@@ -0,0 +1,158 @@
+package stun
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+)
+
+type attribute struct {
+	attrType uint16
+	value    []byte
+}
+
+// message encodes a STUN message with attrs padded to 4 bytes.
+func message(msgType uint16, id transactionID, attrs ...attribute) []byte {
+	body := []byte{}
+	for _, attr := range attrs {
+		body = binary.BigEndian.AppendUint16(body, attr.attrType)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(attr.value)))
+		body = append(body, attr.value...)
+		for len(body)%attrPaddingSize != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	msg := newBindingRequest(id)
+	binary.BigEndian.PutUint16(msg[0:2], msgType)
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(body)))
+
+	return append(msg, body...)
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func TestParseBindingResponse(t *testing.T) {
+	// transaction id and addresses of the RFC 5769 test vectors.
+	id := transactionID(mustDecodeHex("b7e7a701bc34d686fa87dfae"))
+	otherID := transactionID(mustDecodeHex("000000000000000000000001"))
+
+	badCookie := message(bindingSuccess, id)
+	binary.BigEndian.PutUint32(badCookie[4:8], 0)
+
+	testCases := []struct {
+		name          string
+		msg           []byte
+		expectedAddr  netip.AddrPort
+		expectedError string
+	}{
+		{
+			name:         "xor-mapped-ipv4",
+			msg:          message(bindingSuccess, id, attribute{attrXorMapped, mustDecodeHex("0001a147e112a643")}),
+			expectedAddr: netip.MustParseAddrPort("192.0.2.1:32853"),
+		},
+		{
+			name: "xor-mapped-ipv6",
+			msg: message(bindingSuccess, id,
+				attribute{attrXorMapped, mustDecodeHex("0002a1470113a9faa5d3f179bc25f4b5bed2b9d9")},
+			),
+			expectedAddr: netip.MustParseAddrPort("[2001:db8:1234:5678:11:2233:4455:6677]:32853"),
+		},
+		{
+			name: "xor-mapped-preferred",
+			msg: message(bindingSuccess, id,
+				attribute{attrMapped, mustDecodeHex("00018055c6336407")},
+				attribute{attrXorMapped, mustDecodeHex("0001a147e112a643")},
+			),
+			expectedAddr: netip.MustParseAddrPort("192.0.2.1:32853"),
+		},
+		{
+			name:         "mapped-fallback",
+			msg:          message(bindingSuccess, id, attribute{attrMapped, mustDecodeHex("00018055c6336407")}),
+			expectedAddr: netip.MustParseAddrPort("198.51.100.7:32853"),
+		},
+		{
+			name: "unknown-attributes-skipped",
+			msg: message(bindingSuccess, id,
+				attribute{0x8022, []byte("test vector")},
+				attribute{attrXorMapped, mustDecodeHex("0001a147e112a643")},
+			),
+			expectedAddr: netip.MustParseAddrPort("192.0.2.1:32853"),
+		},
+		{
+			name:          "error-response",
+			msg:           message(bindingError, id, attribute{attrErrorCode, append(mustDecodeHex("00000400"), "Bad Request"...)}),
+			expectedError: "binding error 400 Bad Request",
+		},
+		{
+			name:          "without-mapped-address",
+			msg:           message(bindingSuccess, id),
+			expectedError: "response without mapped address",
+		},
+		{
+			name:          "invalid-family",
+			msg:           message(bindingSuccess, id, attribute{attrXorMapped, mustDecodeHex("0003a147e112a643")}),
+			expectedError: "invalid mapped address family 0x03",
+		},
+		{
+			name:          "unexpected-type",
+			msg:           message(bindingRequest, id),
+			expectedError: "unexpected message type 0x0001",
+		},
+		{
+			name:          "truncated",
+			msg:           message(bindingSuccess, id, attribute{attrXorMapped, mustDecodeHex("0001a147e112a643")})[:24],
+			expectedError: "truncated message, 4 of 12 bytes",
+		},
+		{
+			name:          "other-transaction",
+			msg:           message(bindingSuccess, otherID, attribute{attrXorMapped, mustDecodeHex("0001a147e112a643")}),
+			expectedError: udp.ErrUnrelated.Error(),
+		},
+		{
+			name:          "bad-cookie",
+			msg:           badCookie,
+			expectedError: udp.ErrUnrelated.Error(),
+		},
+		{
+			name:          "short-datagram",
+			msg:           []byte{0x01, 0x01},
+			expectedError: udp.ErrUnrelated.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		msg := tc.msg
+		expectedAddr := tc.expectedAddr
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := parseBindingResponse(msg, id)
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedAddr, addr)
+		})
+	}
+}
+
+func TestNewBindingRequest(t *testing.T) {
+	id := transactionID(mustDecodeHex("b7e7a701bc34d686fa87dfae"))
+
+	assert.Equal(t, mustDecodeHex("000100002112a442b7e7a701bc34d686fa87dfae"), newBindingRequest(id))
+}
@@ -0,0 +1,179 @@
+package stun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/source"
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const (
+	sourceName         string = "stun"
+	configNode         string = "ddns.public-ip-api." + sourceName
+	defaultPort        string = "3478"
+	defaultTimeoutSecs int    = 3
+
+	familyIPv4 string = "ipv4"
+	familyIPv6 string = "ipv6"
+
+	// initialRTO is the first retransmission timeout of RFC 5389, doubled
+	// on every retransmission.
+	initialRTO time.Duration = 500 * time.Millisecond
+)
+
+func init() {
+	source.Register(sourceName, func(cnf source.ConfigDecoder, logger source.MessageLogger) (publicip.Getter, error) {
+		return New(cnf, logger)
+	})
+}
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Error(err error)
+	Debug(msg string)
+}
+
+type dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+type stunConfig struct {
+	Servers     []string `yaml:"servers"`
+	Families    []string `yaml:"families"`
+	TimeoutSecs int      `yaml:"timeout-secs"`
+}
+
+type stunGetter struct {
+	config  stunConfig
+	timeout time.Duration
+	dialer  dialer
+	logger  messageLogger
+}
+
+// New builds a getter that sends Binding requests to the servers configured
+// under ddns.public-ip-api.stun, in order, until one answers. Servers without
+// a port are reached on 3478, each one is given timeout-secs to answer.
+func New(cnf configDecoder, logger messageLogger) (publicip.Getter, error) {
+	config := stunConfig{}
+	if err := cnf.Decode(configNode, &config); err != nil {
+		return nil, fmt.Errorf("stun: unable to create new stun instance, err:%w", err)
+	}
+
+	if len(config.Servers) == 0 {
+		return nil, errors.New("stun: missing servers")
+	}
+
+	for i, server := range config.Servers {
+		address, err := udp.WithDefaultPort(server, defaultPort)
+		if err != nil {
+			return nil, fmt.Errorf("stun: invalid server %s, err:%w", server, err)
+		}
+
+		config.Servers[i] = address
+	}
+
+	if len(config.Families) == 0 {
+		config.Families = []string{familyIPv4, familyIPv6}
+	}
+
+	for _, family := range config.Families {
+		if family != familyIPv4 && family != familyIPv6 {
+			return nil, fmt.Errorf("stun: unknown address family %s", family)
+		}
+	}
+
+	if config.TimeoutSecs < 0 {
+		return nil, fmt.Errorf("stun: invalid timeout %d", config.TimeoutSecs)
+	}
+
+	if config.TimeoutSecs == 0 {
+		config.TimeoutSecs = defaultTimeoutSecs
+	}
+
+	return &stunGetter{
+		config:  config,
+		timeout: time.Duration(config.TimeoutSecs) * time.Second,
+		dialer:  &net.Dialer{},
+		logger:  logger,
+	}, nil
+}
+
+func (s *stunGetter) GetIP(ctx context.Context) publicip.IP {
+	ip := publicip.IP{}
+	if slices.Contains(s.config.Families, familyIPv4) {
+		ip.V4 = s.mappedAddress(ctx, "udp4")
+	}
+
+	if slices.Contains(s.config.Families, familyIPv6) {
+		ip.V6 = s.mappedAddress(ctx, "udp6")
+	}
+
+	return ip
+}
+
+// mappedAddress asks the servers in order for the address they see requests
+// sent over network coming from.
+func (s *stunGetter) mappedAddress(ctx context.Context, network string) *string {
+	for _, server := range s.config.Servers {
+		addr, err := s.binding(ctx, network, server)
+		if err != nil {
+			s.logger.Error(fmt.Errorf("stun: server=%s %s binding error: %w", server, network, err))
+			continue
+		}
+
+		value := addr.String()
+		s.logger.Debug(fmt.Sprintf("stun: %s: %s mapped address %s", server, network, value))
+		return &value
+	}
+
+	return nil
+}
+
+// binding runs a Binding transaction with server, the request is
+// retransmitted with a doubling timeout until an answer arrives or the
+// server timeout expires.
+func (s *stunGetter) binding(ctx context.Context, network, server string) (addr netip.Addr, err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	conn, err := s.dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return addr, err
+	}
+	defer conn.Close()
+
+	id, err := newTransactionID()
+	if err != nil {
+		return addr, err
+	}
+	request := newBindingRequest(id)
+
+	err = udp.Exchange(ctx, conn, request, initialRTO, func(msg []byte) error {
+		mapped, err := parseBindingResponse(msg, id)
+		if err != nil {
+			return err
+		}
+
+		if mapped.Addr().Unmap().Is4() != (network == "udp4") {
+			return fmt.Errorf("mapped address %s doesn't match %s", mapped.Addr(), network)
+		}
+
+		addr = mapped.Addr().Unmap()
+		return nil
+	})
+	if errors.Is(err, udp.ErrNoAnswer) {
+		return addr, fmt.Errorf("no answer after %s", s.timeout)
+	}
+
+	return addr, err
+}
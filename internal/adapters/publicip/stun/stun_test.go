@@ -0,0 +1,282 @@
+package stun
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/adapters/publicip/udp"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type configMock struct {
+	config *stunConfig
+}
+
+func (c configMock) Decode(node string, item any) error {
+	if c.config == nil {
+		return errors.New("node ddns.public-ip-api.stun not found")
+	}
+
+	*item.(*stunConfig) = *c.config
+	return nil
+}
+
+type messageLoggerMock struct {
+	mu            sync.Mutex
+	errorMessages []string
+}
+
+func (l *messageLoggerMock) Error(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errorMessages = append(l.errorMessages, err.Error())
+}
+
+func (l *messageLoggerMock) Debug(msg string) {}
+
+// answer builds the datagrams sent back for the n-th request received from
+// a client, nothing is sent when it returns none.
+type answer func(n int, request []byte, from *net.UDPAddr) [][]byte
+
+// responder is an in-process STUN server listening on address until the
+// test ends.
+func responder(t *testing.T, network, address string, answer answer) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		t.Skipf("unable to listen on %s: %s", address, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, udp.MaxPacketSize)
+		for n := 1; ; n++ {
+			size, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			for _, msg := range answer(n, buf[:size], from.(*net.UDPAddr)) {
+				conn.WriteTo(msg, from)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func requestID(request []byte) transactionID {
+	return transactionID(request[8:20])
+}
+
+// xorMapped encodes the XOR-MAPPED-ADDRESS of from.
+func xorMapped(id transactionID, from *net.UDPAddr) attribute {
+	addr := from.AddrPort()
+	family, ip := addrFamilyIPv4, addr.Addr().Unmap().AsSlice()
+	if len(ip) == 16 {
+		family = addrFamilyIPv6
+	}
+
+	mask := binary.BigEndian.AppendUint32(nil, magicCookie)
+	mask = append(mask, id[:]...)
+
+	value := []byte{0, family}
+	value = binary.BigEndian.AppendUint16(value, addr.Port()^uint16(magicCookie>>16))
+	value = append(value, xor(ip, mask)...)
+
+	return attribute{attrXorMapped, value}
+}
+
+func success(n int, request []byte, from *net.UDPAddr) [][]byte {
+	id := requestID(request)
+	return [][]byte{message(bindingSuccess, id, xorMapped(id, from))}
+}
+
+func silent(n int, request []byte, from *net.UDPAddr) [][]byte {
+	return nil
+}
+
+func TestGetIP(t *testing.T) {
+	testCases := []struct {
+		name           string
+		answers        []answer
+		expectedIP     publicip.IP
+		expectedErrors []string
+	}{
+		{
+			name:       "success",
+			answers:    []answer{success},
+			expectedIP: publicip.IP{V4: stringPointer("127.0.0.1")},
+		},
+		{
+			name: "retransmission",
+			answers: []answer{func(n int, request []byte, from *net.UDPAddr) [][]byte {
+				if n == 1 {
+					return nil
+				}
+
+				return success(n, request, from)
+			}},
+			expectedIP: publicip.IP{V4: stringPointer("127.0.0.1")},
+		},
+		{
+			name: "unrelated-datagrams-skipped",
+			answers: []answer{func(n int, request []byte, from *net.UDPAddr) [][]byte {
+				other := transactionID{1}
+				return append([][]byte{
+					[]byte("garbage"),
+					message(bindingSuccess, other, xorMapped(other, &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1})),
+				}, success(n, request, from)...)
+			}},
+			expectedIP: publicip.IP{V4: stringPointer("127.0.0.1")},
+		},
+		{
+			name: "error-response",
+			answers: []answer{func(n int, request []byte, from *net.UDPAddr) [][]byte {
+				return [][]byte{message(bindingError, requestID(request),
+					attribute{attrErrorCode, append([]byte{0, 0, 5, 0}, "Server Error"...)},
+				)}
+			}},
+			expectedErrors: []string{"stun: server=%s udp4 binding error: binding error 500 Server Error"},
+		},
+		{
+			name:           "no-answer",
+			answers:        []answer{silent},
+			expectedErrors: []string{"stun: server=%s udp4 binding error: no answer after 1.2s"},
+		},
+		{
+			name:       "next-server",
+			answers:    []answer{silent, success},
+			expectedIP: publicip.IP{V4: stringPointer("127.0.0.1")},
+			expectedErrors: []string{
+				"stun: server=%s udp4 binding error: no answer after 1.2s",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		answers := tc.answers
+		expectedIP := tc.expectedIP
+		expectedErrors := tc.expectedErrors
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			servers := []string{}
+			for _, answer := range answers {
+				servers = append(servers, responder(t, "udp4", "127.0.0.1:0", answer))
+			}
+
+			logger := &messageLoggerMock{}
+			getter, err := New(configMock{config: &stunConfig{Servers: servers, Families: []string{"ipv4"}}}, logger)
+			require.NoError(t, err)
+			getter.(*stunGetter).timeout = 1200 * time.Millisecond
+
+			assert.Equal(t, expectedIP, getter.GetIP(context.Background()))
+
+			// the failing server is always the first one.
+			for i := range expectedErrors {
+				expectedErrors[i] = strings.Replace(expectedErrors[i], "%s", servers[0], 1)
+			}
+			assert.Equal(t, expectedErrors, logger.errorMessages)
+		})
+	}
+}
+
+func TestGetIPv6(t *testing.T) {
+	server := responder(t, "udp6", "[::1]:0", success)
+
+	logger := &messageLoggerMock{}
+	getter, err := New(configMock{config: &stunConfig{Servers: []string{server}}}, logger)
+	require.NoError(t, err)
+	getter.(*stunGetter).timeout = time.Second
+
+	ip := getter.GetIP(context.Background())
+
+	assert.Equal(t, stringPointer("::1"), ip.V6)
+	assert.Nil(t, ip.V4)
+	assert.Len(t, logger.errorMessages, 1)
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         *stunConfig
+		expectedConfig stunConfig
+		expectedError  string
+	}{
+		{
+			name:   "defaults",
+			config: &stunConfig{Servers: []string{"stun.l.google.com:19302", "stun.cloudflare.com", "[2001:db8::1]", "2001:db8::2"}},
+			expectedConfig: stunConfig{
+				Servers:     []string{"stun.l.google.com:19302", "stun.cloudflare.com:3478", "[2001:db8::1]:3478", "[2001:db8::2]:3478"},
+				Families:    []string{"ipv4", "ipv6"},
+				TimeoutSecs: 3,
+			},
+		},
+		{
+			name:   "configured",
+			config: &stunConfig{Servers: []string{"stun.home.com:3479"}, Families: []string{"ipv6"}, TimeoutSecs: 5},
+			expectedConfig: stunConfig{
+				Servers:     []string{"stun.home.com:3479"},
+				Families:    []string{"ipv6"},
+				TimeoutSecs: 5,
+			},
+		},
+		{
+			name:          "not-configured",
+			expectedError: "stun: unable to create new stun instance, err:node ddns.public-ip-api.stun not found",
+		},
+		{
+			name:          "missing-servers",
+			config:        &stunConfig{},
+			expectedError: "stun: missing servers",
+		},
+		{
+			name:          "invalid-server",
+			config:        &stunConfig{Servers: []string{"stun.home.com:3478:1"}},
+			expectedError: "stun: invalid server stun.home.com:3478:1, err:address stun.home.com:3478:1: too many colons in address",
+		},
+		{
+			name:          "unknown-family",
+			config:        &stunConfig{Servers: []string{"stun.home.com"}, Families: []string{"inet"}},
+			expectedError: "stun: unknown address family inet",
+		},
+		{
+			name:          "invalid-timeout",
+			config:        &stunConfig{Servers: []string{"stun.home.com"}, TimeoutSecs: -1},
+			expectedError: "stun: invalid timeout -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		config := tc.config
+		expectedConfig := tc.expectedConfig
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			getter, err := New(configMock{config: config}, &messageLoggerMock{})
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedConfig, getter.(*stunGetter).config)
+		})
+	}
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
@@ -0,0 +1,125 @@
+// Package udp holds the request and answer exchange over datagrams shared by
+// the public ip sources talking to STUN servers and LAN gateways.
+package udp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+)
+
+// MaxPacketSize is the largest datagram read, answers past it are truncated.
+const MaxPacketSize int = 1500
+
+var (
+	// ErrUnrelated is returned by accept functions for datagrams that aren't
+	// the answer to the request sent, they're dropped while waiting for it.
+	ErrUnrelated = errors.New("unrelated message")
+
+	// ErrNoAnswer is returned when the context expires before an answer is
+	// accepted.
+	ErrNoAnswer = errors.New("no answer")
+)
+
+type deadliner interface {
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+}
+
+// Exchange sends request over the connected conn, it's retransmitted with a
+// timeout starting at rto and doubled every time until accept takes one of
+// the datagrams read or ctx expires.
+func Exchange(ctx context.Context, conn net.Conn, request []byte, rto time.Duration, accept func(msg []byte) error) error {
+	write := func() error {
+		_, err := conn.Write(request)
+		return err
+	}
+
+	read := func(buf []byte) (int, net.Addr, error) {
+		n, err := conn.Read(buf)
+		return n, conn.RemoteAddr(), err
+	}
+
+	return exchange(ctx, conn, rto, write, read, func(msg []byte, _ net.Addr) error { return accept(msg) })
+}
+
+// ExchangeTo is Exchange for unconnected sockets, request is sent to to and
+// accept is told where every datagram read comes from.
+func ExchangeTo(ctx context.Context, conn net.PacketConn, to net.Addr, request []byte, rto time.Duration, accept func(msg []byte, from net.Addr) error) error {
+	write := func() error {
+		_, err := conn.WriteTo(request, to)
+		return err
+	}
+
+	return exchange(ctx, conn, rto, write, conn.ReadFrom, accept)
+}
+
+func exchange(ctx context.Context, conn deadliner, rto time.Duration, write func() error, read func(buf []byte) (int, net.Addr, error), accept func(msg []byte, from net.Addr) error) error {
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	deadline, _ := ctx.Deadline()
+	buf := make([]byte, MaxPacketSize)
+	for ; ; rto *= 2 {
+		if err := write(); errors.Is(err, os.ErrDeadlineExceeded) {
+			return ErrNoAnswer
+		} else if err != nil {
+			return err
+		}
+
+		if err := conn.SetReadDeadline(earliest(time.Now().Add(rto), deadline)); err != nil {
+			return err
+		}
+
+		for {
+			n, from, err := read(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				if expired(ctx, deadline) {
+					return ErrNoAnswer
+				}
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := accept(buf[:n], from); !errors.Is(err, ErrUnrelated) {
+				return err
+			}
+		}
+	}
+}
+
+// WithDefaultPort adds port to addresses given as a bare host name or ip
+// address.
+func WithDefaultPort(address, port string) (string, error) {
+	if ip, err := netip.ParseAddr(strings.Trim(address, "[]")); err == nil {
+		return net.JoinHostPort(ip.String(), port), nil
+	}
+
+	_, _, err := net.SplitHostPort(address)
+	if addrErr := (*net.AddrError)(nil); errors.As(err, &addrErr) && addrErr.Err == "missing port in address" {
+		return net.JoinHostPort(address, port), nil
+	}
+
+	return address, err
+}
+
+// expired tells whether the deadline of ctx passed, read deadlines set to it
+// may fire before ctx is done.
+func expired(ctx context.Context, deadline time.Time) bool {
+	return ctx.Err() != nil || !time.Now().Before(deadline)
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
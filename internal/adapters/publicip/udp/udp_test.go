@@ -0,0 +1,219 @@
+package udp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answer builds the datagrams sent back for the n-th request received,
+// nothing is sent when it returns none.
+type answer func(n int, request []byte) [][]byte
+
+// udpServer is an in-process peer listening on the loopback until the test
+// ends.
+func udpServer(t *testing.T, answer answer) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, MaxPacketSize)
+		for n := 1; ; n++ {
+			size, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			for _, msg := range answer(n, append([]byte{}, buf[:size]...)) {
+				conn.WriteTo(msg, from)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func accept(msg []byte) error {
+	switch string(msg) {
+	case "answer":
+		return nil
+	case "error":
+		return errors.New("invalid answer")
+	default:
+		return ErrUnrelated
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name          string
+		answer        answer
+		timeout       time.Duration
+		expectedError error
+		expectedCalls int
+	}{
+		{
+			name: "answered",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{[]byte("answer")}
+			},
+			timeout:       time.Second,
+			expectedCalls: 1,
+		},
+		{
+			name: "retransmitted",
+			answer: func(n int, request []byte) [][]byte {
+				if n < 2 {
+					return nil
+				}
+
+				return [][]byte{[]byte("answer")}
+			},
+			timeout:       time.Second,
+			expectedCalls: 1,
+		},
+		{
+			name: "unrelated-dropped",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{[]byte("noise"), []byte("answer")}
+			},
+			timeout:       time.Second,
+			expectedCalls: 2,
+		},
+		{
+			name: "accept-error",
+			answer: func(n int, request []byte) [][]byte {
+				return [][]byte{[]byte("error")}
+			},
+			timeout:       time.Second,
+			expectedError: errors.New("invalid answer"),
+			expectedCalls: 1,
+		},
+		{
+			name: "no-answer",
+			answer: func(n int, request []byte) [][]byte {
+				return nil
+			},
+			timeout:       300 * time.Millisecond,
+			expectedError: ErrNoAnswer,
+		},
+	}
+
+	for _, tc := range tests {
+		answer := tc.answer
+		timeout := tc.timeout
+		expectedError := tc.expectedError
+		expectedCalls := tc.expectedCalls
+
+		t.Run(tc.name, func(t *testing.T) {
+			address := udpServer(t, answer)
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			conn, err := net.Dial("udp4", address)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			calls := 0
+			err = Exchange(ctx, conn, []byte("request"), 100*time.Millisecond, func(msg []byte) error {
+				calls++
+				return accept(msg)
+			})
+
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedCalls, calls)
+		})
+	}
+}
+
+func TestExchangeTo(t *testing.T) {
+	address := udpServer(t, func(n int, request []byte) [][]byte {
+		return [][]byte{[]byte("answer")}
+	})
+
+	to, err := net.ResolveUDPAddr("udp4", address)
+	require.NoError(t, err)
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	responder := ""
+	err = ExchangeTo(ctx, conn, to, []byte("request"), 100*time.Millisecond, func(msg []byte, from net.Addr) error {
+		responder = from.String()
+		return accept(msg)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, address, responder)
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	tests := []struct {
+		name            string
+		address         string
+		expectedAddress string
+		expectedError   string
+	}{
+		{
+			name:            "ipv4",
+			address:         "192.168.1.1",
+			expectedAddress: "192.168.1.1:5351",
+		},
+		{
+			name:            "ipv6",
+			address:         "fe80::1",
+			expectedAddress: "[fe80::1]:5351",
+		},
+		{
+			name:            "bracketed-ipv6",
+			address:         "[2001:db8::1]",
+			expectedAddress: "[2001:db8::1]:5351",
+		},
+		{
+			name:            "host-name",
+			address:         "stun.example.com",
+			expectedAddress: "stun.example.com:5351",
+		},
+		{
+			name:            "with-port",
+			address:         "stun.example.com:3478",
+			expectedAddress: "stun.example.com:3478",
+		},
+		{
+			name:            "invalid",
+			address:         "stun.example.com:3478:1",
+			expectedAddress: "stun.example.com:3478:1",
+			expectedError:   "address stun.example.com:3478:1: too many colons in address",
+		},
+	}
+
+	for _, tc := range tests {
+		address := tc.address
+		expectedAddress := tc.expectedAddress
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := WithDefaultPort(address, "5351")
+			if expectedError != "" {
+				assert.EqualError(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, expectedAddress, got)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+// SaveCycle stores cycle along with its decisions, cycles older than the
+// audit retention are dropped on the way.
+func (st *store) SaveCycle(ctx context.Context, cycle ddns.Cycle) error {
+	tx, err := st.driver.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, insertCycle,
+		cycle.StartTime.UTC().Format(time.RFC3339),
+		cycle.Duration.Milliseconds(),
+		cycle.Forced,
+		cycle.IP.Source,
+		valueOrEmpty(cycle.IP.V4),
+		valueOrEmpty(cycle.IP.V6),
+		valueOrEmpty(cycle.PreviousIP.V4),
+		valueOrEmpty(cycle.PreviousIP.V6),
+		cycle.Error,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, decision := range cycle.Decisions {
+		if _, err = tx.ExecContext(ctx, insertDecision,
+			id,
+			decision.Provider,
+			decision.Zone,
+			decision.Record.FQDN,
+			decision.Record.Type,
+			decision.Record.Value,
+			decision.Previous,
+			decision.Reason,
+			decision.Status,
+			decision.Error,
+		); err != nil {
+			return err
+		}
+	}
+
+	if st.auditRetention > 0 {
+		oldest := cycle.StartTime.Add(-st.auditRetention).UTC().Format(time.RFC3339)
+		for _, statement := range []string{pruneDecisions, pruneCycles} {
+			if _, err = tx.ExecContext(ctx, statement, oldest); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (st *store) Cycles(ctx context.Context, limit int) ([]ddns.Cycle, error) {
+	cycles, err := st.cycles(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions, err := st.decisions(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, cycle := range cycles {
+		cycles[i].Decisions = decisions[cycle.ID]
+	}
+
+	return cycles, nil
+}
+
+func (st *store) cycles(ctx context.Context, limit int) ([]ddns.Cycle, error) {
+	rows, err := st.driver.QueryContext(ctx, lastCycles, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cycles := []ddns.Cycle{}
+	for rows.Next() {
+		cycle := ddns.Cycle{}
+		startTime, durationMs := "", int64(0)
+		ipv4, ipv6, previousIPv4, previousIPv6 := "", "", "", ""
+		if err = rows.Scan(
+			&cycle.ID,
+			&startTime,
+			&durationMs,
+			&cycle.Forced,
+			&cycle.IP.Source,
+			&ipv4,
+			&ipv6,
+			&previousIPv4,
+			&previousIPv6,
+			&cycle.Error,
+		); err != nil {
+			st.logger.Warning(fmt.Sprintf("unable to get values err:%s", err.Error()))
+			continue
+		}
+
+		if cycle.StartTime, err = time.Parse(time.RFC3339, startTime); err != nil {
+			st.logger.Warning(fmt.Sprintf("invalid start time %s for cycle %d", startTime, cycle.ID))
+		}
+
+		cycle.Duration = time.Duration(durationMs) * time.Millisecond
+		cycle.IP.V4, cycle.IP.V6 = emptyToNil(ipv4), emptyToNil(ipv6)
+		cycle.PreviousIP = publicip.IP{V4: emptyToNil(previousIPv4), V6: emptyToNil(previousIPv6)}
+		cycles = append(cycles, cycle)
+	}
+
+	return cycles, rows.Err()
+}
+
+// decisions returns the decisions of the last limit cycles by cycle id.
+func (st *store) decisions(ctx context.Context, limit int) (map[int64][]ddns.Decision, error) {
+	rows, err := st.driver.QueryContext(ctx, lastDecisions, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	decisions := map[int64][]ddns.Decision{}
+	for rows.Next() {
+		decision := ddns.Decision{}
+		cycleID := int64(0)
+		if err = rows.Scan(
+			&cycleID,
+			&decision.Provider,
+			&decision.Zone,
+			&decision.Record.FQDN,
+			&decision.Record.Type,
+			&decision.Record.Value,
+			&decision.Previous,
+			&decision.Reason,
+			&decision.Status,
+			&decision.Error,
+		); err != nil {
+			st.logger.Warning(fmt.Sprintf("unable to get values err:%s", err.Error()))
+			continue
+		}
+
+		decisions[cycleID] = append(decisions[cycleID], decision)
+	}
+
+	return decisions, rows.Err()
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+func emptyToNil(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveCycle(t *testing.T) {
+	ipv4 := "10.0.0.2"
+	cycle := ddns.Cycle{
+		StartTime: time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC),
+		Duration:  1500 * time.Millisecond,
+		IP:        publicip.IP{V4: &ipv4, Source: "ipify"},
+		Decisions: []ddns.Decision{
+			{
+				Provider: "aws/main",
+				Zone:     "home.com.",
+				Record:   dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+				Previous: "10.0.0.1",
+				Reason:   ddns.Changed,
+				Status:   dns.Applied,
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		retention     time.Duration
+		expect        func(sqlmock.Sqlmock)
+		expectedError error
+	}{
+		{
+			name:      "saved-and-pruned",
+			retention: 24 * time.Hour,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(insertCycle)).
+					WithArgs("2025-01-03T10:00:00Z", 1500, false, "ipify", "10.0.0.2", "", "", "", "").
+					WillReturnResult(sqlmock.NewResult(7, 1))
+				mock.ExpectExec(regexp.QuoteMeta(insertDecision)).
+					WithArgs(7, "aws/main", "home.com.", "vpn.home.com.", dns.A, "10.0.0.2", "10.0.0.1", ddns.Changed, dns.Applied, "").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(pruneDecisions)).
+					WithArgs("2025-01-02T10:00:00Z").
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(regexp.QuoteMeta(pruneCycles)).
+					WithArgs("2025-01-02T10:00:00Z").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "kept-forever",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(insertCycle)).
+					WillReturnResult(sqlmock.NewResult(7, 1))
+				mock.ExpectExec(regexp.QuoteMeta(insertDecision)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "decision-error",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(insertCycle)).
+					WillReturnResult(sqlmock.NewResult(7, 1))
+				mock.ExpectExec(regexp.QuoteMeta(insertDecision)).
+					WillReturnError(errors.New("disk I/O error"))
+				mock.ExpectRollback()
+			},
+			expectedError: errors.New("disk I/O error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		retention := tc.retention
+		expect := tc.expect
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			expect(mock)
+
+			st := store{driver: db, logger: &mockLogger{}, auditRetention: retention}
+			err = st.SaveCycle(context.Background(), cycle)
+
+			assert.Equal(t, expectedError, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCycles(t *testing.T) {
+	cnf := configDecoderMock{value: t.TempDir() + "/simple-ddns.db", retentionDays: intPointer(30)}
+	st, err := New(cnf, &mockLogger{})
+	assert.NoError(t, err)
+	defer st.Close()
+
+	oldIP, newIP := "10.0.0.1", "10.0.0.2"
+	now := time.Now().UTC().Truncate(time.Second)
+	expired := ddns.Cycle{StartTime: now.Add(-31 * 24 * time.Hour), Error: "no public ip could be detected"}
+	unchanged := ddns.Cycle{
+		StartTime:  now.Add(-5 * time.Minute),
+		Duration:   20 * time.Millisecond,
+		IP:         publicip.IP{V4: &oldIP, Source: "ipify"},
+		PreviousIP: publicip.IP{V4: &oldIP},
+		Decisions: []ddns.Decision{
+			{Provider: "aws/main", Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: oldIP}, Previous: oldIP, Reason: ddns.InSync},
+			{Provider: "aws/main", Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.AAAA}, Reason: ddns.NoIP},
+		},
+	}
+	changed := ddns.Cycle{
+		StartTime:  now,
+		Duration:   2 * time.Second,
+		Forced:     true,
+		IP:         publicip.IP{V4: &newIP, Source: "quorum(ipify,icanhazip)"},
+		PreviousIP: publicip.IP{V4: &oldIP},
+		Decisions: []ddns.Decision{
+			{
+				Provider: "aws/main",
+				Zone:     "home.com.",
+				Record:   dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: newIP},
+				Previous: oldIP,
+				Reason:   ddns.Forced,
+				Status:   dns.Failed,
+				Error:    "throttled",
+			},
+			{Provider: "digitalocean/home", Record: dns.DomainRecord{FQDN: "www.home.com.", Type: dns.A, Value: newIP}, Previous: oldIP, Reason: ddns.Paused},
+		},
+	}
+
+	for _, cycle := range []ddns.Cycle{expired, unchanged, changed} {
+		assert.NoError(t, st.SaveCycle(context.Background(), cycle))
+	}
+
+	cycles, err := st.Cycles(context.Background(), 10)
+	assert.NoError(t, err)
+
+	changed.ID, unchanged.ID = 3, 2
+	assert.Equal(t, []ddns.Cycle{changed, unchanged}, cycles)
+
+	cycles, err = st.Cycles(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []ddns.Cycle{changed}, cycles)
+}
@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+)
+
+func (st *store) SetPaused(ctx context.Context, record dns.DomainRecord, paused bool) error {
+	if !paused {
+		_, err := st.driver.ExecContext(ctx, resumeRecord, record.FQDN, record.Type)
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := st.driver.ExecContext(ctx, pauseRecord, record.FQDN, record.Type, now)
+
+	return err
+}
+
+func (st *store) PausedRecords(ctx context.Context) ([]dns.DomainRecord, error) {
+	rows, err := st.driver.QueryContext(ctx, pausedRecords)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []dns.DomainRecord{}
+	for rows.Next() {
+		record := dns.DomainRecord{}
+		if err = rows.Scan(&record.FQDN, &record.Type); err != nil {
+			st.logger.Warning(fmt.Sprintf("unable to get values err:%s", err.Error()))
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetPaused(t *testing.T) {
+	record := dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A}
+
+	testCases := []struct {
+		name          string
+		paused        bool
+		expect        func(sqlmock.Sqlmock)
+		expectedError error
+	}{
+		{
+			name:   "pause",
+			paused: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(pauseRecord)).
+					WithArgs("vpn.home.com.", dns.A, AnyISODate{}).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name:   "resume",
+			paused: false,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(resumeRecord)).
+					WithArgs("vpn.home.com.", dns.A).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:   "pause-error",
+			paused: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(pauseRecord)).
+					WithArgs("vpn.home.com.", dns.A, AnyISODate{}).
+					WillReturnError(errors.New("database is locked"))
+			},
+			expectedError: errors.New("database is locked"),
+		},
+	}
+
+	for _, tc := range testCases {
+		paused := tc.paused
+		expect := tc.expect
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			expect(mock)
+
+			st := store{driver: db, logger: &mockLogger{}}
+			err = st.SetPaused(context.Background(), record, paused)
+
+			assert.Equal(t, expectedError, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPausedRecords(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(pausedRecords)).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"fqdn", "register_type"}).
+				AddRow("vpn.home.com.", "A").
+				AddRow("vpn.home.com.", "AAAA"),
+		)
+
+	st := store{driver: db, logger: &mockLogger{}}
+	records, err := st.PausedRecords(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A},
+		{FQDN: "vpn.home.com.", Type: dns.AAAA},
+	}, records)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
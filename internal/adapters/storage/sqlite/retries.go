@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+func (st *store) SavePendingRetry(ctx context.Context, retry ddns.PendingRetry) error {
+	_, err := st.driver.ExecContext(ctx, upsertRetry,
+		retry.Provider,
+		retry.Record.FQDN,
+		retry.Record.Type,
+		retry.Record.Value,
+		retry.Attempts,
+		retry.NextAttempt.UTC().Format(time.RFC3339),
+		retry.LastError,
+	)
+
+	return err
+}
+
+func (st *store) DeletePendingRetry(ctx context.Context, provider string, record dns.DomainRecord) error {
+	_, err := st.driver.ExecContext(ctx, deleteRetry, provider, record.FQDN, record.Type)
+	return err
+}
+
+func (st *store) PendingRetries(ctx context.Context, provider string) ([]ddns.PendingRetry, error) {
+	rows, err := st.driver.QueryContext(ctx, pendingRetries, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	retries := []ddns.PendingRetry{}
+	for rows.Next() {
+		retry := ddns.PendingRetry{Provider: provider}
+		nextAttempt := ""
+		if err = rows.Scan(
+			&retry.Record.FQDN,
+			&retry.Record.Type,
+			&retry.Record.Value,
+			&retry.Attempts,
+			&nextAttempt,
+			&retry.LastError,
+		); err != nil {
+			st.logger.Warning(fmt.Sprintf("unable to get values err:%s", err.Error()))
+			continue
+		}
+
+		if retry.NextAttempt, err = time.Parse(time.RFC3339, nextAttempt); err != nil {
+			st.logger.Warning(fmt.Sprintf("invalid next attempt %s for %s", nextAttempt, retry.Record.FQDN))
+		}
+
+		retries = append(retries, retry)
+	}
+
+	return retries, rows.Err()
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavePendingRetry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(upsertRetry)).
+		WithArgs("aws/main", "vpn.home.com.", dns.A, "10.0.0.1", 3, "2025-01-01T10:00:00Z", "throttled").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	st := store{driver: db, logger: &mockLogger{}}
+	err = st.SavePendingRetry(context.Background(), ddns.PendingRetry{
+		Provider:    "aws/main",
+		Record:      dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+		Attempts:    3,
+		NextAttempt: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+		LastError:   "throttled",
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeletePendingRetry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(deleteRetry)).
+		WithArgs("aws/main", "vpn.home.com.", dns.A).
+		WillReturnError(errors.New("database is locked"))
+
+	st := store{driver: db, logger: &mockLogger{}}
+	err = st.DeletePendingRetry(context.Background(), "aws/main", dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A})
+
+	assert.Equal(t, errors.New("database is locked"), err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPendingRetries(t *testing.T) {
+	testCases := []struct {
+		name            string
+		createMock      func(*testing.T) (*sql.DB, sqlmock.Sqlmock)
+		expectedRetries []ddns.PendingRetry
+		expectedError   error
+	}{
+		{
+			name: "query-error",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				mock.ExpectQuery(regexp.QuoteMeta(pendingRetries)).
+					WithArgs("aws/main").
+					WillReturnError(errors.New("query error"))
+
+				return db, mock
+			},
+			expectedRetries: nil,
+			expectedError:   errors.New("query error"),
+		},
+		{
+			name: "pending-retries-ok",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				mock.ExpectQuery(regexp.QuoteMeta(pendingRetries)).
+					WithArgs("aws/main").
+					WillReturnRows(
+						sqlmock.NewRows([]string{"fqdn", "register_type", "ip", "attempts", "next_attempt", "last_error"}).AddRow(
+							"vpn.home.com.",
+							"A",
+							"10.0.0.1",
+							2,
+							"2025-01-01T10:00:00Z",
+							"throttled",
+						),
+					)
+
+				return db, mock
+			},
+			expectedRetries: []ddns.PendingRetry{
+				{
+					Provider:    "aws/main",
+					Record:      dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+					Attempts:    2,
+					NextAttempt: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+					LastError:   "throttled",
+				},
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		db, dbMock := tc.createMock(t)
+		st := store{
+			driver: db,
+			logger: &mockLogger{},
+		}
+		expectedRetries := tc.expectedRetries
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			retries, err := st.PendingRetries(context.Background(), "aws/main")
+
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedRetries, retries)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+
+		db.Close()
+	}
+}
@@ -3,20 +3,25 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"sync"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
 	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+
+	_ "modernc.org/sqlite"
 )
 
 const (
-	databasePath string = "ddns.storage.sqlite.db"
-)
+	databasePath       string = "ddns.storage.sqlite.db"
+	auditRetentionPath string = "ddns.storage.sqlite.audit-retention-days"
+	driverName         string = "sqlite"
 
-var (
-	once sync.Once
+	defaultAuditRetentionDays int = 90
 )
 
 type configDecoder interface {
@@ -30,14 +35,19 @@ type messageLogger interface {
 
 type sqlDriver interface {
 	Exec(query string, args ...any) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	PingContext(ctx context.Context) error
 	Close() error
 }
 
 type store struct {
 	driver sqlDriver
 	logger messageLogger
+
+	// auditRetention is how long sync cycles are kept, forever when zero.
+	auditRetention time.Duration
 }
 
 func New(cnf configDecoder, logger messageLogger) (ddns.Controller, error) {
@@ -47,21 +57,27 @@ func New(cnf configDecoder, logger messageLogger) (ddns.Controller, error) {
 	if err != nil {
 		return nil, err
 	}
+	dbPath = strings.TrimSpace(dbPath)
 
-	st := store{}
-	once.Do(func() {
-		db := &sql.DB{}
-		db, err = sql.Open("sqlite3", dbPath)
-		if err == nil {
-			st.driver = db
-			st.logger = logger
-		}
-	})
+	retentionDays, err := auditRetentionDays(cnf)
+	if err != nil {
+		return nil, err
+	}
 
+	db, err := sql.Open(driverName, dbPath)
 	if err != nil {
 		return nil, err
 	}
 
+	// sqlite allows a single writer, serialize access instead of failing
+	// with SQLITE_BUSY when providers persist concurrently.
+	db.SetMaxOpenConns(1)
+	st := store{
+		driver:         db,
+		logger:         logger,
+		auditRetention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+
 	if err = st.createTable(); err != nil {
 		return nil, err
 	}
@@ -69,8 +85,52 @@ func New(cnf configDecoder, logger messageLogger) (ddns.Controller, error) {
 	return &st, nil
 }
 
+func ValidateConfig(cnf configDecoder) error {
+	dbPath := ""
+	if err := cnf.Decode(databasePath, &dbPath); err != nil {
+		return err
+	}
+
+	dbPath = strings.TrimSpace(dbPath)
+	if dbPath == "" {
+		return errors.New("sqlite: empty database path")
+	}
+
+	if _, err := os.Stat(filepath.Dir(dbPath)); err != nil {
+		return fmt.Errorf("sqlite: invalid database directory, err:%w", err)
+	}
+
+	_, err := auditRetentionDays(cnf)
+	return err
+}
+
+// auditRetentionDays returns how many days of sync cycles are kept, 90 when
+// not configured and every cycle when set to 0.
+func auditRetentionDays(cnf configDecoder) (int, error) {
+	days := defaultAuditRetentionDays
+	if err := cnf.Decode(auditRetentionPath, &days); err == nil && days < 0 {
+		return 0, fmt.Errorf("sqlite: invalid audit retention %d", days)
+	}
+
+	return days, nil
+}
+
 func (st *store) createTable() error {
-	if _, err := st.driver.Exec(createTable); err != nil {
+	for _, statement := range []string{
+		createTable,
+		createRetriesTable,
+		createPausedTable,
+		createCyclesTable,
+		createDecisionsTable,
+		createDecisionsIndex,
+	} {
+		if _, err := st.driver.Exec(statement); err != nil {
+			st.driver.Close()
+			return err
+		}
+	}
+
+	if err := st.migrateProvider(); err != nil {
 		st.driver.Close()
 		return err
 	}
@@ -78,7 +138,42 @@ func (st *store) createTable() error {
 	return nil
 }
 
-func (st *store) UpdateRecord(ctx context.Context, record dns.DomainRecord) error {
+// migrateProvider adds the provider column to ddns_domains when the database
+// was created without it.
+func (st *store) migrateProvider() error {
+	rows, err := st.driver.QueryContext(context.Background(), providerColumn)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns := 0
+	for rows.Next() {
+		if err = rows.Scan(&columns); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil || columns > 0 {
+		return err
+	}
+
+	_, err = st.driver.Exec(addProviderColumn)
+	return err
+}
+
+func (st *store) Ping(ctx context.Context) error {
+	return st.driver.PingContext(ctx)
+}
+
+func (st *store) Close() error {
+	return st.driver.Close()
+}
+
+// UpdateRecord stores the value record has on provider, the value stored for
+// it before on that provider, or before records were stored per provider,
+// stops being active.
+func (st *store) UpdateRecord(ctx context.Context, provider string, record dns.DomainRecord) error {
 	tx, err := st.driver.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 	})
@@ -88,17 +183,16 @@ func (st *store) UpdateRecord(ctx context.Context, record dns.DomainRecord) erro
 
 	defer tx.Rollback()
 
-	if _, err = tx.ExecContext(ctx, deactivateRecord, record.FQDN, record.Type); err != nil {
+	if _, err = tx.ExecContext(ctx, deactivateRecord, provider, record.FQDN, record.Type); err != nil {
 		return err
 	}
 
-	if _, err = tx.ExecContext(ctx, insertRecord, record.FQDN, "now()", record.Type, record.Value, 1); err != nil {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err = tx.ExecContext(ctx, insertRecord, provider, record.FQDN, now, record.Type, record.Value, 1); err != nil {
 		return err
 	}
 
-	tx.Commit()
-
-	return nil
+	return tx.Commit()
 }
 
 func (st *store) GetRecords(ctx context.Context) ([]dns.DomainRecord, error) {
@@ -125,7 +219,33 @@ func (st *store) GetRecords(ctx context.Context) ([]dns.DomainRecord, error) {
 	return records, nil
 }
 
-func (st *store) InitRecords(ctx context.Context, records []dns.DomainRecord) error {
+func (st *store) ActiveRecords(ctx context.Context) ([]ddns.HistoryEntry, error) {
+	rows, err := st.driver.QueryContext(ctx, activeRecords)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []ddns.HistoryEntry{}
+	for rows.Next() {
+		entry := ddns.HistoryEntry{Active: true}
+		updateTime := ""
+		if err = rows.Scan(&entry.Provider, &entry.Record.FQDN, &updateTime, &entry.Record.Type, &entry.Record.Value); err != nil {
+			st.logger.Warning(fmt.Sprintf("unable to get values err:%s", err.Error()))
+			continue
+		}
+
+		if entry.UpdateTime, err = time.Parse(time.RFC3339, updateTime); err != nil {
+			st.logger.Warning(fmt.Sprintf("invalid update time %s for %s", updateTime, entry.Record.FQDN))
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (st *store) InitRecords(ctx context.Context, provider string, records []dns.DomainRecord) error {
 	tx, err := st.driver.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 	})
@@ -138,12 +258,36 @@ func (st *store) InitRecords(ctx context.Context, records []dns.DomainRecord) er
 
 	for _, record := range records {
 		now := time.Now().UTC().Format(time.RFC3339)
-		if _, err = tx.ExecContext(ctx, insertRecord, record.FQDN, now, record.Value, record.Type, 1); err != nil {
+		if _, err = tx.ExecContext(ctx, insertRecord, provider, record.FQDN, now, record.Type, record.Value, 1); err != nil {
 			return err
 		}
 	}
 
-	tx.Commit()
+	return tx.Commit()
+}
 
-	return nil
+func (st *store) History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error) {
+	rows, err := st.driver.QueryContext(ctx, recordHistory, fqdn, fqdn, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []ddns.HistoryEntry{}
+	for rows.Next() {
+		entry := ddns.HistoryEntry{}
+		updateTime := ""
+		if err = rows.Scan(&entry.Provider, &entry.Record.FQDN, &updateTime, &entry.Record.Type, &entry.Record.Value, &entry.Active); err != nil {
+			st.logger.Warning(fmt.Sprintf("unable to get values err:%s", err.Error()))
+			continue
+		}
+
+		if entry.UpdateTime, err = time.Parse(time.RFC3339, updateTime); err != nil {
+			st.logger.Warning(fmt.Sprintf("invalid update time %s for %s", updateTime, entry.Record.FQDN))
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
 }
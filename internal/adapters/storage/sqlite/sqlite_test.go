@@ -5,11 +5,14 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -46,6 +49,39 @@ func TestCreateTable(t *testing.T) {
 
 				mock.ExpectExec(regexp.QuoteMeta(createTable)).
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(createRetriesTable)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(createPausedTable)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(createCyclesTable)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(createDecisionsTable)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(createDecisionsIndex)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(providerColumn)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+				return db, mock
+			},
+			expectedError: nil,
+		},
+		{
+			name: "create_table_adds_provider_column",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, statement := range []string{createTable, createRetriesTable, createPausedTable, createCyclesTable, createDecisionsTable, createDecisionsIndex} {
+					mock.ExpectExec(regexp.QuoteMeta(statement)).
+						WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(providerColumn)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec(regexp.QuoteMeta(addProviderColumn)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
 
 				return db, mock
 			},
@@ -103,11 +139,12 @@ func TestUpdateRecord(t *testing.T) {
 
 				mock.ExpectBegin()
 				mock.ExpectExec(regexp.QuoteMeta(deactivateRecord)).
-					WithArgs("wwww.google.com", "A").WillReturnResult(sqlmock.NewResult(0, 1))
+					WithArgs("route53", "wwww.google.com", "A").WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).
 					WithArgs(
+						"route53",
 						"wwww.google.com",
-						"now()",
+						AnyISODate{},
 						"A",
 						"192.168.1.10",
 						1,
@@ -122,6 +159,31 @@ func TestUpdateRecord(t *testing.T) {
 				Value: "192.168.1.10",
 			},
 		},
+		{
+			name: "test-record-updated-commit-fail",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(deactivateRecord)).
+					WithArgs("route53", "wwww.google.com", "A").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).
+					WithArgs("route53", "wwww.google.com", AnyISODate{}, "A", "192.168.1.10", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(errors.New("database is locked"))
+
+				return db, mock
+			},
+			recordToUpdate: dns.DomainRecord{
+				FQDN:  "wwww.google.com",
+				Type:  dns.A,
+				Value: "192.168.1.10",
+			},
+			expectedError: errors.New("database is locked"),
+		},
 		{
 			name: "test-record-updated-deactivate-fail",
 			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
@@ -132,7 +194,7 @@ func TestUpdateRecord(t *testing.T) {
 
 				mock.ExpectBegin()
 				mock.ExpectExec(regexp.QuoteMeta(deactivateRecord)).
-					WithArgs("wwww.google.com", "A").WillReturnError(errors.New("unable to deactivate record"))
+					WithArgs("route53", "wwww.google.com", "A").WillReturnError(errors.New("unable to deactivate record"))
 				mock.ExpectRollback()
 
 				return db, mock
@@ -154,11 +216,12 @@ func TestUpdateRecord(t *testing.T) {
 
 				mock.ExpectBegin()
 				mock.ExpectExec(regexp.QuoteMeta(deactivateRecord)).
-					WithArgs("wwww.google.com", "A").WillReturnResult(sqlmock.NewResult(0, 1))
+					WithArgs("route53", "wwww.google.com", "A").WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).
 					WithArgs(
+						"route53",
 						"wwww.google.com",
-						"now()",
+						AnyISODate{},
 						"A",
 						"192.168.1.10",
 						1,
@@ -205,7 +268,7 @@ func TestUpdateRecord(t *testing.T) {
 		expectedError := tc.expectedError
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
-			err := st.UpdateRecord(ctx, record)
+			err := st.UpdateRecord(ctx, "route53", record)
 
 			assert.Equal(t, expectedError, err)
 			assert.NoError(t, dbMock.ExpectationsWereMet())
@@ -356,7 +419,7 @@ func TestInitRecords(t *testing.T) {
 				}
 
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).WithArgs("www.google.com", AnyISODate{}, "192.168.1.1", "A", 1).WillReturnError(errors.New("insert error"))
+				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).WithArgs("route53", "www.google.com", AnyISODate{}, "A", "192.168.1.1", 1).WillReturnError(errors.New("insert error"))
 				mock.ExpectRollback()
 
 				return db, mock
@@ -370,6 +433,29 @@ func TestInitRecords(t *testing.T) {
 			},
 			expectedError: errors.New("insert error"),
 		},
+		{
+			name: "init-records-commit-fail",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).WithArgs("route53", "www.google.com", AnyISODate{}, "A", "192.168.1.1", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit().WillReturnError(errors.New("database is locked"))
+
+				return db, mock
+			},
+			records: []dns.DomainRecord{
+				{
+					FQDN:  "www.google.com",
+					Value: "192.168.1.1",
+					Type:  "A",
+				},
+			},
+			expectedError: errors.New("database is locked"),
+		},
 		{
 			name: "init-records-ok",
 			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
@@ -379,8 +465,8 @@ func TestInitRecords(t *testing.T) {
 				}
 
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).WithArgs("www.google.com", AnyISODate{}, "192.168.1.1", "A", 1).WillReturnResult(sqlmock.NewResult(1, 1))
-				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).WithArgs("www6.google.com", AnyISODate{}, "2001:0db8:0000:0000:0000:8a2e:0370:7334", "AAAA", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).WithArgs("route53", "www.google.com", AnyISODate{}, "A", "192.168.1.1", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(insertRecord)).WithArgs("route53", "www6.google.com", AnyISODate{}, "AAAA", "2001:0db8:0000:0000:0000:8a2e:0370:7334", 1).WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 
 				return db, mock
@@ -411,7 +497,7 @@ func TestInitRecords(t *testing.T) {
 
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
-			err := st.InitRecords(ctx, records)
+			err := st.InitRecords(ctx, "route53", records)
 
 			assert.Equal(t, expectedError, err)
 			assert.NoError(t, dbMock.ExpectationsWereMet())
@@ -419,3 +505,325 @@ func TestInitRecords(t *testing.T) {
 		db.Close()
 	}
 }
+
+func TestHistory(t *testing.T) {
+	testCases := []struct {
+		name            string
+		fqdn            string
+		createMock      func(*testing.T) (*sql.DB, sqlmock.Sqlmock)
+		expectedEntries []ddns.HistoryEntry
+		expectedError   error
+	}{
+		{
+			name: "history-query-error",
+			fqdn: "www.google.com",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				mock.ExpectQuery(regexp.QuoteMeta(recordHistory)).
+					WithArgs("www.google.com", "www.google.com", 10).
+					WillReturnError(errors.New("query error"))
+
+				return db, mock
+			},
+			expectedEntries: nil,
+			expectedError:   errors.New("query error"),
+		},
+		{
+			name: "history-ok",
+			fqdn: "",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				mock.ExpectQuery(regexp.QuoteMeta(recordHistory)).
+					WithArgs("", "", 10).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"provider", "fqdn", "update_time", "register_type", "ip", "active"}).AddRow(
+							"route53",
+							"www.google.com",
+							"2025-01-02T10:00:00Z",
+							"A",
+							"192.168.100.2",
+							true,
+						).AddRow(
+							"",
+							"www.google.com",
+							"2025-01-01T10:00:00Z",
+							"A",
+							"192.168.100.1",
+							false,
+						),
+					)
+
+				return db, mock
+			},
+			expectedEntries: []ddns.HistoryEntry{
+				{
+					Provider: "route53",
+					Record: dns.DomainRecord{
+						FQDN:  "www.google.com",
+						Type:  dns.A,
+						Value: "192.168.100.2",
+					},
+					UpdateTime: time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC),
+					Active:     true,
+				},
+				{
+					Record: dns.DomainRecord{
+						FQDN:  "www.google.com",
+						Type:  dns.A,
+						Value: "192.168.100.1",
+					},
+					UpdateTime: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+					Active:     false,
+				},
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		db, dbMock := tc.createMock(t)
+		st := store{
+			driver: db,
+			logger: &mockLogger{},
+		}
+		fqdn := tc.fqdn
+		expectedEntries := tc.expectedEntries
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			entries, err := st.History(ctx, fqdn, 10)
+
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedEntries, entries)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+
+		db.Close()
+	}
+}
+
+func TestActiveRecords(t *testing.T) {
+	testCases := []struct {
+		name            string
+		createMock      func(*testing.T) (*sql.DB, sqlmock.Sqlmock)
+		expectedEntries []ddns.HistoryEntry
+		expectedError   error
+	}{
+		{
+			name: "active-query-error",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				mock.ExpectQuery(regexp.QuoteMeta(activeRecords)).
+					WillReturnError(errors.New("query error"))
+
+				return db, mock
+			},
+			expectedEntries: nil,
+			expectedError:   errors.New("query error"),
+		},
+		{
+			name: "active-ok",
+			createMock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				db, mock, err := sqlmock.New()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				mock.ExpectQuery(regexp.QuoteMeta(activeRecords)).
+					WillReturnRows(
+						sqlmock.NewRows([]string{"provider", "fqdn", "update_time", "register_type", "ip"}).AddRow(
+							"route53",
+							"www.google.com",
+							"2025-01-02T10:00:00Z",
+							"A",
+							"192.168.100.2",
+						),
+					)
+
+				return db, mock
+			},
+			expectedEntries: []ddns.HistoryEntry{
+				{
+					Provider: "route53",
+					Record: dns.DomainRecord{
+						FQDN:  "www.google.com",
+						Type:  dns.A,
+						Value: "192.168.100.2",
+					},
+					UpdateTime: time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC),
+					Active:     true,
+				},
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		db, dbMock := tc.createMock(t)
+		st := store{
+			driver: db,
+			logger: &mockLogger{},
+		}
+		expectedEntries := tc.expectedEntries
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := st.ActiveRecords(context.Background())
+
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedEntries, entries)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+
+		db.Close()
+	}
+}
+
+type configDecoderMock struct {
+	value         string
+	retentionDays *int
+	err           error
+}
+
+func (c configDecoderMock) Decode(node string, item any) error {
+	if node == auditRetentionPath {
+		if c.retentionDays == nil {
+			return fmt.Errorf("node %s not found", node)
+		}
+
+		*item.(*int) = *c.retentionDays
+		return nil
+	}
+
+	if c.err != nil {
+		return c.err
+	}
+
+	*item.(*string) = c.value
+	return nil
+}
+
+func TestValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name          string
+		config        configDecoderMock
+		expectedError string
+	}{
+		{
+			name:   "valid-path",
+			config: configDecoderMock{value: dir + "/simple-ddns.db\n"},
+		},
+		{
+			name:          "node-not-found",
+			config:        configDecoderMock{err: errors.New("node ddns.storage.sqlite.db not found")},
+			expectedError: "node ddns.storage.sqlite.db not found",
+		},
+		{
+			name:          "empty-path",
+			config:        configDecoderMock{value: "\n"},
+			expectedError: "sqlite: empty database path",
+		},
+		{
+			name:          "missing-directory",
+			config:        configDecoderMock{value: dir + "/missing/simple-ddns.db"},
+			expectedError: "sqlite: invalid database directory",
+		},
+		{
+			name:          "invalid-audit-retention",
+			config:        configDecoderMock{value: dir + "/simple-ddns.db", retentionDays: intPointer(-1)},
+			expectedError: "sqlite: invalid audit retention -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateConfig(cnf)
+
+			if expectedError != "" {
+				assert.ErrorContains(t, err, expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewReopen(t *testing.T) {
+	cnf := configDecoderMock{value: t.TempDir() + "/simple-ddns.db\n"}
+	record := dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"}
+
+	first, err := New(cnf, &mockLogger{})
+	assert.NoError(t, err)
+	assert.NoError(t, first.UpdateRecord(context.Background(), "route53", record))
+	assert.NoError(t, first.Close())
+
+	second, err := New(cnf, &mockLogger{})
+	assert.NoError(t, err)
+	defer second.Close()
+
+	records, err := second.GetRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []dns.DomainRecord{record}, records)
+}
+
+func TestNewAddsProviderColumn(t *testing.T) {
+	path := t.TempDir() + "/simple-ddns.db"
+	legacy := `CREATE TABLE ddns_domains (
+			fqdn TEXT NOT NULL,
+			update_time TEXT NOT NULL,
+			register_type TEXT NOT NULL,
+			ip TEXT NOT NULL,
+			active BOOL NOT NULL
+	)`
+
+	db, err := sql.Open(driverName, path)
+	assert.NoError(t, err)
+	_, err = db.Exec(legacy)
+	assert.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO ddns_domains VALUES('vpn.home.com.', '2025-01-01T10:00:00Z', 'A', '10.0.0.1', true)`)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	st, err := New(configDecoderMock{value: path}, &mockLogger{})
+	assert.NoError(t, err)
+	defer st.Close()
+
+	entries, err := st.ActiveRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "", entries[0].Provider)
+
+	record := dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}
+	assert.NoError(t, st.UpdateRecord(context.Background(), "route53", record))
+	assert.NoError(t, st.UpdateRecord(context.Background(), "digitalocean", record))
+
+	entries, err = st.ActiveRecords(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.NotEqual(t, "", entry.Provider)
+		assert.Equal(t, record, entry.Record)
+	}
+}
+
+func intPointer(value int) *int {
+	return &value
+}
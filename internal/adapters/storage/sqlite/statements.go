@@ -6,18 +6,135 @@ const (
 			update_time TEXT NOT NULL,
 			register_type TEXT NOT NULL,
 			ip TEXT NOT NULL,
-			active BOOL NOT NULL
+			active BOOL NOT NULL,
+			provider TEXT NOT NULL DEFAULT ''
 	)`
 
+	// databases created before records were stored per provider lack the
+	// provider column, their rows are kept with an empty provider.
+	providerColumn string = `SELECT COUNT(*) FROM pragma_table_info('ddns_domains') WHERE name = 'provider'`
+
+	addProviderColumn string = `ALTER TABLE ddns_domains ADD COLUMN provider TEXT NOT NULL DEFAULT ''`
+
 	lastRecords string = `SELECT fqdn, ip, register_type FROM ddns_domains WHERE
 			active = true
 	`
+	activeRecords string = `SELECT provider, fqdn, update_time, register_type, ip FROM ddns_domains WHERE
+			active = true
+	`
+
 	insertRecord string = `INSERT INTO ddns_domains
-			(fqdn, update_time, register_type, ip, active)
-			VALUES(?,?,?,?,?)
+			(provider, fqdn, update_time, register_type, ip, active)
+			VALUES(?,?,?,?,?,?)
 	`
 
 	deactivateRecord string = `UPDATE ddns_domains SET active = false
+			WHERE provider IN (?, '') AND fqdn = ? AND register_type = ?
+	`
+
+	recordHistory string = `SELECT provider, fqdn, update_time, register_type, ip, active FROM ddns_domains
+			WHERE (? = '' OR fqdn = ?)
+			ORDER BY update_time DESC
+			LIMIT ?
+	`
+
+	createRetriesTable string = `CREATE TABLE IF NOT EXISTS ddns_pending_retries (
+			provider TEXT NOT NULL,
+			fqdn TEXT NOT NULL,
+			register_type TEXT NOT NULL,
+			ip TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			next_attempt TEXT NOT NULL,
+			last_error TEXT NOT NULL,
+			PRIMARY KEY (provider, fqdn, register_type)
+	)`
+
+	upsertRetry string = `INSERT OR REPLACE INTO ddns_pending_retries
+			(provider, fqdn, register_type, ip, attempts, next_attempt, last_error)
+			VALUES(?,?,?,?,?,?,?)
+	`
+
+	deleteRetry string = `DELETE FROM ddns_pending_retries
+			WHERE provider = ? AND fqdn = ? AND register_type = ?
+	`
+
+	pendingRetries string = `SELECT fqdn, register_type, ip, attempts, next_attempt, last_error
+			FROM ddns_pending_retries WHERE provider = ?
+	`
+
+	createPausedTable string = `CREATE TABLE IF NOT EXISTS ddns_paused_records (
+			fqdn TEXT NOT NULL,
+			register_type TEXT NOT NULL,
+			paused_at TEXT NOT NULL,
+			PRIMARY KEY (fqdn, register_type)
+	)`
+
+	pauseRecord string = `INSERT OR IGNORE INTO ddns_paused_records
+			(fqdn, register_type, paused_at)
+			VALUES(?,?,?)
+	`
+
+	resumeRecord string = `DELETE FROM ddns_paused_records
 			WHERE fqdn = ? AND register_type = ?
 	`
+
+	pausedRecords string = `SELECT fqdn, register_type FROM ddns_paused_records`
+
+	createCyclesTable string = `CREATE TABLE IF NOT EXISTS ddns_audit_cycles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			start_time TEXT NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			forced BOOL NOT NULL,
+			source TEXT NOT NULL,
+			ipv4 TEXT NOT NULL,
+			ipv6 TEXT NOT NULL,
+			previous_ipv4 TEXT NOT NULL,
+			previous_ipv6 TEXT NOT NULL,
+			error TEXT NOT NULL
+	)`
+
+	createDecisionsTable string = `CREATE TABLE IF NOT EXISTS ddns_audit_decisions (
+			cycle_id INTEGER NOT NULL,
+			provider TEXT NOT NULL,
+			zone TEXT NOT NULL,
+			fqdn TEXT NOT NULL,
+			register_type TEXT NOT NULL,
+			ip TEXT NOT NULL,
+			previous_ip TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT NOT NULL
+	)`
+
+	createDecisionsIndex string = `CREATE INDEX IF NOT EXISTS ddns_audit_decisions_cycle
+			ON ddns_audit_decisions (cycle_id)
+	`
+
+	insertCycle string = `INSERT INTO ddns_audit_cycles
+			(start_time, duration_ms, forced, source, ipv4, ipv6, previous_ipv4, previous_ipv6, error)
+			VALUES(?,?,?,?,?,?,?,?,?)
+	`
+
+	insertDecision string = `INSERT INTO ddns_audit_decisions
+			(cycle_id, provider, zone, fqdn, register_type, ip, previous_ip, reason, status, error)
+			VALUES(?,?,?,?,?,?,?,?,?,?)
+	`
+
+	pruneDecisions string = `DELETE FROM ddns_audit_decisions WHERE cycle_id IN
+			(SELECT id FROM ddns_audit_cycles WHERE start_time < ?)
+	`
+
+	pruneCycles string = `DELETE FROM ddns_audit_cycles WHERE start_time < ?`
+
+	lastCycles string = `SELECT id, start_time, duration_ms, forced, source, ipv4, ipv6, previous_ipv4, previous_ipv6, error
+			FROM ddns_audit_cycles
+			ORDER BY id DESC
+			LIMIT ?
+	`
+
+	lastDecisions string = `SELECT cycle_id, provider, zone, fqdn, register_type, ip, previous_ip, reason, status, error
+			FROM ddns_audit_decisions WHERE cycle_id IN
+			(SELECT id FROM ddns_audit_cycles ORDER BY id DESC LIMIT ?)
+			ORDER BY cycle_id DESC, rowid
+	`
 )
@@ -0,0 +1,121 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+// decide explains what a sync does with every record managed by updater,
+// pushed are the records sent to it and forced tells whether they were
+// selected by a forced sync.
+func decide(updater dns.Updater, ip publicip.IP, stored storedRecords, paused map[string]bool, pushed []dns.DomainRecord, forced bool) []ddns.Decision {
+	selected := make(map[string]bool, len(pushed))
+	for _, record := range pushed {
+		selected[recordKey(record)] = true
+	}
+
+	decisions := make([]ddns.Decision, 0)
+	for _, record := range updater.Records() {
+		key := recordKey(record)
+		previous, _ := stored.get(updater.Name(), record)
+		decision := ddns.Decision{
+			Provider: updater.Name(),
+			Zone:     dns.ZoneOf(updater, record),
+			Record:   record,
+			Previous: previous.Record.Value,
+		}
+
+		desired := desiredRecords(ip, []dns.DomainRecord{record})
+		if len(desired) > 0 {
+			decision.Record = desired[0]
+		}
+
+		switch {
+		case paused[key]:
+			decision.Reason = ddns.Paused
+		case len(desired) == 0:
+			decision.Reason = ddns.NoIP
+		case selected[key] && forced:
+			decision.Reason = ddns.Forced
+		case selected[key]:
+			decision.Reason = ddns.Changed
+		case forced:
+			decision.Reason = ddns.NotSelected
+		default:
+			decision.Reason = ddns.InSync
+		}
+
+		decisions = append(decisions, decision)
+	}
+
+	return decisions
+}
+
+// withOutcomes sets what providers answered for the pushed records. Records
+// that failed without an answer, like updates never started, get the errors
+// of their provider.
+func withOutcomes(decisions []ddns.Decision, reports []ProviderReport) []ddns.Decision {
+	outcomes := make(map[string]dns.UpdateResult)
+	for _, report := range reports {
+		for _, result := range report.Results {
+			outcomes[providerKey(report.Provider, result.Record)] = result
+		}
+
+		for _, record := range report.Failed {
+			key := providerKey(report.Provider, record)
+			if _, ok := outcomes[key]; !ok {
+				outcomes[key] = dns.UpdateResult{
+					Record: record,
+					Status: dns.Failed,
+					Err:    errors.New(strings.Join(errorMessages(report.Errors), "; ")),
+				}
+			}
+		}
+	}
+
+	for i, decision := range decisions {
+		result, ok := outcomes[providerKey(decision.Provider, decision.Record)]
+		if !ok {
+			continue
+		}
+
+		decisions[i].Status = result.Status
+		if result.Zone != "" {
+			decisions[i].Zone = result.Zone
+		}
+
+		if result.Err != nil {
+			decisions[i].Error = result.Err.Error()
+		}
+	}
+
+	return decisions
+}
+
+// audit stores what a sync decided. It runs even when the sync was cancelled,
+// and failing to store it is only logged.
+func (r *reconciler) audit(ctx context.Context, start time.Time, forced bool, report Report, decisions []ddns.Decision, err error) {
+	cycle := ddns.Cycle{
+		StartTime:  start,
+		Duration:   time.Since(start),
+		Forced:     forced,
+		IP:         report.IP,
+		PreviousIP: report.PreviousIP,
+		Decisions:  decisions,
+	}
+
+	if err != nil {
+		cycle.Error = err.Error()
+	}
+
+	if err = r.store.SaveCycle(context.WithoutCancel(ctx), cycle); err != nil {
+		r.logger.Warning(fmt.Sprintf("reconciler: unable to store audit log, err:%s", err))
+	}
+}
@@ -0,0 +1,136 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+func TestSyncAudit(t *testing.T) {
+	managed := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A},
+		{FQDN: "www.home.com.", Type: dns.A},
+		{FQDN: "nas.home.com.", Type: dns.A},
+		{FQDN: "vpn.home.com.", Type: dns.AAAA},
+	}
+	stored := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+		{FQDN: "www.home.com.", Type: dns.A, Value: "10.0.0.2"},
+		{FQDN: "nas.home.com.", Type: dns.A, Value: "10.0.0.1"},
+	}
+	oldIP := publicip.IP{V4: stringPointer("10.0.0.1")}
+	newIP := publicip.IP{V4: stringPointer("10.0.0.2"), Source: "ipify"}
+	paused := []dns.DomainRecord{{FQDN: "nas.home.com.", Type: dns.A}}
+
+	decision := func(fqdn string, recordType dns.RecordType, value, previous string, reason ddns.Reason) ddns.Decision {
+		return ddns.Decision{
+			Provider: "mock/main",
+			Zone:     "Z1",
+			Record:   dns.DomainRecord{FQDN: fqdn, Type: recordType, Value: value},
+			Previous: previous,
+			Reason:   reason,
+		}
+	}
+
+	applied := decision("vpn.home.com.", dns.A, "10.0.0.2", "10.0.0.1", ddns.Changed)
+	applied.Status = dns.Applied
+	rejected := decision("vpn.home.com.", dns.A, "10.0.0.2", "10.0.0.1", ddns.Changed)
+	rejected.Status, rejected.Error = dns.Failed, "rejected"
+	forced := decision("www.home.com.", dns.A, "10.0.0.2", "10.0.0.2", ddns.Forced)
+	forced.Status = dns.Applied
+
+	testCases := []struct {
+		name           string
+		ip             publicip.IP
+		failing        map[string]bool
+		force          string
+		expectedCycles []ddns.Cycle
+	}{
+		{
+			name: "changed",
+			ip:   newIP,
+			expectedCycles: []ddns.Cycle{{
+				IP:         newIP,
+				PreviousIP: oldIP,
+				Decisions: []ddns.Decision{
+					applied,
+					decision("www.home.com.", dns.A, "10.0.0.2", "10.0.0.2", ddns.InSync),
+					decision("nas.home.com.", dns.A, "10.0.0.2", "10.0.0.1", ddns.Paused),
+					decision("vpn.home.com.", dns.AAAA, "", "", ddns.NoIP),
+				},
+			}},
+		},
+		{
+			name:    "failed",
+			ip:      newIP,
+			failing: map[string]bool{"vpn.home.com.": true},
+			expectedCycles: []ddns.Cycle{{
+				IP:         newIP,
+				PreviousIP: oldIP,
+				Decisions: []ddns.Decision{
+					rejected,
+					decision("www.home.com.", dns.A, "10.0.0.2", "10.0.0.2", ddns.InSync),
+					decision("nas.home.com.", dns.A, "10.0.0.2", "10.0.0.1", ddns.Paused),
+					decision("vpn.home.com.", dns.AAAA, "", "", ddns.NoIP),
+				},
+			}},
+		},
+		{
+			name:  "forced",
+			ip:    newIP,
+			force: "www.home.com",
+			expectedCycles: []ddns.Cycle{{
+				Forced:     true,
+				IP:         newIP,
+				PreviousIP: oldIP,
+				Decisions: []ddns.Decision{
+					decision("vpn.home.com.", dns.A, "10.0.0.2", "10.0.0.1", ddns.NotSelected),
+					forced,
+					decision("nas.home.com.", dns.A, "10.0.0.2", "10.0.0.1", ddns.Paused),
+					decision("vpn.home.com.", dns.AAAA, "", "", ddns.NoIP),
+				},
+			}},
+		},
+		{
+			name:           "no-public-ip",
+			expectedCycles: []ddns.Cycle{{Error: "no public ip could be detected"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		ip := tc.ip
+		failing := tc.failing
+		force := tc.force
+		expectedCycles := tc.expectedCycles
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := &storeMock{records: stored, paused: paused}
+			r := reconciler{
+				getter:   getterMock{ip: ip},
+				store:    store,
+				updaters: []dns.Updater{&zonedUpdaterMock{updaterMock{managed: managed, failing: failing}}},
+				logger:   loggerMock{},
+				workers:  1,
+				timeout:  time.Second,
+			}
+
+			if force != "" {
+				_, _ = r.ForceSync(context.Background(), force)
+			} else {
+				_, _ = r.Sync(context.Background())
+			}
+
+			for i := range store.cycles {
+				assert.False(t, store.cycles[i].StartTime.IsZero())
+				store.cycles[i].StartTime, store.cycles[i].Duration = time.Time{}, 0
+			}
+			assert.Equal(t, expectedCycles, store.cycles)
+		})
+	}
+}
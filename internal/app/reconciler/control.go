@@ -0,0 +1,229 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+type ProviderHealth struct {
+	Provider    string
+	Records     int
+	LastUpdate  time.Time
+	LastSuccess time.Time
+	LastError   string
+	Failed      int
+	Duration    time.Duration
+	Healthy     bool
+}
+
+// ForceSync pushes the records named fqdn, or every managed record when fqdn
+// is empty, even if the stored value is up to date. Pending retries for those
+// records are dropped so the push isn't held back by a backoff.
+func (r *reconciler) ForceSync(ctx context.Context, fqdn string) (Report, error) {
+	if fqdn != "" && len(r.managed(fqdn, "")) == 0 {
+		return Report{}, fmt.Errorf("reconciler: %w: %s", ErrUnknownRecord, fqdn)
+	}
+
+	report, err := r.sync(ctx, func(record dns.DomainRecord) bool {
+		return fqdn == "" || sameFQDN(record.FQDN, fqdn)
+	})
+	r.finish(ctx, report, err)
+
+	return report, err
+}
+
+// Pause excludes the records named fqdn from updates until resumed, an empty
+// recordType pauses every type managed for that name.
+func (r *reconciler) Pause(ctx context.Context, fqdn string, recordType dns.RecordType) error {
+	return r.setPaused(ctx, fqdn, recordType, true)
+}
+
+func (r *reconciler) Resume(ctx context.Context, fqdn string, recordType dns.RecordType) error {
+	return r.setPaused(ctx, fqdn, recordType, false)
+}
+
+// History returns the values stored for fqdn, newest first. Managed names
+// are matched like ForceSync does, others are looked up as given so records
+// no longer configured can still be inspected.
+func (r *reconciler) History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error) {
+	if records := r.managed(fqdn, ""); fqdn != "" && len(records) > 0 {
+		fqdn = records[0].FQDN
+	}
+
+	entries, err := r.store.History(ctx, fqdn, limit)
+	if err != nil {
+		return nil, fmt.Errorf("reconciler: unable to read history, err:%w", err)
+	}
+
+	return entries, nil
+}
+
+// Providers returns what the last updates pushed to each provider left
+// behind, providers that had nothing to update yet are reported as healthy.
+func (r *reconciler) Providers() []ProviderHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	providers := make([]ProviderHealth, 0, len(r.updaters))
+	for _, updater := range r.updaters {
+		health, ok := r.providers[updater.Name()]
+		if !ok {
+			health = ProviderHealth{Provider: updater.Name(), Healthy: true}
+		}
+		health.Records = len(updater.Records())
+
+		providers = append(providers, health)
+	}
+
+	return providers
+}
+
+// trackProviders keeps the outcome of the last update pushed to each
+// provider and the last error of every record that failed. Providers whose
+// records were all deferred weren't contacted and keep their health.
+func (r *reconciler) trackProviders(reports []ProviderReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.providers == nil {
+		r.providers = make(map[string]ProviderHealth)
+		r.recordErrors = make(map[string]string)
+	}
+
+	now := time.Now()
+	for _, report := range reports {
+		for _, result := range report.Results {
+			switch result.Status {
+			case dns.Failed:
+				if result.Err == nil {
+					continue
+				}
+				r.recordErrors[providerKey(report.Provider, result.Record)] = result.Err.Error()
+			case dns.Applied, dns.Unchanged:
+				delete(r.recordErrors, providerKey(report.Provider, result.Record))
+			}
+		}
+
+		if deferred(report) {
+			continue
+		}
+
+		health := r.providers[report.Provider]
+		health.Provider = report.Provider
+		health.LastUpdate = now
+		health.Duration = report.Duration
+		health.Failed = len(report.Failed)
+		health.Healthy = len(report.Errors) == 0
+		health.LastError = ""
+
+		if health.Healthy {
+			health.LastSuccess = now
+		} else {
+			health.LastError = report.Errors[len(report.Errors)-1].Error()
+		}
+
+		r.providers[report.Provider] = health
+	}
+}
+
+// deferred tells whether every record of report waits for a scheduled retry.
+func deferred(report ProviderReport) bool {
+	if len(report.Results) == 0 || len(report.Errors) > 0 {
+		return false
+	}
+
+	for _, result := range report.Results {
+		if result.Status != dns.Deferred {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *reconciler) setPaused(ctx context.Context, fqdn string, recordType dns.RecordType, paused bool) error {
+	records := r.managed(fqdn, recordType)
+	if len(records) == 0 {
+		return fmt.Errorf("reconciler: %w: %s", ErrUnknownRecord, strings.TrimSpace(fmt.Sprintf("%s %s", recordType, fqdn)))
+	}
+
+	action := "resumed"
+	if paused {
+		action = "paused"
+	}
+
+	for _, record := range records {
+		if err := r.store.SetPaused(ctx, record, paused); err != nil {
+			return fmt.Errorf("reconciler: unable to update paused state of %s %s, err:%w", record.Type, record.FQDN, err)
+		}
+
+		r.logger.Info(fmt.Sprintf("reconciler: %s %s %s", record.Type, record.FQDN, action))
+	}
+
+	return nil
+}
+
+// managed returns the records named fqdn, optionally of a single type, as
+// configured on the providers.
+func (r *reconciler) managed(fqdn string, recordType dns.RecordType) []dns.DomainRecord {
+	records := make([]dns.DomainRecord, 0)
+	for _, updater := range r.updaters {
+		for _, record := range updater.Records() {
+			if !sameFQDN(record.FQDN, fqdn) || (recordType != "" && record.Type != recordType) {
+				continue
+			}
+
+			record.Value = ""
+			if !slices.Contains(records, record) {
+				records = append(records, record)
+			}
+		}
+	}
+
+	return records
+}
+
+func (r *reconciler) pausedRecords(ctx context.Context) (map[string]bool, error) {
+	records, err := r.store.PausedRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reconciler: unable to read paused records, err:%w", err)
+	}
+
+	paused := make(map[string]bool, len(records))
+	for _, record := range records {
+		paused[recordKey(record)] = true
+	}
+
+	return paused, nil
+}
+
+// forcedRecords selects the desired records force asks for and clears their
+// pending retries on provider.
+func (r *reconciler) forcedRecords(ctx context.Context, provider string, desired []dns.DomainRecord, force func(dns.DomainRecord) bool) []dns.DomainRecord {
+	forced := make([]dns.DomainRecord, 0)
+	for _, record := range desired {
+		if !force(record) {
+			continue
+		}
+
+		if err := r.store.DeletePendingRetry(ctx, provider, record); err != nil {
+			r.logger.Warning(fmt.Sprintf("reconciler: unable to clear pending retry for %s on %s, err:%s", record.FQDN, provider, err))
+		}
+
+		forced = append(forced, record)
+	}
+
+	return forced
+}
+
+// sameFQDN compares names ignoring case and the trailing root dot, which
+// providers differ on.
+func sameFQDN(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
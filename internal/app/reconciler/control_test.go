@@ -0,0 +1,198 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+func TestForceSync(t *testing.T) {
+	managed := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A},
+		{FQDN: "jenkins.home.com.", Type: dns.A},
+	}
+	stored := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+		{FQDN: "jenkins.home.com.", Type: dns.A, Value: "10.0.0.1"},
+	}
+
+	testCases := []struct {
+		name           string
+		fqdn           string
+		paused         []dns.DomainRecord
+		expectedPushed []dns.DomainRecord
+		expectedError  error
+	}{
+		{
+			name: "all",
+			fqdn: "",
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+				{FQDN: "jenkins.home.com.", Type: dns.A, Value: "10.0.0.1"},
+			},
+		},
+		{
+			name: "single-without-root-dot",
+			fqdn: "vpn.home.com",
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+			},
+		},
+		{
+			name:   "paused-skipped",
+			fqdn:   "",
+			paused: []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A}},
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "jenkins.home.com.", Type: dns.A, Value: "10.0.0.1"},
+			},
+		},
+		{
+			name:          "unknown",
+			fqdn:          "www.home.com",
+			expectedError: errors.New("reconciler: record not managed by any provider: www.home.com"),
+		},
+	}
+
+	for _, tc := range testCases {
+		fqdn := tc.fqdn
+		paused := tc.paused
+		expectedPushed := tc.expectedPushed
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			updater := &updaterMock{managed: managed}
+			store := &storeMock{records: stored, paused: paused}
+			r := reconciler{
+				getter:   getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}},
+				store:    store,
+				updaters: []dns.Updater{updater},
+				logger:   loggerMock{},
+				workers:  1,
+				timeout:  time.Second,
+			}
+
+			_, err := r.ForceSync(context.Background(), fqdn)
+
+			if expectedError != nil {
+				assert.EqualError(t, err, expectedError.Error())
+				assert.ErrorIs(t, err, ErrUnknownRecord)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, expectedPushed, updater.pushed)
+			assert.Equal(t, expectedPushed, store.cleared)
+		})
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	managed := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A},
+		{FQDN: "vpn.home.com.", Type: dns.AAAA},
+	}
+
+	store := &storeMock{}
+	r := reconciler{
+		getter:   getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.2")}},
+		store:    store,
+		updaters: []dns.Updater{&updaterMock{managed: managed}},
+		logger:   loggerMock{},
+	}
+	ctx := context.Background()
+
+	assert.NoError(t, r.Pause(ctx, "vpn.home.com", ""))
+	assert.Equal(t, managed, store.paused)
+
+	assert.NoError(t, r.Resume(ctx, "vpn.home.com.", dns.AAAA))
+	assert.Equal(t, managed[:1], store.paused)
+
+	states, err := r.Records(ctx)
+	assert.NoError(t, err)
+	assert.True(t, states[0].Paused)
+	assert.False(t, states[1].Paused)
+
+	err = r.Pause(ctx, "vpn.home.com", dns.RecordType("CNAME"))
+	assert.EqualError(t, err, "reconciler: record not managed by any provider: CNAME vpn.home.com")
+}
+
+func TestProviders(t *testing.T) {
+	r := reconciler{
+		updaters: []dns.Updater{
+			namedUpdaterMock{name: "aws/main", managed: []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A}}},
+			namedUpdaterMock{name: "digital-ocean/main"},
+		},
+	}
+
+	r.trackProviders([]ProviderReport{
+		{
+			Provider: "aws/main",
+			Failed:   []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A}},
+			Errors:   []error{errors.New("throttled")},
+			Duration: time.Second,
+		},
+	})
+
+	providers := r.Providers()
+
+	assert.Len(t, providers, 2)
+	assert.Equal(t, "aws/main", providers[0].Provider)
+	assert.Equal(t, 1, providers[0].Records)
+	assert.Equal(t, 1, providers[0].Failed)
+	assert.Equal(t, "throttled", providers[0].LastError)
+	assert.False(t, providers[0].Healthy)
+	assert.True(t, providers[0].LastSuccess.IsZero())
+	assert.Equal(t, ProviderHealth{Provider: "digital-ocean/main", Healthy: true}, providers[1])
+
+	r.trackProviders([]ProviderReport{
+		{
+			Provider: "aws/main",
+			Results:  []dns.UpdateResult{{Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A}, Status: dns.Deferred}},
+		},
+	})
+
+	assert.Equal(t, providers, r.Providers())
+}
+
+type zonedUpdaterMock struct {
+	updaterMock
+}
+
+func (u *zonedUpdaterMock) Zone(record dns.DomainRecord) string {
+	return "Z1"
+}
+
+func TestRecordsLastError(t *testing.T) {
+	record := dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A}
+	r := reconciler{
+		store:    &storeMock{},
+		updaters: []dns.Updater{&zonedUpdaterMock{updaterMock{managed: []dns.DomainRecord{record}}}},
+		logger:   loggerMock{},
+	}
+	failed := record
+	failed.Value = "10.0.0.2"
+
+	r.trackProviders([]ProviderReport{{
+		Provider: "mock/main",
+		Results:  []dns.UpdateResult{{Record: failed, Status: dns.Failed, Err: errors.New("throttled")}},
+		Errors:   []error{errors.New("throttled")},
+	}})
+
+	states, err := r.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []dns.RecordState{{Record: record, Provider: "mock/main", Zone: "Z1", LastError: "throttled"}}, states)
+
+	r.trackProviders([]ProviderReport{{
+		Provider: "mock/main",
+		Results:  []dns.UpdateResult{{Record: failed, Status: dns.Applied}},
+	}})
+
+	states, err = r.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "", states[0].LastError)
+}
@@ -0,0 +1,85 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+)
+
+type job struct {
+	updater dns.Updater
+	records []dns.DomainRecord
+}
+
+// dispatch pushes every job to its provider using at most r.workers concurrent
+// calls. Reports are returned in the same order as jobs.
+func (r *reconciler) dispatch(ctx context.Context, jobs []job) []ProviderReport {
+	reports := make([]ProviderReport, len(jobs))
+	sem := make(chan struct{}, r.workers)
+
+	wg := sync.WaitGroup{}
+	for i, j := range jobs {
+		wg.Add(1)
+		go func(i int, j job) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+			}
+
+			if err := ctx.Err(); err != nil {
+				reports[i] = ProviderReport{
+					Provider: j.updater.Name(),
+					Failed:   j.records,
+					Errors:   []error{fmt.Errorf("reconciler: update on %s not started, err:%w", j.updater.Name(), err)},
+				}
+				return
+			}
+
+			reports[i] = r.update(ctx, j)
+		}(i, j)
+	}
+	wg.Wait()
+
+	return reports
+}
+
+func (r *reconciler) update(ctx context.Context, j job) ProviderReport {
+	report := ProviderReport{Provider: j.updater.Name()}
+	start := time.Now()
+
+	providerCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	report.Results = j.updater.UpdateDomains(providerCtx, j.records)
+	cancel()
+	report.Duration = time.Since(start)
+
+	for _, result := range report.Results {
+		record := result.Record
+
+		switch result.Status {
+		case dns.Applied, dns.Unchanged:
+			r.logger.Info(fmt.Sprintf("reconciler: %s %s %s to %s on %s", record.Type, record.FQDN, result.Status, record.Value, report.Provider))
+			if err := r.store.UpdateRecord(ctx, report.Provider, record); err != nil {
+				report.Failed = append(report.Failed, record)
+				report.Errors = append(report.Errors, fmt.Errorf("reconciler: unable to store record %s, err:%w", record.FQDN, err))
+				continue
+			}
+
+			report.Updated = append(report.Updated, record)
+		case dns.Failed:
+			report.Failed = append(report.Failed, record)
+			report.Errors = append(report.Errors, fmt.Errorf("reconciler: unable to update %s %s on %s, err:%w", record.Type, record.FQDN, report.Provider, result.Err))
+		case dns.Skipped:
+			r.logger.Debug(fmt.Sprintf("reconciler: %s %s skipped by %s", record.Type, record.FQDN, report.Provider))
+		case dns.Deferred:
+			r.logger.Info(fmt.Sprintf("reconciler: %s %s deferred on %s, %s", record.Type, record.FQDN, report.Provider, result.Err))
+		}
+	}
+
+	return report
+}
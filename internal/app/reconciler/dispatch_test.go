@@ -0,0 +1,129 @@
+package reconciler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+type namedUpdaterMock struct {
+	name    string
+	managed []dns.DomainRecord
+	delay   time.Duration
+	running *int32
+	maxSeen *int32
+}
+
+func (u namedUpdaterMock) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	if u.running != nil {
+		current := atomic.AddInt32(u.running, 1)
+		defer atomic.AddInt32(u.running, -1)
+
+		for {
+			seen := atomic.LoadInt32(u.maxSeen)
+			if current <= seen || atomic.CompareAndSwapInt32(u.maxSeen, seen, current) {
+				break
+			}
+		}
+	}
+
+	select {
+	case <-time.After(u.delay):
+		return resultsFor(records, nil)
+	case <-ctx.Done():
+		return resultsFor(records, ctx.Err())
+	}
+}
+
+func (u namedUpdaterMock) Records() []dns.DomainRecord {
+	return u.managed
+}
+
+func (u namedUpdaterMock) Name() string {
+	return u.name
+}
+
+func TestDispatchSlowProviderDoesNotBlockOthers(t *testing.T) {
+	store := &storeMock{}
+	r := reconciler{
+		store:   store,
+		logger:  loggerMock{},
+		workers: 2,
+		timeout: 50 * time.Millisecond,
+	}
+
+	slowRecord := dns.DomainRecord{FQDN: "slow.home.com.", Type: dns.A, Value: "10.0.0.1"}
+	fastRecord := dns.DomainRecord{FQDN: "fast.home.com.", Type: dns.A, Value: "10.0.0.1"}
+
+	reports := r.dispatch(context.Background(), []job{
+		{updater: namedUpdaterMock{name: "aws/slow", delay: time.Hour}, records: []dns.DomainRecord{slowRecord}},
+		{updater: namedUpdaterMock{name: "aws/fast"}, records: []dns.DomainRecord{fastRecord}},
+	})
+
+	assert.Len(t, reports, 2)
+
+	assert.Equal(t, "aws/slow", reports[0].Provider)
+	assert.Equal(t, []dns.DomainRecord{slowRecord}, reports[0].Failed)
+	assert.Len(t, reports[0].Errors, 1)
+	assert.EqualError(t, reports[0].Errors[0], "reconciler: unable to update A slow.home.com. on aws/slow, err:context deadline exceeded")
+
+	assert.Equal(t, "aws/fast", reports[1].Provider)
+	assert.Equal(t, []dns.DomainRecord{fastRecord}, reports[1].Updated)
+	assert.Empty(t, reports[1].Errors)
+
+	assert.Equal(t, []dns.DomainRecord{fastRecord}, store.updated)
+}
+
+func TestDispatchWorkerLimit(t *testing.T) {
+	running := int32(0)
+	maxSeen := int32(0)
+
+	r := reconciler{
+		store:   &storeMock{},
+		logger:  loggerMock{},
+		workers: 2,
+		timeout: time.Second,
+	}
+
+	jobs := []job{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		jobs = append(jobs, job{
+			updater: namedUpdaterMock{name: name, delay: 10 * time.Millisecond, running: &running, maxSeen: &maxSeen},
+			records: []dns.DomainRecord{{FQDN: name + ".home.com.", Type: dns.A, Value: "10.0.0.1"}},
+		})
+	}
+
+	reports := r.dispatch(context.Background(), jobs)
+
+	assert.Len(t, reports, 5)
+	for i, report := range reports {
+		assert.Equal(t, jobs[i].updater.Name(), report.Provider)
+		assert.Len(t, report.Updated, 1)
+	}
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxSeen), int32(2))
+}
+
+func TestDispatchCancelledCycle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := reconciler{
+		store:   &storeMock{},
+		logger:  loggerMock{},
+		workers: 1,
+		timeout: time.Second,
+	}
+
+	reports := r.dispatch(ctx, []job{
+		{updater: namedUpdaterMock{name: "aws/main"}, records: []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A}}},
+	})
+
+	assert.Len(t, reports, 1)
+	assert.Empty(t, reports[0].Updated)
+	assert.Len(t, reports[0].Failed, 1)
+	assert.EqualError(t, reports[0].Errors[0], "reconciler: update on aws/main not started, err:context canceled")
+}
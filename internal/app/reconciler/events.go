@@ -0,0 +1,172 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type Option func(*reconciler)
+
+// WithNotifier sends the events of every cycle to notifier.
+func WithNotifier(notifier notify.Notifier) Option {
+	return func(r *reconciler) {
+		r.notifier = notifier
+	}
+}
+
+// finish records the outcome of a sync and notifies about it.
+func (r *reconciler) finish(ctx context.Context, report Report, err error) {
+	cycleErr := err
+	if cycleErr == nil {
+		cycleErr = report.Err()
+	}
+
+	if cycleErr != nil {
+		r.logger.Error(cycleErr)
+	}
+
+	r.mu.Lock()
+	previous := r.state.lastIP
+	r.mu.Unlock()
+
+	if previous.V4 == nil && previous.V6 == nil {
+		previous = report.PreviousIP
+	}
+
+	r.track(report.IP, cycleErr)
+	r.trackProviders(report.Providers)
+	r.notify(ctx, r.events(time.Now(), previous, report)...)
+}
+
+// events turns a sync report into notifications. previous is the ip before
+// the cycle, either detected by the last cycle or read from the store.
+func (r *reconciler) events(now time.Time, previous publicip.IP, report Report) []notify.Event {
+	events := make([]notify.Event, 0)
+	if event, ok := r.outageEvent(now, report.IP); ok {
+		events = append(events, event)
+	}
+
+	if changes := ipChanges(previous, report.IP); len(changes) > 0 {
+		events = append(events, notify.Event{
+			Type:     notify.IPChanged,
+			Severity: notify.Info,
+			Time:     now,
+			Summary:  fmt.Sprintf("public ip changed: %s", strings.Join(changes, ", ")),
+			OldIP:    previous,
+			NewIP:    report.IP,
+		})
+	}
+
+	for _, provider := range report.Providers {
+		applied := make([]dns.DomainRecord, 0)
+		for _, result := range provider.Results {
+			if result.Status == dns.Applied {
+				applied = append(applied, result.Record)
+			}
+		}
+
+		if len(applied) > 0 {
+			events = append(events, notify.Event{
+				Type:     notify.RecordsUpdated,
+				Severity: notify.Info,
+				Time:     now,
+				Summary:  fmt.Sprintf("%d records updated on %s", len(applied), provider.Provider),
+				NewIP:    report.IP,
+				Provider: provider.Provider,
+				Records:  applied,
+			})
+		}
+
+		if len(provider.Failed) > 0 {
+			events = append(events, notify.Event{
+				Type:     notify.UpdateFailed,
+				Severity: notify.Error,
+				Time:     now,
+				Summary:  fmt.Sprintf("%d records failed to update on %s", len(provider.Failed), provider.Provider),
+				OldIP:    previous,
+				NewIP:    report.IP,
+				Provider: provider.Provider,
+				Records:  provider.Failed,
+				Errors:   errorMessages(provider.Errors),
+			})
+		}
+	}
+
+	return events
+}
+
+// outageEvent reports once when no public ip source answered for the outage
+// threshold, and once more when one answers again.
+func (r *reconciler) outageEvent(now time.Time, ip publicip.IP) (notify.Event, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ip.V4 == nil && ip.V6 == nil {
+		if r.state.outageSince.IsZero() {
+			r.state.outageSince = now
+		}
+
+		down := now.Sub(r.state.outageSince)
+		if r.state.outageNotified || down < r.outage {
+			return notify.Event{}, false
+		}
+
+		r.state.outageNotified = true
+		return notify.Event{
+			Type:     notify.SourceOutage,
+			Severity: notify.Error,
+			Time:     now,
+			Summary:  fmt.Sprintf("no public ip source answered for %s", down.Round(time.Second)),
+		}, true
+	}
+
+	down := now.Sub(r.state.outageSince)
+	notified := r.state.outageNotified
+	r.state.outageSince = time.Time{}
+	r.state.outageNotified = false
+
+	if !notified {
+		return notify.Event{}, false
+	}
+
+	return notify.Event{
+		Type:     notify.SourceRecovered,
+		Severity: notify.Info,
+		Time:     now,
+		Summary:  fmt.Sprintf("public ip detected by %s again after %s", ip.Source, down.Round(time.Second)),
+		NewIP:    ip,
+	}, true
+}
+
+func (r *reconciler) notify(ctx context.Context, events ...notify.Event) {
+	if r.notifier == nil {
+		return
+	}
+
+	// notifications about a cycle interrupted by a shutdown are still sent.
+	ctx = context.WithoutCancel(ctx)
+	for _, event := range events {
+		if err := r.notifier.Notify(ctx, event); err != nil {
+			r.logger.Warning(fmt.Sprintf("reconciler: unable to notify %s, err:%s", event.Type, err))
+		}
+	}
+}
+
+func ipChanges(previous, current publicip.IP) []string {
+	changes := make([]string, 0)
+	if previous.V4 != nil && current.V4 != nil && *previous.V4 != *current.V4 {
+		changes = append(changes, fmt.Sprintf("%s %s -> %s", dns.A, *previous.V4, *current.V4))
+	}
+
+	if previous.V6 != nil && current.V6 != nil && *previous.V6 != *current.V6 {
+		changes = append(changes, fmt.Sprintf("%s %s -> %s", dns.AAAA, *previous.V6, *current.V6))
+	}
+
+	return changes
+}
@@ -0,0 +1,202 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+type notifierMock struct {
+	events []notify.Event
+	err    error
+}
+
+func (n *notifierMock) Notify(ctx context.Context, event notify.Event) error {
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func (n *notifierMock) Name() string {
+	return "mock"
+}
+
+func TestEvents(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	previous := publicip.IP{V4: stringPointer("10.0.0.1"), V6: stringPointer("fd00::1")}
+	current := publicip.IP{V4: stringPointer("10.0.0.2"), V6: stringPointer("fd00::1")}
+	vpn := dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}
+	www := dns.DomainRecord{FQDN: "www.home.com.", Type: dns.A, Value: "10.0.0.2"}
+
+	testCases := []struct {
+		name           string
+		previous       publicip.IP
+		report         Report
+		expectedEvents []notify.Event
+	}{
+		{
+			name:           "nothing-changed",
+			previous:       previous,
+			report:         Report{IP: previous},
+			expectedEvents: []notify.Event{},
+		},
+		{
+			name:           "first-detection",
+			previous:       publicip.IP{},
+			report:         Report{IP: current},
+			expectedEvents: []notify.Event{},
+		},
+		{
+			name:     "ip-changed",
+			previous: previous,
+			report: Report{IP: current, Providers: []ProviderReport{
+				{
+					Provider: "aws/main",
+					Results: []dns.UpdateResult{
+						{Record: vpn, Status: dns.Applied},
+						{Record: www, Status: dns.Failed, Err: errors.New("throttled")},
+					},
+					Updated: []dns.DomainRecord{vpn},
+					Failed:  []dns.DomainRecord{www},
+					Errors:  []error{errors.New("throttled")},
+				},
+			}},
+			expectedEvents: []notify.Event{
+				{
+					Type:     notify.IPChanged,
+					Severity: notify.Info,
+					Time:     now,
+					Summary:  "public ip changed: A 10.0.0.1 -> 10.0.0.2",
+					OldIP:    previous,
+					NewIP:    current,
+				},
+				{
+					Type:     notify.RecordsUpdated,
+					Severity: notify.Info,
+					Time:     now,
+					Summary:  "1 records updated on aws/main",
+					NewIP:    current,
+					Provider: "aws/main",
+					Records:  []dns.DomainRecord{vpn},
+				},
+				{
+					Type:     notify.UpdateFailed,
+					Severity: notify.Error,
+					Time:     now,
+					Summary:  "1 records failed to update on aws/main",
+					OldIP:    previous,
+					NewIP:    current,
+					Provider: "aws/main",
+					Records:  []dns.DomainRecord{www},
+					Errors:   []string{"throttled"},
+				},
+			},
+		},
+		{
+			name:     "unchanged-records-not-reported",
+			previous: current,
+			report: Report{IP: current, Providers: []ProviderReport{
+				{
+					Provider: "aws/main",
+					Results:  []dns.UpdateResult{{Record: vpn, Status: dns.Unchanged}},
+					Updated:  []dns.DomainRecord{vpn},
+				},
+			}},
+			expectedEvents: []notify.Event{},
+		},
+	}
+
+	for _, tc := range testCases {
+		previous := tc.previous
+		report := tc.report
+		expectedEvents := tc.expectedEvents
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := reconciler{outage: time.Minute}
+
+			assert.Equal(t, expectedEvents, r.events(now, previous, report))
+		})
+	}
+}
+
+func TestOutageEvents(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	ip := publicip.IP{V4: stringPointer("10.0.0.1"), Source: "ipify"}
+	r := reconciler{outage: 10 * time.Minute}
+
+	types := func(events []notify.Event) []notify.EventType {
+		eventTypes := []notify.EventType{}
+		for _, event := range events {
+			eventTypes = append(eventTypes, event.Type)
+		}
+		return eventTypes
+	}
+
+	assert.Empty(t, types(r.events(start, ip, Report{})))
+	assert.Empty(t, types(r.events(start.Add(5*time.Minute), ip, Report{})))
+
+	events := r.events(start.Add(10*time.Minute), ip, Report{})
+	assert.Equal(t, []notify.EventType{notify.SourceOutage}, types(events))
+	assert.Equal(t, "no public ip source answered for 10m0s", events[0].Summary)
+
+	assert.Empty(t, types(r.events(start.Add(15*time.Minute), ip, Report{})))
+
+	events = r.events(start.Add(20*time.Minute), ip, Report{IP: ip})
+	assert.Equal(t, []notify.EventType{notify.SourceRecovered}, types(events))
+	assert.Equal(t, "public ip detected by ipify again after 20m0s", events[0].Summary)
+
+	assert.Empty(t, types(r.events(start.Add(25*time.Minute), ip, Report{IP: ip})))
+}
+
+func TestFinishNotifies(t *testing.T) {
+	notifier := &notifierMock{err: errors.New("connection refused")}
+	r := reconciler{
+		store:    &storeMock{},
+		logger:   loggerMock{},
+		outage:   time.Minute,
+		notifier: notifier,
+	}
+	r.state.lastIP = publicip.IP{V4: stringPointer("10.0.0.1")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.finish(ctx, Report{IP: publicip.IP{V4: stringPointer("10.0.0.2")}, PreviousIP: publicip.IP{V4: stringPointer("10.0.0.2")}}, nil)
+
+	assert.Len(t, notifier.events, 1)
+	assert.Equal(t, notify.IPChanged, notifier.events[0].Type)
+	assert.Equal(t, "10.0.0.2", *r.state.lastIP.V4)
+}
+
+func TestSyncNotifies(t *testing.T) {
+	notifier := &notifierMock{}
+	r := reconciler{
+		getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.2")}},
+		store: &storeMock{entries: []ddns.HistoryEntry{
+			{Provider: "route53", Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"}},
+		}},
+		updaters: []dns.Updater{
+			namedUpdaterMock{name: "route53", managed: []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A}}},
+		},
+		logger:   loggerMock{},
+		workers:  1,
+		timeout:  time.Second,
+		outage:   time.Minute,
+		notifier: notifier,
+	}
+
+	report, err := r.Sync(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, report.Updated, 1)
+
+	assert.Len(t, notifier.events, 2)
+	assert.Equal(t, notify.IPChanged, notifier.events[0].Type)
+	assert.Equal(t, notify.RecordsUpdated, notifier.events[1].Type)
+	assert.Equal(t, "10.0.0.2", *r.state.lastIP.V4)
+}
@@ -0,0 +1,113 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const (
+	checkSync     string = "sync"
+	checkStore    string = "store"
+	checkPublicIP string = "public-ip"
+)
+
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+type Health struct {
+	Ready       bool
+	LastSync    time.Time
+	LastSuccess time.Time
+	Checks      []Check
+}
+
+type cycleState struct {
+	lastSync    time.Time
+	lastSuccess time.Time
+	lastErr     error
+	lastIP      publicip.IP
+	lastIPAt    time.Time
+
+	outageSince    time.Time
+	outageNotified bool
+}
+
+// Health reports whether the daemon is still doing its job: a sync cycle
+// succeeded within the last two check periods, the store answers and a public
+// ip source responded in that window. Only the store is queried, so it's
+// cheap enough to be polled by probes.
+func (r *reconciler) Health(ctx context.Context) Health {
+	r.mu.Lock()
+	state := r.state
+	r.mu.Unlock()
+
+	now := time.Now()
+	maxAge := r.maxSyncAge()
+	health := Health{LastSync: state.lastSync, LastSuccess: state.lastSuccess}
+
+	syncCheck := Check{Name: checkSync, OK: !state.lastSuccess.IsZero() && now.Sub(state.lastSuccess) <= maxAge}
+	switch {
+	case state.lastSync.IsZero():
+		syncCheck.Detail = "no sync cycle completed yet"
+	case state.lastErr != nil:
+		syncCheck.Detail = state.lastErr.Error()
+	case !syncCheck.OK:
+		syncCheck.Detail = fmt.Sprintf("last successful sync at %s", state.lastSuccess.Format(time.RFC3339))
+	}
+
+	storeCheck := Check{Name: checkStore, OK: true}
+	if err := r.store.Ping(ctx); err != nil {
+		storeCheck = Check{Name: checkStore, OK: false, Detail: err.Error()}
+	}
+
+	ipCheck := Check{Name: checkPublicIP, OK: !state.lastIPAt.IsZero() && now.Sub(state.lastIPAt) <= maxAge}
+	if ipCheck.OK {
+		ipCheck.Detail = fmt.Sprintf("detected by %s", state.lastIP.Source)
+	} else {
+		ipCheck.Detail = "no public ip source responded recently"
+	}
+
+	health.Checks = []Check{syncCheck, storeCheck, ipCheck}
+	health.Ready = syncCheck.OK && storeCheck.OK && ipCheck.OK
+
+	return health
+}
+
+// LastIP returns the public ip detected by the last sync cycle and when it
+// was detected, without querying any public ip source.
+func (r *reconciler) LastIP() (publicip.IP, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.state.lastIP, r.state.lastIPAt
+}
+
+// maxSyncAge allows a missed cycle, plus the time a slow one may take,
+// before the daemon is considered stalled.
+func (r *reconciler) maxSyncAge() time.Duration {
+	return 2*r.period + r.timeout
+}
+
+func (r *reconciler) track(ip publicip.IP, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.state.lastSync = now
+	r.state.lastErr = err
+
+	if ip.V4 != nil || ip.V6 != nil {
+		r.state.lastIP = ip
+		r.state.lastIPAt = now
+	}
+
+	if err == nil {
+		r.state.lastSuccess = now
+	}
+}
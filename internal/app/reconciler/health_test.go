@@ -0,0 +1,126 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	now := time.Now()
+	ip := publicip.IP{V4: stringPointer("10.0.0.1"), Source: "ipify"}
+
+	testCases := []struct {
+		name           string
+		state          cycleState
+		store          *storeMock
+		expectedReady  bool
+		expectedChecks []Check
+	}{
+		{
+			name:          "no-cycle-yet",
+			store:         &storeMock{},
+			expectedReady: false,
+			expectedChecks: []Check{
+				{Name: checkSync, OK: false, Detail: "no sync cycle completed yet"},
+				{Name: checkStore, OK: true},
+				{Name: checkPublicIP, OK: false, Detail: "no public ip source responded recently"},
+			},
+		},
+		{
+			name:          "ready",
+			state:         cycleState{lastSync: now, lastSuccess: now, lastIP: ip, lastIPAt: now},
+			store:         &storeMock{},
+			expectedReady: true,
+			expectedChecks: []Check{
+				{Name: checkSync, OK: true},
+				{Name: checkStore, OK: true},
+				{Name: checkPublicIP, OK: true, Detail: "detected by ipify"},
+			},
+		},
+		{
+			name: "last-cycle-failed-recently-succeeded",
+			state: cycleState{
+				lastSync:    now,
+				lastSuccess: now.Add(-time.Minute),
+				lastErr:     errors.New("provider error"),
+				lastIP:      ip,
+				lastIPAt:    now,
+			},
+			store:         &storeMock{},
+			expectedReady: true,
+			expectedChecks: []Check{
+				{Name: checkSync, OK: true, Detail: "provider error"},
+				{Name: checkStore, OK: true},
+				{Name: checkPublicIP, OK: true, Detail: "detected by ipify"},
+			},
+		},
+		{
+			name: "stalled",
+			state: cycleState{
+				lastSync:    now.Add(-time.Hour),
+				lastSuccess: now.Add(-time.Hour),
+				lastIP:      ip,
+				lastIPAt:    now.Add(-time.Hour),
+			},
+			store:         &storeMock{},
+			expectedReady: false,
+			expectedChecks: []Check{
+				{Name: checkSync, OK: false, Detail: "last successful sync at " + now.Add(-time.Hour).Format(time.RFC3339)},
+				{Name: checkStore, OK: true},
+				{Name: checkPublicIP, OK: false, Detail: "no public ip source responded recently"},
+			},
+		},
+		{
+			name:          "store-unreachable",
+			state:         cycleState{lastSync: now, lastSuccess: now, lastIP: ip, lastIPAt: now},
+			store:         &storeMock{pingErr: errors.New("database is locked")},
+			expectedReady: false,
+			expectedChecks: []Check{
+				{Name: checkSync, OK: true},
+				{Name: checkStore, OK: false, Detail: "database is locked"},
+				{Name: checkPublicIP, OK: true, Detail: "detected by ipify"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		state := tc.state
+		store := tc.store
+		expectedReady := tc.expectedReady
+		expectedChecks := tc.expectedChecks
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := reconciler{
+				store:   store,
+				period:  time.Minute,
+				timeout: time.Minute,
+				state:   state,
+			}
+
+			health := r.Health(context.Background())
+
+			assert.Equal(t, expectedReady, health.Ready)
+			assert.Equal(t, expectedChecks, health.Checks)
+		})
+	}
+}
+
+func TestTrack(t *testing.T) {
+	r := reconciler{}
+	ip := publicip.IP{V4: stringPointer("10.0.0.1"), Source: "ipify"}
+
+	r.track(ip, nil)
+	first := r.state
+	assert.False(t, first.lastSuccess.IsZero())
+	assert.Equal(t, ip, first.lastIP)
+
+	r.track(publicip.IP{}, ErrNoPublicIP)
+	assert.Equal(t, first.lastSuccess, r.state.lastSuccess)
+	assert.Equal(t, first.lastIPAt, r.state.lastIPAt)
+	assert.Equal(t, ErrNoPublicIP, r.state.lastErr)
+}
@@ -0,0 +1,92 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/hook"
+)
+
+// WithHooks runs runner before and after records are pushed, and after syncs
+// that failed.
+func WithHooks(runner hook.Runner) Option {
+	return func(r *reconciler) {
+		r.hooks = runner
+	}
+}
+
+// preUpdateRun describes the records about to be pushed.
+func preUpdateRun(report Report, jobs []job) hook.Run {
+	records := make([]dns.DomainRecord, 0)
+	for _, job := range jobs {
+		records = append(records, job.records...)
+	}
+
+	return hook.Run{
+		Stage:   hook.PreUpdate,
+		Result:  hook.Pending,
+		OldIP:   report.PreviousIP,
+		NewIP:   report.IP,
+		Records: records,
+	}
+}
+
+// postUpdateRun describes the records pushed, the result is a failure when
+// any record couldn't be pushed.
+func postUpdateRun(report Report) hook.Run {
+	run := hook.Run{
+		Stage:   hook.PostUpdate,
+		Result:  hook.Success,
+		OldIP:   report.PreviousIP,
+		NewIP:   report.IP,
+		Records: report.Updated,
+		Errors:  errorMessages(report.Errors),
+	}
+
+	if len(report.Failed) > 0 {
+		run.Result = hook.Failure
+	}
+
+	return run
+}
+
+// failureRun describes the records that couldn't be pushed, or why the sync
+// couldn't run at all when err is set.
+func failureRun(report Report, err error) hook.Run {
+	run := hook.Run{
+		Stage:   hook.OnFailure,
+		Result:  hook.Failure,
+		OldIP:   report.PreviousIP,
+		NewIP:   report.IP,
+		Records: report.Failed,
+		Errors:  errorMessages(report.Errors),
+	}
+
+	if err != nil {
+		run.Errors = append(run.Errors, err.Error())
+	}
+
+	return run
+}
+
+// runHook runs the hooks of a stage, failing hooks are logged and never stop
+// a sync.
+func (r *reconciler) runHook(ctx context.Context, run hook.Run) {
+	if r.hooks == nil {
+		return
+	}
+
+	if err := r.hooks.Run(ctx, run); err != nil {
+		r.logger.Warning(fmt.Sprintf("reconciler: %s hooks failed, err:%s", run.Stage, err))
+	}
+}
+
+func errorMessages(errs []error) []string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return messages
+}
@@ -0,0 +1,129 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/hook"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+type runnerMock struct {
+	runs []hook.Run
+	err  error
+}
+
+func (r *runnerMock) Run(ctx context.Context, run hook.Run) error {
+	r.runs = append(r.runs, run)
+	return r.err
+}
+
+func TestSyncHooks(t *testing.T) {
+	managed := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A},
+		{FQDN: "www.home.com.", Type: dns.A},
+	}
+	oldIP := publicip.IP{V4: stringPointer("10.0.0.1")}
+	newIP := publicip.IP{V4: stringPointer("10.0.0.2")}
+	stored := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+		{FQDN: "www.home.com.", Type: dns.A, Value: "10.0.0.1"},
+	}
+	vpn := dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}
+	www := dns.DomainRecord{FQDN: "www.home.com.", Type: dns.A, Value: "10.0.0.2"}
+
+	testCases := []struct {
+		name            string
+		ip              publicip.IP
+		store           *storeMock
+		updater         *updaterMock
+		runner          *runnerMock
+		expectedRuns    []hook.Run
+		expectedUpdated []dns.DomainRecord
+	}{
+		{
+			name:    "nothing-to-push",
+			ip:      oldIP,
+			store:   &storeMock{records: stored},
+			updater: &updaterMock{managed: managed},
+			runner:  &runnerMock{},
+		},
+		{
+			name:    "records-pushed",
+			ip:      newIP,
+			store:   &storeMock{records: stored},
+			updater: &updaterMock{managed: managed},
+			runner:  &runnerMock{},
+			expectedRuns: []hook.Run{
+				{Stage: hook.PreUpdate, Result: hook.Pending, OldIP: oldIP, NewIP: newIP, Records: []dns.DomainRecord{vpn, www}},
+				{Stage: hook.PostUpdate, Result: hook.Success, OldIP: oldIP, NewIP: newIP, Records: []dns.DomainRecord{vpn, www}, Errors: []string{}},
+			},
+			expectedUpdated: []dns.DomainRecord{vpn, www},
+		},
+		{
+			name:    "failing-hooks-dont-stop-updates",
+			ip:      newIP,
+			store:   &storeMock{records: stored},
+			updater: &updaterMock{managed: managed},
+			runner:  &runnerMock{err: errors.New("exit status 1")},
+			expectedRuns: []hook.Run{
+				{Stage: hook.PreUpdate, Result: hook.Pending, OldIP: oldIP, NewIP: newIP, Records: []dns.DomainRecord{vpn, www}},
+				{Stage: hook.PostUpdate, Result: hook.Success, OldIP: oldIP, NewIP: newIP, Records: []dns.DomainRecord{vpn, www}, Errors: []string{}},
+			},
+			expectedUpdated: []dns.DomainRecord{vpn, www},
+		},
+		{
+			name:    "records-failed",
+			ip:      newIP,
+			store:   &storeMock{records: stored},
+			updater: &updaterMock{managed: managed, failing: map[string]bool{"www.home.com.": true}},
+			runner:  &runnerMock{},
+			expectedRuns: []hook.Run{
+				{Stage: hook.PreUpdate, Result: hook.Pending, OldIP: oldIP, NewIP: newIP, Records: []dns.DomainRecord{vpn, www}},
+				{Stage: hook.PostUpdate, Result: hook.Failure, OldIP: oldIP, NewIP: newIP, Records: []dns.DomainRecord{vpn}, Errors: []string{"reconciler: unable to update A www.home.com. on mock/main, err:rejected"}},
+				{Stage: hook.OnFailure, Result: hook.Failure, OldIP: oldIP, NewIP: newIP, Records: []dns.DomainRecord{www}, Errors: []string{"reconciler: unable to update A www.home.com. on mock/main, err:rejected"}},
+			},
+			expectedUpdated: []dns.DomainRecord{vpn},
+		},
+		{
+			name:    "no-public-ip",
+			store:   &storeMock{records: stored},
+			updater: &updaterMock{managed: managed},
+			runner:  &runnerMock{},
+			expectedRuns: []hook.Run{
+				{Stage: hook.OnFailure, Result: hook.Failure, Errors: []string{"no public ip could be detected"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		ip := tc.ip
+		store := tc.store
+		updater := tc.updater
+		runner := tc.runner
+		expectedRuns := tc.expectedRuns
+		expectedUpdated := tc.expectedUpdated
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := reconciler{
+				getter:   getterMock{ip: ip},
+				store:    store,
+				updaters: []dns.Updater{updater},
+				logger:   loggerMock{},
+				workers:  1,
+				timeout:  time.Second,
+			}
+			WithHooks(runner)(&r)
+
+			_, _ = r.Sync(context.Background())
+
+			assert.Equal(t, expectedRuns, runner.runs)
+			assert.Equal(t, expectedUpdated, store.updated)
+		})
+	}
+}
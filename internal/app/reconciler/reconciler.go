@@ -0,0 +1,485 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/hook"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/notify"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+const (
+	checkPeriodPath    string        = "ddns.check-period-mins"
+	updatesPath        string        = "ddns.updates"
+	gracePeriodPath    string        = "ddns.shutdown-grace-secs"
+	outagePath         string        = "ddns.notifications.source-outage-mins"
+	defaultCheckPeriod time.Duration = time.Minute
+	defaultWorkers     int           = 4
+	defaultTimeoutSecs int           = 60
+	defaultGraceSecs   int           = 30
+	defaultOutageMins  int           = 15
+)
+
+var (
+	ErrNoPublicIP    = errors.New("no public ip could be detected")
+	ErrUnknownRecord = errors.New("record not managed by any provider")
+)
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warning(msg string)
+	Error(err error)
+}
+
+type updatesConfig struct {
+	Workers             int `yaml:"workers"`
+	ProviderTimeoutSecs int `yaml:"provider-timeout-secs"`
+}
+
+type ProviderReport struct {
+	Provider string
+	Results  []dns.UpdateResult
+	Updated  []dns.DomainRecord
+	Failed   []dns.DomainRecord
+	Errors   []error
+	Duration time.Duration
+}
+
+type Report struct {
+	IP         publicip.IP
+	PreviousIP publicip.IP
+	Updated    []dns.DomainRecord
+	Failed     []dns.DomainRecord
+	Errors     []error
+	Providers  []ProviderReport
+}
+
+func (r Report) Err() error {
+	return errors.Join(r.Errors...)
+}
+
+type Status struct {
+	IP      publicip.IP
+	Records []dns.RecordState
+}
+
+type reconciler struct {
+	getter   publicip.Getter
+	store    ddns.Controller
+	updaters []dns.Updater
+	logger   messageLogger
+	period   time.Duration
+	workers  int
+	timeout  time.Duration
+	grace    time.Duration
+	outage   time.Duration
+	trigger  chan struct{}
+	notifier notify.Notifier
+	hooks    hook.Runner
+
+	// syncMu keeps forced syncs from racing the scheduled cycle.
+	syncMu sync.Mutex
+
+	mu           sync.Mutex
+	state        cycleState
+	providers    map[string]ProviderHealth
+	recordErrors map[string]string
+}
+
+func New(
+	cnf configDecoder,
+	getter publicip.Getter,
+	store ddns.Controller,
+	updaters []dns.Updater,
+	logger messageLogger,
+	opts ...Option,
+) (*reconciler, error) {
+	period := defaultCheckPeriod
+
+	mins := 0
+	if err := cnf.Decode(checkPeriodPath, &mins); err == nil {
+		if mins <= 0 {
+			return nil, fmt.Errorf("reconciler: invalid check period %d", mins)
+		}
+		period = time.Duration(mins) * time.Minute
+	}
+
+	updates := updatesConfig{}
+	if err := cnf.Decode(updatesPath, &updates); err != nil {
+		updates = updatesConfig{}
+	}
+
+	if updates.Workers < 0 || updates.ProviderTimeoutSecs < 0 {
+		return nil, fmt.Errorf("reconciler: invalid updates config workers=%d provider-timeout-secs=%d", updates.Workers, updates.ProviderTimeoutSecs)
+	}
+
+	if updates.Workers == 0 {
+		updates.Workers = defaultWorkers
+	}
+
+	if updates.ProviderTimeoutSecs == 0 {
+		updates.ProviderTimeoutSecs = defaultTimeoutSecs
+	}
+
+	graceSecs := defaultGraceSecs
+	if err := cnf.Decode(gracePeriodPath, &graceSecs); err == nil && graceSecs < 0 {
+		return nil, fmt.Errorf("reconciler: invalid shutdown grace period %d", graceSecs)
+	}
+
+	outageMins := defaultOutageMins
+	if err := cnf.Decode(outagePath, &outageMins); err == nil && outageMins <= 0 {
+		return nil, fmt.Errorf("reconciler: invalid source outage threshold %d", outageMins)
+	}
+
+	r := &reconciler{
+		getter:   getter,
+		store:    store,
+		updaters: updaters,
+		logger:   logger,
+		period:   period,
+		workers:  updates.Workers,
+		timeout:  time.Duration(updates.ProviderTimeoutSecs) * time.Second,
+		grace:    time.Duration(graceSecs) * time.Second,
+		outage:   time.Duration(outageMins) * time.Minute,
+		trigger:  make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+// Run syncs every check period, or earlier when Trigger is called, until ctx
+// is cancelled. A cycle in flight when that happens is given the shutdown
+// grace period to finish before its own context is cancelled.
+func (r *reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+
+	for {
+		r.cycle(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-r.trigger:
+			r.logger.Info("reconciler: sync triggered")
+		}
+	}
+}
+
+// Trigger asks a running Run loop to start a cycle now, triggers received
+// while a cycle is running are merged into a single extra cycle.
+func (r *reconciler) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Close sends the events held back by the notifier before closing the store.
+func (r *reconciler) Close() error {
+	return errors.Join(notify.Close(r.notifier), r.store.Close())
+}
+
+func (r *reconciler) cycle(ctx context.Context) {
+	cycleCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		r.logger.Info(fmt.Sprintf("reconciler: waiting up to %s for in-flight updates", r.grace))
+		timer := time.NewTimer(r.grace)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+			r.logger.Warning("reconciler: shutdown grace period expired, cancelling in-flight updates")
+			cancel()
+		}
+	}()
+
+	_, _ = r.Sync(cycleCtx)
+}
+
+// Sync runs a single detect, diff, update and persist cycle and notifies about
+// its outcome, whether it's run by Run or on its own. The returned error is
+// only set when the cycle couldn't run at all, provider and storage failures
+// are collected in the report.
+func (r *reconciler) Sync(ctx context.Context) (Report, error) {
+	report, err := r.sync(ctx, nil)
+	r.finish(ctx, report, err)
+
+	return report, err
+}
+
+// sync pushes the records whose stored value differs from the public ip.
+// When force is set, only the records it selects are pushed and they are
+// pushed even if the stored value is already up to date. Paused records are
+// never pushed.
+func (r *reconciler) sync(ctx context.Context, force func(dns.DomainRecord) bool) (Report, error) {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+
+	start := time.Now()
+	report, decisions, err := r.push(ctx, force)
+	if err != nil || len(report.Failed) > 0 {
+		r.runHook(ctx, failureRun(report, err))
+	}
+	r.audit(ctx, start, force != nil, report, decisions, err)
+
+	return report, err
+}
+
+// push runs the pre-update hooks, pushes the changed records and runs the
+// post-update hooks, hooks only run when there's something to push. The
+// returned decisions explain what was done with every managed record.
+func (r *reconciler) push(ctx context.Context, force func(dns.DomainRecord) bool) (Report, []ddns.Decision, error) {
+	report := Report{}
+
+	ip := r.getter.GetIP(ctx)
+	if ip.V4 == nil && ip.V6 == nil {
+		return report, nil, ErrNoPublicIP
+	}
+	report.IP = ip
+	r.logger.Debug(fmt.Sprintf("reconciler: public ip detected by %s", ip.Source))
+
+	entries, err := r.store.ActiveRecords(ctx)
+	if err != nil {
+		return report, nil, fmt.Errorf("reconciler: unable to read stored records, err:%w", err)
+	}
+	report.PreviousIP = storedIP(entries)
+	stored := newStoredRecords(entries)
+
+	paused, err := r.pausedRecords(ctx)
+	if err != nil {
+		return report, nil, err
+	}
+
+	jobs := make([]job, 0, len(r.updaters))
+	decisions := make([]ddns.Decision, 0)
+	for _, updater := range r.updaters {
+		desired := desiredRecords(ip, activeRecords(updater.Records(), paused))
+
+		changed := changedRecords(updater.Name(), desired, stored)
+		if force != nil {
+			changed = r.forcedRecords(ctx, updater.Name(), desired, force)
+		}
+		decisions = append(decisions, decide(updater, ip, stored, paused, changed, force != nil)...)
+
+		if len(changed) == 0 {
+			continue
+		}
+
+		jobs = append(jobs, job{updater: updater, records: changed})
+	}
+
+	if len(jobs) == 0 {
+		return report, decisions, nil
+	}
+
+	r.runHook(ctx, preUpdateRun(report, jobs))
+	providerReports := r.dispatch(ctx, jobs)
+	for _, providerReport := range providerReports {
+		report.Updated = append(report.Updated, providerReport.Updated...)
+		report.Failed = append(report.Failed, providerReport.Failed...)
+		report.Errors = append(report.Errors, providerReport.Errors...)
+		report.Providers = append(report.Providers, providerReport)
+	}
+	r.runHook(ctx, postUpdateRun(report))
+
+	return report, withOutcomes(decisions, providerReports), nil
+}
+
+// Records returns every managed record along with the value currently stored
+// for it, without querying any public ip source.
+func (r *reconciler) Records(ctx context.Context) ([]dns.RecordState, error) {
+	stored, err := r.store.ActiveRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reconciler: unable to read stored records, err:%w", err)
+	}
+
+	paused, err := r.pausedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := newStoredRecords(stored)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states := make([]dns.RecordState, 0)
+	for _, updater := range r.updaters {
+		for _, record := range updater.Records() {
+			entry, _ := current.get(updater.Name(), record)
+			record.Value = entry.Record.Value
+			states = append(states, dns.RecordState{
+				Record:     record,
+				Provider:   updater.Name(),
+				Zone:       dns.ZoneOf(updater, record),
+				UpdateTime: entry.UpdateTime,
+				Paused:     paused[recordKey(record)],
+				LastError:  r.recordErrors[providerKey(updater.Name(), record)],
+			})
+		}
+	}
+
+	return states, nil
+}
+
+// Status detects the public ip and compares every managed record with it.
+func (r *reconciler) Status(ctx context.Context) (Status, error) {
+	return r.status(ctx, r.getter.GetIP(ctx))
+}
+
+// LastStatus compares every managed record with the public ip detected by the
+// last cycle, no public ip source is queried.
+func (r *reconciler) LastStatus(ctx context.Context) (Status, error) {
+	ip, _ := r.LastIP()
+	return r.status(ctx, ip)
+}
+
+func (r *reconciler) status(ctx context.Context, ip publicip.IP) (Status, error) {
+	status := Status{IP: ip}
+
+	states, err := r.Records(ctx)
+	if err != nil {
+		return status, err
+	}
+
+	for i, state := range states {
+		switch {
+		case state.Record.Type == dns.A && status.IP.V4 != nil:
+			state.Desired = *status.IP.V4
+		case state.Record.Type == dns.AAAA && status.IP.V6 != nil:
+			state.Desired = *status.IP.V6
+		}
+
+		state.InSync = state.Desired != "" && state.Desired == state.Record.Value
+		states[i] = state
+	}
+
+	status.Records = states
+	return status, nil
+}
+
+func desiredRecords(ip publicip.IP, managed []dns.DomainRecord) []dns.DomainRecord {
+	desired := make([]dns.DomainRecord, 0, len(managed))
+	for _, record := range managed {
+		switch {
+		case record.Type == dns.A && ip.V4 != nil:
+			record.Value = *ip.V4
+		case record.Type == dns.AAAA && ip.V6 != nil:
+			record.Value = *ip.V6
+		default:
+			continue
+		}
+
+		desired = append(desired, record)
+	}
+
+	return desired
+}
+
+// storedIP returns the addresses stored records point at, the first record
+// of each type is used.
+func storedIP(stored []ddns.HistoryEntry) publicip.IP {
+	ip := publicip.IP{}
+	for _, entry := range stored {
+		record := entry.Record
+		value := record.Value
+		switch {
+		case record.Type == dns.A && ip.V4 == nil:
+			ip.V4 = &value
+		case record.Type == dns.AAAA && ip.V6 == nil:
+			ip.V6 = &value
+		}
+	}
+
+	return ip
+}
+
+// activeRecords drops the paused records from managed.
+func activeRecords(managed []dns.DomainRecord, paused map[string]bool) []dns.DomainRecord {
+	active := make([]dns.DomainRecord, 0, len(managed))
+	for _, record := range managed {
+		if paused[recordKey(record)] {
+			continue
+		}
+
+		active = append(active, record)
+	}
+
+	return active
+}
+
+// changedRecords returns the desired records whose value stored for provider
+// differs.
+func changedRecords(provider string, desired []dns.DomainRecord, stored storedRecords) []dns.DomainRecord {
+	changed := make([]dns.DomainRecord, 0)
+	for _, record := range desired {
+		if entry, ok := stored.get(provider, record); ok && entry.Record.Value == record.Value {
+			continue
+		}
+
+		changed = append(changed, record)
+	}
+
+	return changed
+}
+
+func recordKey(record dns.DomainRecord) string {
+	return fmt.Sprintf("%s/%s", record.Type, record.FQDN)
+}
+
+func providerKey(provider string, record dns.DomainRecord) string {
+	return fmt.Sprintf("%s:%s", provider, recordKey(record))
+}
+
+// storedRecords indexes the active stored values by provider and record, the
+// same record is kept apart on every provider managing it.
+type storedRecords map[string]ddns.HistoryEntry
+
+func newStoredRecords(entries []ddns.HistoryEntry) storedRecords {
+	stored := make(storedRecords, len(entries))
+	for _, entry := range entries {
+		stored[providerKey(entry.Provider, entry.Record)] = entry
+	}
+
+	return stored
+}
+
+// get returns the value stored for record on provider. Values stored before
+// records were kept per provider have none and apply to every provider.
+func (s storedRecords) get(provider string, record dns.DomainRecord) (ddns.HistoryEntry, bool) {
+	if entry, ok := s[providerKey(provider, record)]; ok {
+		return entry, true
+	}
+
+	entry, ok := s[providerKey("", record)]
+	return entry, ok
+}
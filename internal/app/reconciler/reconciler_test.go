@@ -0,0 +1,631 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+	"github.com/stretchr/testify/assert"
+)
+
+type getterMock struct {
+	ip publicip.IP
+}
+
+func (g getterMock) GetIP(ctx context.Context) publicip.IP {
+	return g.ip
+}
+
+type countingGetterMock struct {
+	ip    publicip.IP
+	calls *int32
+}
+
+func (g countingGetterMock) GetIP(ctx context.Context) publicip.IP {
+	atomic.AddInt32(g.calls, 1)
+	return g.ip
+}
+
+type storeMock struct {
+	mu          sync.Mutex
+	records     []dns.DomainRecord
+	entries     []ddns.HistoryEntry
+	getErr      error
+	updateErr   error
+	updated     []dns.DomainRecord
+	initialized []dns.DomainRecord
+	pingErr     error
+	paused      []dns.DomainRecord
+	cleared     []dns.DomainRecord
+	cycles      []ddns.Cycle
+}
+
+func (s *storeMock) UpdateRecord(ctx context.Context, provider string, record dns.DomainRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.updateErr != nil {
+		return s.updateErr
+	}
+
+	s.updated = append(s.updated, record)
+	return nil
+}
+
+func (s *storeMock) GetRecords(ctx context.Context) ([]dns.DomainRecord, error) {
+	return s.records, s.getErr
+}
+
+func (s *storeMock) ActiveRecords(ctx context.Context) ([]ddns.HistoryEntry, error) {
+	entries := slices.Clone(s.entries)
+	for _, record := range s.records {
+		entries = append(entries, ddns.HistoryEntry{Record: record, Active: true})
+	}
+
+	return entries, s.getErr
+}
+
+func (s *storeMock) InitRecords(ctx context.Context, provider string, records []dns.DomainRecord) error {
+	s.initialized = append(s.initialized, records...)
+	return nil
+}
+
+func (s *storeMock) History(ctx context.Context, fqdn string, limit int) ([]ddns.HistoryEntry, error) {
+	return nil, nil
+}
+
+func (s *storeMock) Ping(ctx context.Context) error {
+	return s.pingErr
+}
+
+func (s *storeMock) Close() error {
+	return nil
+}
+
+func (s *storeMock) SavePendingRetry(ctx context.Context, retry ddns.PendingRetry) error {
+	return nil
+}
+
+func (s *storeMock) DeletePendingRetry(ctx context.Context, provider string, record dns.DomainRecord) error {
+	s.cleared = append(s.cleared, record)
+	return nil
+}
+
+func (s *storeMock) PendingRetries(ctx context.Context, provider string) ([]ddns.PendingRetry, error) {
+	return nil, nil
+}
+
+func (s *storeMock) SetPaused(ctx context.Context, record dns.DomainRecord, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused = slices.DeleteFunc(s.paused, func(r dns.DomainRecord) bool { return r == record })
+	if paused {
+		s.paused = append(s.paused, record)
+	}
+
+	return nil
+}
+
+func (s *storeMock) PausedRecords(ctx context.Context) ([]dns.DomainRecord, error) {
+	return s.paused, nil
+}
+
+func (s *storeMock) SaveCycle(ctx context.Context, cycle ddns.Cycle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cycles = append(s.cycles, cycle)
+	return nil
+}
+
+func (s *storeMock) Cycles(ctx context.Context, limit int) ([]ddns.Cycle, error) {
+	return s.cycles, nil
+}
+
+type updaterMock struct {
+	managed  []dns.DomainRecord
+	err      error
+	failing  map[string]bool
+	deferred map[string]bool
+	pushed   []dns.DomainRecord
+}
+
+func (u *updaterMock) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	u.pushed = append(u.pushed, records...)
+
+	results := []dns.UpdateResult{}
+	for _, record := range records {
+		if u.err != nil {
+			results = append(results, dns.UpdateResult{Record: record, Status: dns.Failed, Err: u.err})
+			continue
+		}
+
+		if u.failing[record.FQDN] {
+			results = append(results, dns.UpdateResult{Record: record, Status: dns.Failed, Err: errors.New("rejected")})
+			continue
+		}
+
+		if u.deferred[record.FQDN] {
+			results = append(results, dns.UpdateResult{Record: record, Status: dns.Deferred, Err: errors.New("retry scheduled")})
+			continue
+		}
+
+		results = append(results, dns.UpdateResult{Record: record, Status: dns.Applied})
+	}
+
+	return results
+}
+
+func resultsFor(records []dns.DomainRecord, err error) []dns.UpdateResult {
+	results := []dns.UpdateResult{}
+	for _, record := range records {
+		if err != nil {
+			results = append(results, dns.UpdateResult{Record: record, Status: dns.Failed, Err: err})
+			continue
+		}
+
+		results = append(results, dns.UpdateResult{Record: record, Status: dns.Applied})
+	}
+
+	return results
+}
+
+func (u *updaterMock) Records() []dns.DomainRecord {
+	return u.managed
+}
+
+func (u *updaterMock) Name() string {
+	return "mock/main"
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Info(msg string)    {}
+func (loggerMock) Warning(msg string) {}
+func (loggerMock) Error(err error)    {}
+
+type configMock struct {
+	values map[string]any
+}
+
+func (c configMock) Decode(node string, item any) error {
+	value, ok := c.values[node]
+	if !ok {
+		return errors.New("node not found")
+	}
+
+	reflect.ValueOf(item).Elem().Set(reflect.ValueOf(value))
+	return nil
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name            string
+		config          configMock
+		expectedPeriod  string
+		expectedWorkers int
+		expectedTimeout string
+		expectedError   error
+	}{
+		{
+			name:            "defaults",
+			config:          configMock{},
+			expectedPeriod:  "1m0s",
+			expectedWorkers: defaultWorkers,
+			expectedTimeout: "1m0s",
+		},
+		{
+			name: "configured",
+			config: configMock{values: map[string]any{
+				checkPeriodPath: 5,
+				updatesPath:     updatesConfig{Workers: 2, ProviderTimeoutSecs: 10},
+			}},
+			expectedPeriod:  "5m0s",
+			expectedWorkers: 2,
+			expectedTimeout: "10s",
+		},
+		{
+			name:          "invalid-period",
+			config:        configMock{values: map[string]any{checkPeriodPath: -1}},
+			expectedError: errors.New("reconciler: invalid check period -1"),
+		},
+		{
+			name:          "invalid-workers",
+			config:        configMock{values: map[string]any{updatesPath: updatesConfig{Workers: -1}}},
+			expectedError: errors.New("reconciler: invalid updates config workers=-1 provider-timeout-secs=0"),
+		},
+		{
+			name:          "invalid-grace-period",
+			config:        configMock{values: map[string]any{gracePeriodPath: -1}},
+			expectedError: errors.New("reconciler: invalid shutdown grace period -1"),
+		},
+		{
+			name:          "invalid-outage-threshold",
+			config:        configMock{values: map[string]any{outagePath: 0}},
+			expectedError: errors.New("reconciler: invalid source outage threshold 0"),
+		},
+	}
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		expectedPeriod := tc.expectedPeriod
+		expectedWorkers := tc.expectedWorkers
+		expectedTimeout := tc.expectedTimeout
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(cnf, getterMock{}, &storeMock{}, nil, loggerMock{})
+
+			assert.Equal(t, expectedError, err)
+			if err == nil {
+				assert.Equal(t, expectedPeriod, r.period.String())
+				assert.Equal(t, expectedWorkers, r.workers)
+				assert.Equal(t, expectedTimeout, r.timeout.String())
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	managed := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A},
+		{FQDN: "vpn6.home.com.", Type: dns.AAAA},
+	}
+
+	testCases := []struct {
+		name            string
+		ip              publicip.IP
+		store           *storeMock
+		updater         *updaterMock
+		expectedPushed  []dns.DomainRecord
+		expectedUpdated []dns.DomainRecord
+		expectedFailed  []dns.DomainRecord
+		expectedError   error
+		expectedReport  error
+	}{
+		{
+			name:          "no-public-ip",
+			store:         &storeMock{},
+			updater:       &updaterMock{managed: managed},
+			expectedError: ErrNoPublicIP,
+		},
+		{
+			name:          "store-error",
+			ip:            publicip.IP{V4: stringPointer("10.0.0.1")},
+			store:         &storeMock{getErr: errors.New("db error")},
+			updater:       &updaterMock{managed: managed},
+			expectedError: errors.New("reconciler: unable to read stored records, err:db error"),
+		},
+		{
+			name:    "nothing-changed",
+			ip:      publicip.IP{V4: stringPointer("10.0.0.1")},
+			store:   &storeMock{records: []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"}}},
+			updater: &updaterMock{managed: managed},
+		},
+		{
+			name: "only-changed-records-pushed",
+			ip:   publicip.IP{V4: stringPointer("10.0.0.2"), V6: stringPointer("::1")},
+			store: &storeMock{records: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"},
+				{FQDN: "vpn6.home.com.", Type: dns.AAAA, Value: "::1"},
+			}},
+			updater: &updaterMock{managed: managed},
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			},
+			expectedUpdated: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			},
+		},
+		{
+			name:    "new-records-pushed",
+			ip:      publicip.IP{V6: stringPointer("::1")},
+			store:   &storeMock{},
+			updater: &updaterMock{managed: managed},
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "vpn6.home.com.", Type: dns.AAAA, Value: "::1"},
+			},
+			expectedUpdated: []dns.DomainRecord{
+				{FQDN: "vpn6.home.com.", Type: dns.AAAA, Value: "::1"},
+			},
+		},
+		{
+			name:    "updater-error-not-persisted",
+			ip:      publicip.IP{V4: stringPointer("10.0.0.2")},
+			store:   &storeMock{},
+			updater: &updaterMock{managed: managed, err: errors.New("aws error")},
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			},
+			expectedFailed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			},
+			expectedReport: errors.New("reconciler: unable to update A vpn.home.com. on mock/main, err:aws error"),
+		},
+		{
+			name:    "only-applied-records-persisted",
+			ip:      publicip.IP{V4: stringPointer("10.0.0.2"), V6: stringPointer("::1")},
+			store:   &storeMock{},
+			updater: &updaterMock{managed: managed, failing: map[string]bool{"vpn6.home.com.": true}},
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+				{FQDN: "vpn6.home.com.", Type: dns.AAAA, Value: "::1"},
+			},
+			expectedUpdated: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			},
+			expectedFailed: []dns.DomainRecord{
+				{FQDN: "vpn6.home.com.", Type: dns.AAAA, Value: "::1"},
+			},
+			expectedReport: errors.New("reconciler: unable to update AAAA vpn6.home.com. on mock/main, err:rejected"),
+		},
+		{
+			name:    "deferred-records-not-failed",
+			ip:      publicip.IP{V4: stringPointer("10.0.0.2"), V6: stringPointer("::1")},
+			store:   &storeMock{},
+			updater: &updaterMock{managed: managed, deferred: map[string]bool{"vpn6.home.com.": true}},
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+				{FQDN: "vpn6.home.com.", Type: dns.AAAA, Value: "::1"},
+			},
+			expectedUpdated: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			},
+		},
+		{
+			name:    "persist-error",
+			ip:      publicip.IP{V4: stringPointer("10.0.0.2")},
+			store:   &storeMock{updateErr: errors.New("db locked")},
+			updater: &updaterMock{managed: managed},
+			expectedPushed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			},
+			expectedFailed: []dns.DomainRecord{
+				{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			},
+			expectedReport: errors.New("reconciler: unable to store record vpn.home.com., err:db locked"),
+		},
+	}
+
+	for _, tc := range testCases {
+		ip := tc.ip
+		store := tc.store
+		updater := tc.updater
+		expectedPushed := tc.expectedPushed
+		expectedUpdated := tc.expectedUpdated
+		expectedFailed := tc.expectedFailed
+		expectedError := tc.expectedError
+		expectedReport := tc.expectedReport
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := reconciler{
+				getter:   getterMock{ip: ip},
+				store:    store,
+				updaters: []dns.Updater{updater},
+				logger:   loggerMock{},
+				workers:  1,
+				timeout:  time.Second,
+			}
+
+			report, err := r.Sync(context.Background())
+
+			if expectedError != nil {
+				assert.EqualError(t, err, expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if expectedReport != nil {
+				assert.EqualError(t, report.Err(), expectedReport.Error())
+			} else {
+				assert.NoError(t, report.Err())
+			}
+
+			assert.Equal(t, expectedPushed, updater.pushed)
+			assert.Equal(t, expectedUpdated, store.updated)
+			assert.Equal(t, expectedUpdated, report.Updated)
+			assert.Equal(t, expectedFailed, report.Failed)
+		})
+	}
+}
+
+func TestSyncKeepsProvidersApart(t *testing.T) {
+	record := dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A}
+	store := &storeMock{entries: []ddns.HistoryEntry{
+		{Provider: "route53", Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+		{Provider: "digitalocean", Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"}},
+		{Record: dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}},
+	}}
+
+	r := reconciler{
+		getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.2")}},
+		store:  store,
+		updaters: []dns.Updater{
+			namedUpdaterMock{name: "route53", managed: []dns.DomainRecord{record}},
+			namedUpdaterMock{name: "digitalocean", managed: []dns.DomainRecord{record}},
+			namedUpdaterMock{name: "cloudflare", managed: []dns.DomainRecord{record}},
+		},
+		logger:  loggerMock{},
+		workers: 1,
+		timeout: time.Second,
+	}
+
+	report, err := r.Sync(context.Background())
+	assert.NoError(t, err)
+
+	assert.Len(t, report.Providers, 1)
+	assert.Equal(t, "digitalocean", report.Providers[0].Provider)
+	assert.Equal(t, []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"}}, report.Updated)
+}
+
+func TestStatus(t *testing.T) {
+	managed := []dns.DomainRecord{
+		{FQDN: "vpn.home.com.", Type: dns.A},
+		{FQDN: "jenkins.home.com.", Type: dns.A},
+		{FQDN: "vpn6.home.com.", Type: dns.AAAA},
+	}
+
+	r := reconciler{
+		getter: getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.2")}},
+		store: &storeMock{records: []dns.DomainRecord{
+			{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			{FQDN: "jenkins.home.com.", Type: dns.A, Value: "10.0.0.1"},
+		}},
+		updaters: []dns.Updater{&updaterMock{managed: managed}},
+		logger:   loggerMock{},
+	}
+
+	status, err := r.Status(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", *status.IP.V4)
+	assert.Equal(t, []dns.RecordState{
+		{
+			Record:   dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			Provider: "mock/main",
+			Desired:  "10.0.0.2",
+			InSync:   true,
+		},
+		{
+			Record:   dns.DomainRecord{FQDN: "jenkins.home.com.", Type: dns.A, Value: "10.0.0.1"},
+			Provider: "mock/main",
+			Desired:  "10.0.0.2",
+			InSync:   false,
+		},
+		{
+			Record:   dns.DomainRecord{FQDN: "vpn6.home.com.", Type: dns.AAAA},
+			Provider: "mock/main",
+		},
+	}, status.Records)
+}
+
+func TestLastStatus(t *testing.T) {
+	calls := int32(0)
+	r := reconciler{
+		getter: countingGetterMock{ip: publicip.IP{V4: stringPointer("10.0.0.3")}, calls: &calls},
+		store: &storeMock{records: []dns.DomainRecord{
+			{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+		}},
+		updaters: []dns.Updater{&updaterMock{managed: []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A}}}},
+		logger:   loggerMock{},
+	}
+	r.state.lastIP = publicip.IP{V4: stringPointer("10.0.0.2")}
+
+	status, err := r.LastStatus(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	assert.Equal(t, "10.0.0.2", *status.IP.V4)
+	assert.Equal(t, []dns.RecordState{
+		{
+			Record:   dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.2"},
+			Provider: "mock/main",
+			Desired:  "10.0.0.2",
+			InSync:   true,
+		},
+	}, status.Records)
+}
+
+func TestRunTrigger(t *testing.T) {
+	calls := int32(0)
+	r := reconciler{
+		getter:  countingGetterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}, calls: &calls},
+		store:   &storeMock{},
+		logger:  loggerMock{},
+		period:  time.Hour,
+		workers: 1,
+		timeout: time.Second,
+		trigger: make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 5*time.Millisecond)
+
+	r.Trigger()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestRunShutdown(t *testing.T) {
+	record := dns.DomainRecord{FQDN: "vpn.home.com.", Type: dns.A}
+
+	testCases := []struct {
+		name            string
+		delay           time.Duration
+		grace           time.Duration
+		expectedUpdated []dns.DomainRecord
+	}{
+		{
+			name:            "in-flight-cycle-finishes",
+			delay:           100 * time.Millisecond,
+			grace:           time.Second,
+			expectedUpdated: []dns.DomainRecord{{FQDN: "vpn.home.com.", Type: dns.A, Value: "10.0.0.1"}},
+		},
+		{
+			name:            "grace-period-expires",
+			delay:           time.Second,
+			grace:           20 * time.Millisecond,
+			expectedUpdated: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		delay := tc.delay
+		grace := tc.grace
+		expectedUpdated := tc.expectedUpdated
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := &storeMock{}
+			r := reconciler{
+				getter:   getterMock{ip: publicip.IP{V4: stringPointer("10.0.0.1")}},
+				store:    store,
+				updaters: []dns.Updater{namedUpdaterMock{name: "slow", managed: []dns.DomainRecord{record}, delay: delay}},
+				logger:   loggerMock{},
+				period:   time.Hour,
+				workers:  1,
+				timeout:  time.Minute,
+				grace:    grace,
+				trigger:  make(chan struct{}, 1),
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- r.Run(ctx)
+			}()
+
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+
+			select {
+			case err := <-done:
+				assert.ErrorIs(t, err, context.Canceled)
+			case <-time.After(2 * time.Second):
+				t.Fatal("Run didn't return after cancellation")
+			}
+
+			assert.Equal(t, expectedUpdated, store.updated)
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+const (
+	retryPath string = "ddns.retry"
+
+	defaultMaxAttempts        int     = 3
+	defaultInitialBackoffSecs int     = 1
+	defaultMaxBackoffSecs     int     = 300
+	defaultMultiplier         float64 = 2
+	defaultJitter             float64 = 0.2
+)
+
+var ErrRetryScheduled = errors.New("retry scheduled")
+
+type configDecoder interface {
+	Decode(node string, item any) error
+}
+
+type messageLogger interface {
+	Debug(msg string)
+	Warning(msg string)
+}
+
+type retryConfig struct {
+	MaxAttempts        int     `yaml:"max-attempts"`
+	InitialBackoffSecs int     `yaml:"initial-backoff-secs"`
+	MaxBackoffSecs     int     `yaml:"max-backoff-secs"`
+	Multiplier         float64 `yaml:"multiplier"`
+	Jitter             float64 `yaml:"jitter"`
+}
+
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+}
+
+type updater struct {
+	next   dns.Updater
+	policy Policy
+	store  ddns.RetryStore
+	logger messageLogger
+	now    func() time.Time
+	random func() float64
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+func NewPolicy(cnf configDecoder) (Policy, error) {
+	rc := retryConfig{}
+	if err := cnf.Decode(retryPath, &rc); err != nil {
+		rc = retryConfig{}
+	}
+
+	if rc.MaxAttempts < 0 || rc.InitialBackoffSecs < 0 || rc.MaxBackoffSecs < 0 || rc.Multiplier < 0 {
+		return Policy{}, fmt.Errorf("retry: invalid config %+v", rc)
+	}
+
+	if rc.Jitter < 0 || rc.Jitter > 1 {
+		return Policy{}, fmt.Errorf("retry: jitter must be between 0 and 1, got %v", rc.Jitter)
+	}
+
+	if rc.MaxAttempts == 0 {
+		rc.MaxAttempts = defaultMaxAttempts
+	}
+
+	if rc.InitialBackoffSecs == 0 {
+		rc.InitialBackoffSecs = defaultInitialBackoffSecs
+	}
+
+	if rc.MaxBackoffSecs == 0 {
+		rc.MaxBackoffSecs = defaultMaxBackoffSecs
+	}
+
+	if rc.Multiplier == 0 {
+		rc.Multiplier = defaultMultiplier
+	}
+
+	if rc.Jitter == 0 {
+		rc.Jitter = defaultJitter
+	}
+
+	return Policy{
+		MaxAttempts:    rc.MaxAttempts,
+		InitialBackoff: time.Duration(rc.InitialBackoffSecs) * time.Second,
+		MaxBackoff:     time.Duration(rc.MaxBackoffSecs) * time.Second,
+		Multiplier:     rc.Multiplier,
+		Jitter:         rc.Jitter,
+	}, nil
+}
+
+// New wraps next so that records failing with a retryable error are retried
+// with exponential backoff and jitter, up to policy.MaxAttempts counted across
+// calls. Records still failing afterwards are stored as pending retries and
+// are deferred until their backoff expires, even across restarts, then sent
+// once per call. A pending retry is dropped when the record's value changes.
+func New(next dns.Updater, policy Policy, store ddns.RetryStore, logger messageLogger) dns.Updater {
+	return &updater{
+		next:   next,
+		policy: policy,
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+		random: rand.Float64,
+		sleep:  sleep,
+	}
+}
+
+func (u *updater) Name() string {
+	return u.next.Name()
+}
+
+func (u *updater) Records() []dns.DomainRecord {
+	return u.next.Records()
+}
+
+func (u *updater) Zone(record dns.DomainRecord) string {
+	return dns.ZoneOf(u.next, record)
+}
+
+func (u *updater) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	pending := u.pendingRetries(ctx)
+
+	final := make(map[dns.DomainRecord]dns.UpdateResult, len(records))
+	attempts := make(map[dns.DomainRecord]int, len(records))
+
+	toSend := make([]dns.DomainRecord, 0, len(records))
+	for _, record := range records {
+		retry, ok := pending[key(record)]
+		switch {
+		case ok && retry.Record.Value != record.Value:
+			u.logger.Debug(fmt.Sprintf("retry: %s %s on %s changed to %s, dropping its pending retry", record.Type, record.FQDN, u.Name(), record.Value))
+			retry = ddns.PendingRetry{}
+		case ok && retry.NextAttempt.After(u.now()):
+			final[record] = dns.UpdateResult{
+				Record: record,
+				Status: dns.Deferred,
+				Err:    fmt.Errorf("%w at %s, last error: %s", ErrRetryScheduled, retry.NextAttempt.Format(time.RFC3339), retry.LastError),
+			}
+			continue
+		}
+
+		attempts[record] = retry.Attempts
+		toSend = append(toSend, record)
+	}
+
+	for attempt := 1; len(toSend) > 0; attempt++ {
+		retryable := make([]dns.DomainRecord, 0)
+		for _, result := range u.next.UpdateDomains(ctx, toSend) {
+			final[result.Record] = result
+			attempts[result.Record]++
+
+			if result.Status == dns.Failed && Retryable(result.Err) && attempts[result.Record] < u.policy.MaxAttempts {
+				retryable = append(retryable, result.Record)
+			}
+		}
+
+		if len(retryable) == 0 {
+			break
+		}
+
+		backoff := u.backoff(attempt)
+		u.logger.Debug(fmt.Sprintf("retry: %d records failed on %s, retrying in %s", len(retryable), u.Name(), backoff))
+		if err := u.sleep(ctx, backoff); err != nil {
+			break
+		}
+
+		toSend = retryable
+	}
+
+	results := make([]dns.UpdateResult, 0, len(records))
+	for _, record := range records {
+		result := final[record]
+		u.track(ctx, record, result, attempts[record], pending)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func (u *updater) pendingRetries(ctx context.Context) map[string]ddns.PendingRetry {
+	pending := map[string]ddns.PendingRetry{}
+
+	retries, err := u.store.PendingRetries(ctx, u.Name())
+	if err != nil {
+		u.logger.Warning(fmt.Sprintf("retry: unable to read pending retries for %s, err:%s", u.Name(), err))
+		return pending
+	}
+
+	for _, retry := range retries {
+		pending[key(retry.Record)] = retry
+	}
+
+	return pending
+}
+
+func (u *updater) track(ctx context.Context, record dns.DomainRecord, result dns.UpdateResult, attempts int, pending map[string]ddns.PendingRetry) {
+	if result.Status == dns.Deferred {
+		return
+	}
+
+	if result.Status == dns.Failed && Retryable(result.Err) {
+		retry := ddns.PendingRetry{
+			Provider:    u.Name(),
+			Record:      record,
+			Attempts:    attempts,
+			NextAttempt: u.now().Add(u.backoff(attempts)),
+			LastError:   result.Err.Error(),
+		}
+
+		u.logger.Warning(fmt.Sprintf("retry: %s %s on %s failed %d times, next attempt at %s",
+			record.Type, record.FQDN, u.Name(), attempts, retry.NextAttempt.Format(time.RFC3339)))
+		if err := u.store.SavePendingRetry(ctx, retry); err != nil {
+			u.logger.Warning(fmt.Sprintf("retry: unable to save pending retry for %s, err:%s", record.FQDN, err))
+		}
+		return
+	}
+
+	if _, ok := pending[key(record)]; !ok {
+		return
+	}
+
+	if err := u.store.DeletePendingRetry(ctx, u.Name(), record); err != nil {
+		u.logger.Warning(fmt.Sprintf("retry: unable to delete pending retry for %s, err:%s", record.FQDN, err))
+	}
+}
+
+// backoff returns the wait after the given number of failed attempts, capped
+// at MaxBackoff and spread by ±Jitter so providers aren't hit in lockstep.
+func (u *updater) backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	d := float64(u.policy.InitialBackoff) * math.Pow(u.policy.Multiplier, float64(attempts-1))
+	if d > float64(u.policy.MaxBackoff) {
+		d = float64(u.policy.MaxBackoff)
+	}
+
+	d += d * u.policy.Jitter * (2*u.random() - 1)
+
+	return time.Duration(d)
+}
+
+// Retryable reports whether a failed update may succeed if sent again.
+// Permanent errors and cancellations aren't retried.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if dns.IsPermanent(err) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	return true
+}
+
+func key(record dns.DomainRecord) string {
+	return fmt.Sprintf("%s/%s", record.Type, record.FQDN)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
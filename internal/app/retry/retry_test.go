@@ -0,0 +1,447 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/storage/ddns"
+)
+
+type configMock struct {
+	values map[string]any
+}
+
+func (c configMock) Decode(node string, item any) error {
+	value, ok := c.values[node]
+	if !ok {
+		return errors.New("node not found")
+	}
+
+	reflect.ValueOf(item).Elem().Set(reflect.ValueOf(value))
+	return nil
+}
+
+type loggerMock struct{}
+
+func (loggerMock) Debug(msg string)   {}
+func (loggerMock) Warning(msg string) {}
+
+type storeMock struct {
+	pending []ddns.PendingRetry
+	saved   []ddns.PendingRetry
+	deleted []dns.DomainRecord
+}
+
+func (s *storeMock) SavePendingRetry(ctx context.Context, retry ddns.PendingRetry) error {
+	s.saved = append(s.saved, retry)
+	return nil
+}
+
+func (s *storeMock) DeletePendingRetry(ctx context.Context, provider string, record dns.DomainRecord) error {
+	s.deleted = append(s.deleted, record)
+	return nil
+}
+
+func (s *storeMock) PendingRetries(ctx context.Context, provider string) ([]ddns.PendingRetry, error) {
+	return s.pending, nil
+}
+
+// updaterMock fails each fqdn in failures that many times before applying it,
+// fqdns in permanent always fail with a permanent error.
+type updaterMock struct {
+	failures  map[string]int
+	permanent map[string]bool
+	calls     [][]dns.DomainRecord
+}
+
+func (u *updaterMock) Name() string {
+	return "mock/main"
+}
+
+func (u *updaterMock) Records() []dns.DomainRecord {
+	return nil
+}
+
+func (u *updaterMock) UpdateDomains(ctx context.Context, records []dns.DomainRecord) []dns.UpdateResult {
+	u.calls = append(u.calls, records)
+
+	results := []dns.UpdateResult{}
+	for _, record := range records {
+		switch {
+		case u.permanent[record.FQDN]:
+			results = append(results, dns.UpdateResult{Record: record, Status: dns.Failed, Err: dns.Permanent(errors.New("invalid change"))})
+		case u.failures[record.FQDN] > 0:
+			u.failures[record.FQDN]--
+			results = append(results, dns.UpdateResult{Record: record, Status: dns.Failed, Err: errors.New("throttled")})
+		default:
+			results = append(results, dns.UpdateResult{Record: record, Status: dns.Applied})
+		}
+	}
+
+	return results
+}
+
+func TestNewPolicy(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         configMock
+		expectedPolicy Policy
+		expectedError  error
+	}{
+		{
+			name:   "defaults",
+			config: configMock{},
+			expectedPolicy: Policy{
+				MaxAttempts:    defaultMaxAttempts,
+				InitialBackoff: time.Second,
+				MaxBackoff:     5 * time.Minute,
+				Multiplier:     defaultMultiplier,
+				Jitter:         defaultJitter,
+			},
+		},
+		{
+			name: "configured",
+			config: configMock{values: map[string]any{
+				retryPath: retryConfig{MaxAttempts: 5, InitialBackoffSecs: 2, MaxBackoffSecs: 60, Multiplier: 3, Jitter: 0.5},
+			}},
+			expectedPolicy: Policy{
+				MaxAttempts:    5,
+				InitialBackoff: 2 * time.Second,
+				MaxBackoff:     time.Minute,
+				Multiplier:     3,
+				Jitter:         0.5,
+			},
+		},
+		{
+			name:          "invalid-attempts",
+			config:        configMock{values: map[string]any{retryPath: retryConfig{MaxAttempts: -1}}},
+			expectedError: errors.New("retry: invalid config {MaxAttempts:-1 InitialBackoffSecs:0 MaxBackoffSecs:0 Multiplier:0 Jitter:0}"),
+		},
+		{
+			name:          "invalid-jitter",
+			config:        configMock{values: map[string]any{retryPath: retryConfig{Jitter: 1.5}}},
+			expectedError: errors.New("retry: jitter must be between 0 and 1, got 1.5"),
+		},
+	}
+
+	for _, tc := range testCases {
+		cnf := tc.config
+		expectedPolicy := tc.expectedPolicy
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := NewPolicy(cnf)
+
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedPolicy, policy)
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+
+	testCases := []struct {
+		name            string
+		attempts        int
+		random          float64
+		expectedBackoff time.Duration
+	}{
+		{
+			name:            "first-attempt",
+			attempts:        1,
+			random:          0.5,
+			expectedBackoff: time.Second,
+		},
+		{
+			name:            "third-attempt",
+			attempts:        3,
+			random:          0.5,
+			expectedBackoff: 4 * time.Second,
+		},
+		{
+			name:            "capped",
+			attempts:        10,
+			random:          0.5,
+			expectedBackoff: 10 * time.Second,
+		},
+		{
+			name:            "jitter-low",
+			attempts:        2,
+			random:          0,
+			expectedBackoff: time.Second,
+		},
+		{
+			name:            "jitter-high",
+			attempts:        2,
+			random:          1,
+			expectedBackoff: 3 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		attempts := tc.attempts
+		random := tc.random
+		expectedBackoff := tc.expectedBackoff
+
+		t.Run(tc.name, func(t *testing.T) {
+			u := updater{policy: policy, random: func() float64 { return random }}
+
+			assert.Equal(t, expectedBackoff, u.backoff(attempts))
+		})
+	}
+}
+
+func TestUpdateDomains(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := dns.DomainRecord{Type: dns.A, FQDN: "vpn.example.com", Value: "10.0.0.1"}
+	policy := Policy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Multiplier:     2,
+	}
+
+	testCases := []struct {
+		name            string
+		updater         *updaterMock
+		store           *storeMock
+		expectedStatus  dns.UpdateStatus
+		expectedCalls   int
+		expectedSleeps  []time.Duration
+		expectedSaved   []ddns.PendingRetry
+		expectedDeleted []dns.DomainRecord
+	}{
+		{
+			name:           "applied",
+			updater:        &updaterMock{},
+			store:          &storeMock{},
+			expectedStatus: dns.Applied,
+			expectedCalls:  1,
+		},
+		{
+			name:           "applied-after-retries",
+			updater:        &updaterMock{failures: map[string]int{record.FQDN: 2}},
+			store:          &storeMock{},
+			expectedStatus: dns.Applied,
+			expectedCalls:  3,
+			expectedSleeps: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:           "budget-exhausted",
+			updater:        &updaterMock{failures: map[string]int{record.FQDN: 5}},
+			store:          &storeMock{},
+			expectedStatus: dns.Failed,
+			expectedCalls:  3,
+			expectedSleeps: []time.Duration{time.Second, 2 * time.Second},
+			expectedSaved: []ddns.PendingRetry{
+				{
+					Provider:    "mock/main",
+					Record:      record,
+					Attempts:    3,
+					NextAttempt: now.Add(4 * time.Second),
+					LastError:   "throttled",
+				},
+			},
+		},
+		{
+			name:           "permanent-error",
+			updater:        &updaterMock{permanent: map[string]bool{record.FQDN: true}},
+			store:          &storeMock{},
+			expectedStatus: dns.Failed,
+			expectedCalls:  1,
+		},
+		{
+			name:    "retry-scheduled",
+			updater: &updaterMock{},
+			store: &storeMock{pending: []ddns.PendingRetry{
+				{Provider: "mock/main", Record: record, Attempts: 3, NextAttempt: now.Add(time.Minute), LastError: "throttled"},
+			}},
+			expectedStatus: dns.Deferred,
+			expectedCalls:  0,
+		},
+		{
+			name:    "retry-scheduled-value-changed",
+			updater: &updaterMock{},
+			store: &storeMock{pending: []ddns.PendingRetry{
+				{Provider: "mock/main", Record: dns.DomainRecord{Type: dns.A, FQDN: record.FQDN, Value: "10.0.0.9"}, Attempts: 3, NextAttempt: now.Add(time.Minute), LastError: "throttled"},
+			}},
+			expectedStatus:  dns.Applied,
+			expectedCalls:   1,
+			expectedDeleted: []dns.DomainRecord{record},
+		},
+		{
+			name:    "retry-scheduled-value-changed-failed",
+			updater: &updaterMock{failures: map[string]int{record.FQDN: 1}},
+			store: &storeMock{pending: []ddns.PendingRetry{
+				{Provider: "mock/main", Record: dns.DomainRecord{Type: dns.A, FQDN: record.FQDN, Value: "10.0.0.9"}, Attempts: 3, NextAttempt: now.Add(time.Minute), LastError: "throttled"},
+			}},
+			expectedStatus:  dns.Applied,
+			expectedCalls:   2,
+			expectedSleeps:  []time.Duration{time.Second},
+			expectedDeleted: []dns.DomainRecord{record},
+		},
+		{
+			name:    "retry-due-applied",
+			updater: &updaterMock{},
+			store: &storeMock{pending: []ddns.PendingRetry{
+				{Provider: "mock/main", Record: record, Attempts: 3, NextAttempt: now.Add(-time.Second), LastError: "throttled"},
+			}},
+			expectedStatus:  dns.Applied,
+			expectedCalls:   1,
+			expectedDeleted: []dns.DomainRecord{record},
+		},
+		{
+			name:    "retry-due-failed-again",
+			updater: &updaterMock{failures: map[string]int{record.FQDN: 1}},
+			store: &storeMock{pending: []ddns.PendingRetry{
+				{Provider: "mock/main", Record: record, Attempts: 3, NextAttempt: now.Add(-time.Second), LastError: "throttled"},
+			}},
+			expectedStatus: dns.Failed,
+			expectedCalls:  1,
+			expectedSaved: []ddns.PendingRetry{
+				{
+					Provider:    "mock/main",
+					Record:      record,
+					Attempts:    4,
+					NextAttempt: now.Add(8 * time.Second),
+					LastError:   "throttled",
+				},
+			},
+		},
+		{
+			name:    "retry-due-within-budget",
+			updater: &updaterMock{failures: map[string]int{record.FQDN: 2}},
+			store: &storeMock{pending: []ddns.PendingRetry{
+				{Provider: "mock/main", Record: record, Attempts: 1, NextAttempt: now.Add(-time.Second), LastError: "throttled"},
+			}},
+			expectedStatus: dns.Failed,
+			expectedCalls:  2,
+			expectedSleeps: []time.Duration{time.Second},
+			expectedSaved: []ddns.PendingRetry{
+				{
+					Provider:    "mock/main",
+					Record:      record,
+					Attempts:    3,
+					NextAttempt: now.Add(4 * time.Second),
+					LastError:   "throttled",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		updaterMock := tc.updater
+		store := tc.store
+		expectedStatus := tc.expectedStatus
+		expectedCalls := tc.expectedCalls
+		expectedSleeps := tc.expectedSleeps
+		expectedSaved := tc.expectedSaved
+		expectedDeleted := tc.expectedDeleted
+
+		t.Run(tc.name, func(t *testing.T) {
+			var sleeps []time.Duration
+			u := &updater{
+				next:   updaterMock,
+				policy: policy,
+				store:  store,
+				logger: loggerMock{},
+				now:    func() time.Time { return now },
+				random: func() float64 { return 0.5 },
+				sleep: func(ctx context.Context, d time.Duration) error {
+					sleeps = append(sleeps, d)
+					return nil
+				},
+			}
+
+			results := u.UpdateDomains(context.Background(), []dns.DomainRecord{record})
+
+			assert.Len(t, results, 1)
+			assert.Equal(t, record, results[0].Record)
+			assert.Equal(t, expectedStatus, results[0].Status)
+			assert.Len(t, updaterMock.calls, expectedCalls)
+			assert.Equal(t, expectedSleeps, sleeps)
+			assert.Equal(t, expectedSaved, store.saved)
+			assert.Equal(t, expectedDeleted, store.deleted)
+		})
+	}
+}
+
+func TestUpdateDomainsCancelled(t *testing.T) {
+	record := dns.DomainRecord{Type: dns.A, FQDN: "vpn.example.com", Value: "10.0.0.1"}
+	mock := &updaterMock{failures: map[string]int{record.FQDN: 5}}
+	store := &storeMock{}
+
+	u := &updater{
+		next:   mock,
+		policy: Policy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: time.Minute, Multiplier: 2},
+		store:  store,
+		logger: loggerMock{},
+		now:    time.Now,
+		random: func() float64 { return 0.5 },
+		sleep: func(ctx context.Context, d time.Duration) error {
+			return context.Canceled
+		},
+	}
+
+	results := u.UpdateDomains(context.Background(), []dns.DomainRecord{record})
+
+	assert.Len(t, mock.calls, 1)
+	assert.Equal(t, dns.Failed, results[0].Status)
+	assert.Len(t, store.saved, 1)
+}
+
+func TestRetryable(t *testing.T) {
+	testCases := []struct {
+		name              string
+		err               error
+		expectedRetryable bool
+	}{
+		{
+			name:              "nil",
+			err:               nil,
+			expectedRetryable: false,
+		},
+		{
+			name:              "transient",
+			err:               errors.New("connection reset"),
+			expectedRetryable: true,
+		},
+		{
+			name:              "permanent",
+			err:               dns.Permanent(errors.New("invalid change")),
+			expectedRetryable: false,
+		},
+		{
+			name:              "cancelled",
+			err:               context.Canceled,
+			expectedRetryable: false,
+		},
+		{
+			name:              "deadline",
+			err:               context.DeadlineExceeded,
+			expectedRetryable: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := tc.err
+		expectedRetryable := tc.expectedRetryable
+
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, expectedRetryable, Retryable(err))
+		})
+	}
+}
@@ -3,7 +3,6 @@ package config
 import (
 	"bytes"
 	"errors"
-	"flag"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -11,6 +10,7 @@ import (
 
 	yaml "gopkg.in/yaml.v2"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 )
 
@@ -23,32 +23,20 @@ type config struct {
 	vp *viper.Viper
 }
 
-func New() (*config, error) {
-	var configFile *string
-	if configFile = parseArguments(); configFile == nil {
+func New(configFile string) (*config, error) {
+	if configFile == "" {
 		return nil, errors.New(errReadConfigFile)
 	}
 
 	cnf := new(config)
 	cnf.vp = viper.New()
-	if err := cnf.read(*configFile); err != nil {
+	if err := cnf.read(configFile); err != nil {
 		return nil, err
 	}
 
 	return cnf, nil
 }
 
-func parseArguments() *string {
-	configFile := flag.String("config", "", "-config=<CONFIG-FILE-PATH>")
-	flag.Parse()
-
-	if *configFile == "" {
-		return nil
-	}
-
-	return configFile
-}
-
 func (c *config) read(file string) error {
 	c.vp.SetConfigType(configFileType)
 	c.vp.SetConfigFile(file)
@@ -59,6 +47,16 @@ func (c *config) read(file string) error {
 	return nil
 }
 
+// Watch calls onChange every time the config file is written. The config is
+// re-read before onChange runs, callers that need to validate a change before
+// applying it should load a fresh copy with New instead.
+func (c *config) Watch(onChange func()) {
+	c.vp.OnConfigChange(func(fsnotify.Event) {
+		onChange()
+	})
+	c.vp.WatchConfig()
+}
+
 func (c *config) Decode(node string, item any) error {
 	bytes, err := c.find(node)
 	if err != nil {
@@ -121,3 +119,63 @@ func (c *config) find(node string) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+// Dump encodes the effective configuration with the values of settings that
+// look like credentials redacted, so it can be shown or logged.
+func (c *config) Dump() ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("config haven't been read")
+	}
+
+	return encode(redact("", c.vp.AllSettings()))
+}
+
+// DumpRaw encodes the effective configuration as it was read, credentials
+// included.
+func (c *config) DumpRaw() ([]byte, error) {
+	if c == nil {
+		return nil, errors.New("config haven't been read")
+	}
+
+	return encode(c.vp.AllSettings())
+}
+
+func encode(settings any) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := yaml.NewEncoder(buf).Encode(settings); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// redact returns a copy of value where every setting at a sensitive path is
+// replaced, lists are walked too since provider accounts hold credentials.
+func redact(path string, value any) any {
+	if sensitive(path) {
+		return redacted
+	}
+
+	switch node := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(node))
+		for k, v := range node {
+			copied[k] = redact(join(path, k), v)
+		}
+		return copied
+	case map[any]any:
+		copied := make(map[any]any, len(node))
+		for k, v := range node {
+			copied[k] = redact(join(path, fmt.Sprint(k)), v)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(node))
+		for i, v := range node {
+			copied[i] = redact(path, v)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"path/filepath"
 
@@ -301,37 +302,139 @@ func dereferenceValues(t *testing.T, value any) any {
 	return nil
 }
 
-func TestParseArguments(t *testing.T) {
-	var (
-		fileOk string = "file-for-config"
-	)
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, []byte(content[fileOk]), 0o644); err != nil {
+		t.Fatal(err)
+	}
 
 	testCases := []struct {
-		name           string
-		arguments      []string
-		expectedResult *string
+		name          string
+		file          string
+		expectedError string
 	}{
 		{
-			name:           "normal-case",
-			arguments:      []string{"app-name", "-config", "file-for-config"},
-			expectedResult: &fileOk,
+			name: "normal-case",
+			file: file,
+		},
+		{
+			name:          "empty-file-name",
+			file:          "",
+			expectedError: errReadConfigFile,
+		},
+		{
+			name:          "missing-file",
+			file:          filepath.Join(dir, "missing.yaml"),
+			expectedError: errReadConfigFile,
 		},
-		// TODO: Test when no flags are defined
 	}
 
 	for _, tc := range testCases {
-		args := tc.arguments
-		expectedResult := tc.expectedResult
-		t.Run(tc.name, func(t *testing.T) {
-			originalArgs := os.Args
-			defer func() {
-				os.Args = originalArgs
-			}()
+		file := tc.file
+		expectedError := tc.expectedError
 
-			os.Args = args
-			result := parseArguments()
+		t.Run(tc.name, func(t *testing.T) {
+			cnf, err := New(file)
 
-			assert.Equal(t, expectedResult, result)
+			if expectedError != "" {
+				assert.ErrorContains(t, err, expectedError)
+				assert.Nil(t, cnf)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, cnf)
+			}
 		})
 	}
 }
+
+func TestDump(t *testing.T) {
+	cnf := config{vp: viper.New()}
+	cnf.vp.SetFs(createFS(t, fileOk, content[fileOk]))
+
+	err := cnf.read(fileOk)
+	assert.NoError(t, err)
+
+	dump, err := cnf.Dump()
+	assert.NoError(t, err)
+	assert.Contains(t, string(dump), "db: /var/simple-ddns.db")
+	assert.Contains(t, string(dump), "endpoint: https://api6.ipify.org")
+}
+
+func TestDumpRedacted(t *testing.T) {
+	secrets := `
+ddns:
+  http:
+    admin-token: admin-secret
+  notifications:
+    webhook:
+      - name: ops
+        url: https://hooks.home.com/ddns?token=url-secret
+        secret: hmac-secret
+        headers:
+          Authorization: "Bearer header-secret"
+    slack:
+      - name: ops
+        url: https://hooks.slack.com/services/T000/B000/slack-secret
+    discord:
+      - name: ops
+        url: https://discord.com/api/webhooks/1234/discord-secret
+  dns-server:
+    digital-ocean:
+      - account: main
+        api-key: do-secret
+        domain: home.com
+`
+
+	cnf := config{vp: viper.New()}
+	cnf.vp.SetFs(createFS(t, fileOk, secrets))
+
+	err := cnf.read(fileOk)
+	assert.NoError(t, err)
+
+	dump, err := cnf.Dump()
+	assert.NoError(t, err)
+	assert.Contains(t, string(dump), "url: <redacted>")
+	assert.Contains(t, string(dump), "domain: home.com")
+	assert.Contains(t, string(dump), "api-key: <redacted>")
+
+	raw, err := cnf.DumpRaw()
+	assert.NoError(t, err)
+
+	for _, secret := range []string{"admin-secret", "hmac-secret", "header-secret", "do-secret", "url-secret", "slack-secret", "discord-secret"} {
+		assert.NotContains(t, string(dump), secret)
+		assert.Contains(t, string(raw), secret)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(content[fileOk]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cnf, err := New(file)
+	assert.NoError(t, err)
+
+	changed := make(chan struct{}, 1)
+	cnf.Watch(func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	if err = os.WriteFile(file, []byte("ddns:\n  check-period-mins: 10\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change wasn't notified")
+	}
+
+	period := 0
+	assert.NoError(t, cnf.Decode("ddns.check-period-mins", &period))
+	assert.Equal(t, 10, period)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const redacted string = "<redacted>"
+
+var sensitiveKeys = []string{"key", "secret", "password", "token", "authorization"}
+
+// notificationsPath holds notifiers whose url is the credential itself, like
+// slack and discord webhooks or urls carrying a token in their query.
+const notificationsPath string = "ddns.notifications."
+
+type dumper interface {
+	DumpRaw() ([]byte, error)
+}
+
+// Diff describes every setting added, removed or changed between two
+// configurations, one line per setting. Values of settings that look like
+// credentials are redacted so the result can be logged.
+func Diff(previous, current dumper) ([]string, error) {
+	before, err := flatten(previous)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := flatten(current)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	changes := []string{}
+	for _, key := range keys {
+		old, hadOld := before[key]
+		new, hasNew := after[key]
+
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("+ %s: %s", key, display(key, new)))
+		case !hasNew:
+			changes = append(changes, fmt.Sprintf("- %s: %s", key, display(key, old)))
+		case !reflect.DeepEqual(old, new):
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", key, display(key, old), display(key, new)))
+		}
+	}
+
+	return changes, nil
+}
+
+func flatten(cnf dumper) (map[string]any, error) {
+	dump, err := cnf.DumpRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[any]any{}
+	if err = yaml.Unmarshal(dump, &settings); err != nil {
+		return nil, err
+	}
+
+	flat := map[string]any{}
+	flattenInto(flat, "", settings)
+
+	return flat, nil
+}
+
+func flattenInto(flat map[string]any, prefix string, value any) {
+	node, ok := value.(map[any]any)
+	if !ok {
+		flat[prefix] = value
+		return
+	}
+
+	for key, child := range node {
+		flattenInto(flat, join(prefix, fmt.Sprint(key)), child)
+	}
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+func display(path string, value any) string {
+	if sensitive(path) {
+		return redacted
+	}
+
+	// lists may hold credentials too, e.g. the account blocks of a provider.
+	if _, ok := value.([]any); ok {
+		return "[...]"
+	}
+
+	return fmt.Sprint(value)
+}
+
+// sensitive tells whether the setting at path looks like a credential.
+func sensitive(path string) bool {
+	lower := strings.ToLower(path)
+	if strings.HasPrefix(lower, notificationsPath) && strings.HasSuffix(lower, ".url") {
+		return true
+	}
+
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(lower, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
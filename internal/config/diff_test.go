@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dumperMock struct {
+	dump string
+	err  error
+}
+
+func (d dumperMock) DumpRaw() ([]byte, error) {
+	return []byte(d.dump), d.err
+}
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		name            string
+		previous        dumperMock
+		current         dumperMock
+		expectedChanges []string
+		expectedError   error
+	}{
+		{
+			name: "unchanged",
+			previous: dumperMock{dump: `
+ddns:
+  check-period-mins: 5
+`},
+			current: dumperMock{dump: `
+ddns:
+  check-period-mins: 5
+`},
+			expectedChanges: []string{},
+		},
+		{
+			name: "added-removed-changed",
+			previous: dumperMock{dump: `
+ddns:
+  check-period-mins: 5
+  updates:
+    workers: 4
+`},
+			current: dumperMock{dump: `
+ddns:
+  check-period-mins: 10
+  retry:
+    max-attempts: 3
+`},
+			expectedChanges: []string{
+				"~ ddns.check-period-mins: 5 -> 10",
+				"+ ddns.retry.max-attempts: 3",
+				"- ddns.updates.workers: 4",
+			},
+		},
+		{
+			name: "redacted",
+			previous: dumperMock{dump: `
+ddns:
+  notifications:
+    api-key: old
+  dns-server:
+    aws:
+      - account: main
+`},
+			current: dumperMock{dump: `
+ddns:
+  notifications:
+    api-key: new
+  dns-server:
+    aws:
+      - account: other
+`},
+			expectedChanges: []string{
+				"~ ddns.dns-server.aws: [...] -> [...]",
+				"~ ddns.notifications.api-key: <redacted> -> <redacted>",
+			},
+		},
+		{
+			name: "redacted-notifier-url",
+			previous: dumperMock{dump: `
+ddns:
+  notifications:
+    slack:
+      url: https://hooks.slack.com/services/T000/B000/old-secret
+  public-ip-api:
+    ipify:
+      url: https://api.ipify.org
+`},
+			current: dumperMock{dump: `
+ddns:
+  notifications:
+    slack:
+      url: https://hooks.slack.com/services/T000/B000/new-secret
+  public-ip-api:
+    ipify:
+      url: https://api64.ipify.org
+`},
+			expectedChanges: []string{
+				"~ ddns.notifications.slack.url: <redacted> -> <redacted>",
+				"~ ddns.public-ip-api.ipify.url: https://api.ipify.org -> https://api64.ipify.org",
+			},
+		},
+		{
+			name:          "dump-error",
+			previous:      dumperMock{err: errors.New("config haven't been read")},
+			current:       dumperMock{},
+			expectedError: errors.New("config haven't been read"),
+		},
+	}
+
+	for _, tc := range testCases {
+		previous := tc.previous
+		current := tc.current
+		expectedChanges := tc.expectedChanges
+		expectedError := tc.expectedError
+
+		t.Run(tc.name, func(t *testing.T) {
+			changes, err := Diff(previous, current)
+
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expectedChanges, changes)
+		})
+	}
+}
@@ -1,20 +1,75 @@
 package dns
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 const (
 	A    RecordType = "A"
 	AAAA RecordType = "AAAA"
+
+	Applied   UpdateStatus = "applied"
+	Unchanged UpdateStatus = "unchanged"
+	Failed    UpdateStatus = "failed"
+	Skipped   UpdateStatus = "skipped"
+	// Deferred records weren't sent, a retry of an earlier failure is
+	// scheduled for later.
+	Deferred UpdateStatus = "deferred"
 )
 
 type RecordType string
 
+type UpdateStatus string
+
 type DomainRecord struct {
 	Type  RecordType
 	Value string
 	FQDN  string
 }
 
+// UpdateResult is the outcome of pushing a single record to a provider. Err is
+// only set when Status is Failed, or Deferred to tell when the retry is due.
+// Zone is the provider zone the record was sent to, if any.
+type UpdateResult struct {
+	Record DomainRecord
+	Zone   string
+	Status UpdateStatus
+	Err    error
+}
+
+// RecordState is a managed record on a provider along with the value stored
+// for it. Desired and InSync are only set when it's compared with a public ip.
+type RecordState struct {
+	Record     DomainRecord
+	Provider   string
+	Zone       string
+	UpdateTime time.Time
+	Paused     bool
+	LastError  string
+	Desired    string
+	InSync     bool
+}
+
 type Updater interface {
-	UpdateDomains(context.Context, []DomainRecord) error
+	UpdateDomains(context.Context, []DomainRecord) []UpdateResult
+	Records() []DomainRecord
+	Name() string
+}
+
+// ZoneResolver is implemented by updaters that know the zone a managed record
+// belongs to before pushing it.
+type ZoneResolver interface {
+	Zone(DomainRecord) string
+}
+
+// ZoneOf returns the zone updater places record in, or an empty string when
+// the updater can't tell.
+func ZoneOf(updater Updater, record DomainRecord) string {
+	resolver, ok := updater.(ZoneResolver)
+	if !ok {
+		return ""
+	}
+
+	return resolver.Zone(record)
 }
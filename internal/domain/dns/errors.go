@@ -0,0 +1,31 @@
+package dns
+
+import "errors"
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as a failure that retrying won't fix, like a rejected
+// change or invalid credentials. Updaters use it so callers can tell it apart
+// from throttling and network errors.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
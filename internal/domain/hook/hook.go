@@ -0,0 +1,42 @@
+package hook
+
+import (
+	"context"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const (
+	PreUpdate  Stage = "pre-update"
+	PostUpdate Stage = "post-update"
+	OnFailure  Stage = "on-failure"
+
+	Pending Result = "pending"
+	Success Result = "success"
+	Failure Result = "failure"
+)
+
+type Stage string
+
+type Result string
+
+// Run describes the cycle hooks run for. Records are the records about to be
+// pushed on pre-update, the pushed ones on post-update and the failed ones on
+// failure.
+type Run struct {
+	Stage   Stage
+	Result  Result
+	OldIP   publicip.IP
+	NewIP   publicip.IP
+	Records []dns.DomainRecord
+	Errors  []string
+}
+
+type Runner interface {
+	Run(ctx context.Context, run Run) error
+}
+
+func Stages() []Stage {
+	return []Stage{PreUpdate, PostUpdate, OnFailure}
+}
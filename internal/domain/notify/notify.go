@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
+)
+
+const (
+	IPChanged       EventType = "ip-changed"
+	RecordsUpdated  EventType = "records-updated"
+	UpdateFailed    EventType = "update-failed"
+	SourceOutage    EventType = "source-outage"
+	SourceRecovered EventType = "source-recovered"
+
+	Info    Severity = "info"
+	Warning Severity = "warning"
+	Error   Severity = "error"
+)
+
+type EventType string
+
+type Severity string
+
+// Event is something worth telling people about. OldIP and NewIP are set on
+// ip changes, Provider, Records and Errors on record updates and failures.
+type Event struct {
+	Type     EventType
+	Severity Severity
+	Time     time.Time
+	Summary  string
+	OldIP    publicip.IP
+	NewIP    publicip.IP
+	Provider string
+	Records  []dns.DomainRecord
+	Errors   []string
+}
+
+type Notifier interface {
+	Notify(context.Context, Event) error
+	Name() string
+}
+
+// Closer is implemented by notifiers holding events back, like digests, that
+// have to be sent before the notifier is dropped.
+type Closer interface {
+	Close() error
+}
+
+// Close sends what notifier holds back and stops it, notifiers holding
+// nothing back are left as they are.
+func Close(notifier Notifier) error {
+	closer, ok := notifier.(Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+func EventTypes() []EventType {
+	return []EventType{IPChanged, RecordsUpdated, UpdateFailed, SourceOutage, SourceRecovered}
+}
+
+// Severities returns the severities from the least to the most severe.
+func Severities() []Severity {
+	return []Severity{Info, Warning, Error}
+}
+
+// AtLeast reports whether s is as severe as min or more.
+func (s Severity) AtLeast(min Severity) bool {
+	return slices.Index(Severities(), s) >= slices.Index(Severities(), min)
+}
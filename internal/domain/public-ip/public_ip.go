@@ -3,8 +3,9 @@ package publicip
 import "context"
 
 type IP struct {
-	V4 *string
-	V6 *string
+	V4     *string
+	V6     *string
+	Source string
 }
 
 type Getter interface {
@@ -2,12 +2,94 @@ package ddns
 
 import (
 	"context"
+	"time"
 
 	"github.com/jorgesanchez-e/simple-ddns/internal/domain/dns"
+	publicip "github.com/jorgesanchez-e/simple-ddns/internal/domain/public-ip"
 )
 
+const (
+	Changed     Reason = "changed"
+	InSync      Reason = "in-sync"
+	Forced      Reason = "forced"
+	NotSelected Reason = "not-selected"
+	Paused      Reason = "paused"
+	NoIP        Reason = "no-ip"
+)
+
+// HistoryEntry is a value stored for a record on a provider, Provider is empty
+// for values stored before records were kept per provider.
+type HistoryEntry struct {
+	Provider   string
+	Record     dns.DomainRecord
+	UpdateTime time.Time
+	Active     bool
+}
+
+type PendingRetry struct {
+	Provider    string
+	Record      dns.DomainRecord
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+}
+
+// Reason tells why a sync cycle pushed a managed record or left it alone.
+type Reason string
+
+// Decision is what a sync cycle did with a record managed by a provider.
+// Record holds the value the public ip asked for, Previous the value stored
+// before the cycle. Status and Error are only set for pushed records.
+type Decision struct {
+	Provider string
+	Zone     string
+	Record   dns.DomainRecord
+	Previous string
+	Reason   Reason
+	Status   dns.UpdateStatus
+	Error    string
+}
+
+// Cycle is the audit entry of a sync cycle, Error is set when the cycle
+// couldn't run at all.
+type Cycle struct {
+	ID         int64
+	StartTime  time.Time
+	Duration   time.Duration
+	Forced     bool
+	IP         publicip.IP
+	PreviousIP publicip.IP
+	Error      string
+	Decisions  []Decision
+}
+
 type Controller interface {
-	UpdateRecord(context.Context, dns.DomainRecord) error
+	UpdateRecord(ctx context.Context, provider string, record dns.DomainRecord) error
 	GetRecords(context.Context) ([]dns.DomainRecord, error)
-	InitRecords(context.Context, []dns.DomainRecord) error
+	ActiveRecords(context.Context) ([]HistoryEntry, error)
+	InitRecords(ctx context.Context, provider string, records []dns.DomainRecord) error
+	History(ctx context.Context, fqdn string, limit int) ([]HistoryEntry, error)
+	Ping(context.Context) error
+	Close() error
+	RetryStore
+	PauseStore
+	AuditStore
+}
+
+type RetryStore interface {
+	SavePendingRetry(context.Context, PendingRetry) error
+	DeletePendingRetry(ctx context.Context, provider string, record dns.DomainRecord) error
+	PendingRetries(ctx context.Context, provider string) ([]PendingRetry, error)
+}
+
+// PauseStore keeps the records an operator excluded from updates.
+type PauseStore interface {
+	SetPaused(ctx context.Context, record dns.DomainRecord, paused bool) error
+	PausedRecords(context.Context) ([]dns.DomainRecord, error)
+}
+
+// AuditStore keeps what every sync cycle decided, newest cycles first.
+type AuditStore interface {
+	SaveCycle(context.Context, Cycle) error
+	Cycles(ctx context.Context, limit int) ([]Cycle, error)
 }
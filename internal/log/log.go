@@ -14,6 +14,15 @@ const (
 
 type Level int32
 
+func ParseLevel(level string) (Level, error) {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return Error, err
+	}
+
+	return Level(lvl), nil
+}
+
 type logger struct {
 	log *log.Logger
 }
@@ -41,6 +50,10 @@ func (l *logger) Warn(msg string) {
 	l.log.Warn(msg)
 }
 
+func (l *logger) Warning(msg string) {
+	l.log.Warn(msg)
+}
+
 func (l *logger) Error(err error) {
 	l.log.Error(err.Error())
 }